@@ -2,15 +2,23 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/slok/alertgram/internal/notify/telegram"
 )
 
 var (
 	// Version will be populated in compilation time.
 	Version = "dev"
+
+	telegramWebhookSecretRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
 )
 
 // flag descriptions.
@@ -19,30 +27,113 @@ const (
 	descAMWebhookPath      = "The path where the server will be handling the alertmanager webhook alert requests."
 	descAMChatIDQS         = "The optional query string key used to customize the chat id of the notification. Does not depend on the notifier type."
 	descAMDMSPath          = "The path for the dead man switch alerts from the Alertmanger."
+	descGrafanaEnabled     = "Enables the Grafana unified alerting webhook alerts input."
+	descGrafanaWebhookPath = "The path where the server will be handling the Grafana unified alerting webhook alert requests, it will be served on the alertmanager listen address."
+	descGenericWebhookPath = "The path where the server will be handling the generic JSON webhook alert requests, it will be served on the alertmanager listen address."
+	descGenericMappingPath = "The path to the mapping configuration file of the generic JSON webhook alerts. If set, the generic JSON webhook will be enabled."
+	descPDEnabled          = "Enables the PagerDuty Events API v2 compatible alerts input, requires the accepted routing keys."
+	descPDRoutingKey       = "The routing key accepted on the PagerDuty events, the events with other routing keys are rejected. Can be repeated."
+	descPDWebhookPath      = "The path where the server will be handling the PagerDuty Events API v2 compatible requests, it will be served on the alertmanager listen address."
+	descAMAPIURL           = "The Alertmanager URL that will be used to use its API (e.g. to silence the alerts from the telegram buttons)."
+	descAMPollInterval     = "The interval to poll the alerts from the Alertmanager API instead of receiving them on the webhook (in Go time duration). If set, the poller will be enabled, requires the Alertmanager API URL."
+	descAMPollFilter       = "Alertmanager style label matcher of the alerts that will be polled (e.g. 'severity=\"critical\"'). Can be repeated."
+	descAMPollReceiver     = "The regex of the Alertmanager receivers of the alerts that will be polled."
 	descTelegramAPIToken   = "The token that will be used to use the telegram API to send the alerts."
-	descTelegramDefChatID  = "The default ID of the chat (group/channel) in telegram where the alerts will be sent."
+	descTelegramDefChatID  = "The default ID of the chat (group/channel) in telegram where the alerts will be sent, optionally with a forum topic ID (e.g. '-1001234567890/42')."
+	descTelegramBot        = "The extra telegram bot API tokens by alias (e.g. 'team1=123456:ABC-DEF'), the bot is selected with its alias as a prefix of the chat IDs (e.g. 'team1:-1001234567890'). Can be repeated."
+	descTelegramBotChatID  = "The default chat ID of the extra telegram bots by alias (e.g. 'team1=-1001234567890'), if not set the default chat ID will be used. Can be repeated."
+	descTelegramChatRate   = "The maximum number of messages per second that will be sent to the same telegram chat."
+	descTelegramGroupRate  = "The maximum number of messages per minute that will be sent to the same telegram group or channel."
+	descTelegramRetries    = "The number of retries when telegram responds with too many requests error. A negative value disables the retries."
+	descTelegramResMode    = "How the resolved alerts will be notified: in a new message, editing the firing alerts message in place or replying to the firing alerts message."
+	descTelegramMsgStore   = "The path of the file where the firing alerts telegram messages will be stored for the edit and reply resolved modes, if not set they will be stored in memory."
+	descTelegramMsgTTL     = "The time the firing alerts telegram messages will be stored for the edit and reply resolved modes (in Go time duration)."
+	descTelegramMigrations = "The path of the file where the telegram chats migrated to a new chat (e.g. a group upgraded to a supergroup) will be stored, if not set they will be stored in memory."
+	descTelegramButtons    = "Adds buttons to the telegram alert messages to silence, acknowledge and open the alerts. Silence buttons require the Alertmanager API URL."
+	descTelegramSilences   = "The durations of the telegram silence buttons (in Go time duration). Can be repeated."
+	descTelegramSilent     = "Alertmanager style label matcher of the alerts that will be sent to telegram without notification sound (e.g. 'severity=\"info\"'), the messages are silent when all their alerts match any matcher. Can be repeated."
+	descTelegramPin        = "Alertmanager style label matcher of the firing alerts whose telegram messages will be pinned until resolved (e.g. 'severity=\"critical\"'). Can be repeated."
+	descTelegramCommands   = "Enables the telegram bot commands to list and silence the alerts, check the dead man's switch and mute the chats."
+	descTelegramAllowUser  = "The telegram user IDs allowed to use the bot commands and alert buttons. Can be repeated."
+	descTelegramAllowChat  = "The telegram chat IDs where anyone can use the bot commands and alert buttons, if no users or chats are allowed, the default chat ID will be allowed. Can be repeated."
+	descTelegramUpdSecret  = "The secret token that telegram sends on the bot updates webhook requests, the requests without it are rejected. If not set, a random one is generated on start."
+	descTelegramUpdatesURL = "The public URL of the telegram bot updates webhook, its path will be served on the alertmanager listen address. If not set, the updates will be polled from telegram."
+	descTelegramGraphURL   = "The Prometheus URL used to render the graphs of the firing alerts expressions that will be sent to telegram. If not set, the graphs will not be sent."
+	descTelegramGraphRange = "The time range of the telegram alert graphs until now (in Go time duration)."
+	descTelegramGraphMode  = "How the telegram alert graphs will be sent: with the alerts message as the caption (if it fits) or as a reply to the alerts message."
+	descTelegramGraphMax   = "The maximum number of graphs sent with a telegram alerts message."
+	descSlackWebhookURL    = "The Slack incoming webhook URL that will be used to send the alerts to Slack."
+	descSlackAPIToken      = "The Slack bot token that will be used to send the alerts to Slack using the API (instead of an incoming webhook)."
+	descSlackChannel       = "The default Slack channel where the alerts will be sent (required when using the Slack API)."
+	descDiscordWebhook     = "The Discord webhook URLs by alias (e.g. 'team1=https://discord.com/api/webhooks/...'), the chat ID will be used as the alias. Can be repeated."
+	descDiscordDefWebhook  = "The alias of the Discord webhook where the alerts will be sent by default."
+	descDiscordUsername    = "The username of the Discord webhook messages, if not set it will use the webhook default."
+	descMatrixHomeserver   = "The Matrix homeserver URL that will be used to send the alerts to Matrix."
+	descMatrixAccessToken  = "The Matrix user access token that will be used to send the alerts to Matrix."
+	descMatrixRoomID       = "The default Matrix room ID where the alerts will be sent."
+	descWebhookURL         = "The URL where the alerts will be sent as JSON by the generic webhook notifier."
+	descWebhookBodyTmpl    = "The path to a Go text template used to render the JSON body of the generic webhook requests."
+	descWebhookHeader      = "Custom headers of the generic webhook requests (e.g. 'Authorization=Bearer xyz'). Can be repeated."
+	descWebhookHMACSecret  = "The secret used to sign the generic webhook requests body with HMAC-SHA256 (set on 'X-Alertgram-Signature' header)."
+	descWebhookSuccessCode = "The response status codes of the generic webhook that will be treated as success (by default 200, 201, 202 and 204). Can be repeated."
+	descEmailSMTPAddr      = "The SMTP server address ('host:port') that will be used to send the alerts by email."
+	descEmailTLSMode       = "How the SMTP connection will be secured."
+	descEmailInsecure      = "Disables the SMTP server TLS certificate verification."
+	descEmailUsername      = "The SMTP username, if set, the SMTP PLAIN auth will be used."
+	descEmailPassword      = "The SMTP password."
+	descEmailFrom          = "The sender address of the alert emails."
+	descEmailTo            = "The default comma separated recipients where the alert emails will be sent, the chat ID will be used as the recipients list."
 	descMetricsListenAddr  = "The listen address where the metrics will be being served."
 	descMetricsPath        = "The path where the metrics will be being served."
 	descMetricsHCPath      = "The path where the healthcheck will be being served, it uses the same port as the metrics."
 	descDMSEnable          = "Enables the dead man switch, that will send an alert if no alert is received at regular intervals."
 	descDMSInterval        = "The interval the dead mans switch needs to receive an alert to not activate and send a notification alert (in Go time duration)."
-	descDMSChatID          = "The chat ID (group/channel/room) the dead man's witch will sent the alerts. Does not depend on the notifier type and if not set it will be used notifier default chat ID."
+	descDMSChatID          = "The chat ID (group/channel/room) the dead man's witch will sent the alerts, prefix it with the notifier type to select the notifier (e.g 'email:oncall@example.org'). If not set it will be used notifier default chat ID."
 	descDebug              = "Run the application in debug mode."
 	descNotifyDryRun       = "Dry run the notification and show in the terminal instead of sending."
 	descNotifyTemplatePath = "The path to set a custom template for the notification messages."
+	descNotifyTemplateMode = "The markup of the custom template, the Telegram messages will be sent with this parse mode. Matrix and email use the default template if it's not html."
+	descNotifyWorkers      = "The number of notifications that can be sent concurrently."
+	descNotifyTimeout      = "The maximum duration of a notification send (in Go time duration)."
 	descAlertLabelChatID   = "The label of the alert that will carry the chat id to forward the alert."
+	descAlertRoutingPath   = "The path to the routing configuration file that selects the chats and notifiers of the alerts based on their labels."
+	descQueuePath          = "The path of the directory for the durable notifications queue. If set, the notifications will be stored on disk and retried until they are sent."
+	descQueueDLPath        = "The path of the directory where the notifications that could not be sent will be stored. By default a directory inside the queue directory."
+	descQueueMaxRetries    = "The number of retries of a queued notification before moving it to the dead letter directory, 0 disables the retries."
+	descQueueInitBackoff   = "The wait time before retrying a queued notification for the first time, it will be doubled on each retry (in Go time duration)."
+	descQueueMaxBackoff    = "The maximum wait time between queued notification retries (in Go time duration)."
 )
 
 const (
-	defAMListenAddr      = ":8080"
-	defAMWebhookPath     = "/alerts"
-	defAMChatIDQS        = "chat-id"
-	defAMDMSPath         = "/alerts/dms"
-	defMetricsListenAddr = ":8081"
-	defMetricsPath       = "/metrics"
-	defMetricsHCPath     = "/status"
-	defDMSInterval       = "15m"
-	defAlertLabelChatID  = "chat_id"
+	defAMListenAddr       = ":8080"
+	defAMWebhookPath      = "/alerts"
+	defAMChatIDQS         = "chat-id"
+	defAMDMSPath          = "/alerts/dms"
+	defGrafanaWebhookPath = "/grafana/alerts"
+	defGenericWebhookPath = "/generic/alerts"
+	defPDWebhookPath      = "/v2/enqueue"
+	defTelegramBot        = "default"
+	defTelegramChatRate   = "1"
+	defTelegramGroupRate  = "20"
+	defTelegramRetries    = "3"
+	defTelegramResMode    = "new"
+	defTelegramMsgTTL     = "168h"
+	defTelegramGraphRange = "30m"
+	defTelegramGraphMode  = "caption"
+	defTelegramGraphMax   = "1"
+	defDiscordDefWebhook  = "default"
+	defEmailTLSMode       = "starttls"
+	defMetricsListenAddr  = ":8081"
+	defMetricsPath        = "/metrics"
+	defMetricsHCPath      = "/status"
+	defDMSInterval        = "15m"
+	defAlertLabelChatID   = "chat_id"
+	defNotifyTemplateMode = "html"
+	defNotifyWorkers      = "10"
+	defNotifyTimeout      = "30s"
+	defQueueMaxRetries    = "10"
+	defQueueInitBackoff   = "1s"
+	defQueueMaxBackoff    = "5m"
 )
 
 // Config has the configuration of the application.
@@ -51,8 +142,62 @@ type Config struct {
 	AlertmanagerWebhookPath        string
 	AlertmanagerChatIDQQueryString string
 	AlertmanagerDMSPath            string
+	AlertmanagerAPIURL             string
+	AlertmanagerPollInterval       time.Duration
+	AlertmanagerPollFilter         []string
+	AlertmanagerPollReceiver       string
+	GrafanaEnabled                 bool
+	GrafanaWebhookPath             string
+	GenericWebhookPath             string
+	GenericMappingConfig           *os.File
+	PagerDutyEnabled               bool
+	PagerDutyRoutingKeys           []string
+	PagerDutyWebhookPath           string
 	TeletramAPIToken               string
-	TelegramChatID                 int64
+	TelegramChatID                 string
+	TelegramBots                   map[string]string
+	TelegramBotChatIDs             map[string]string
+	TelegramChatRateLimit          float64
+	TelegramGroupRateLimit         float64
+	TelegramMaxRetries             int
+	TelegramResolvedMode           string
+	TelegramMessageStorePath       string
+	TelegramMessageStoreTTL        time.Duration
+	TelegramChatMigrationsPath     string
+	TelegramAlertButtons           bool
+	TelegramSilenceDurations       []time.Duration
+	TelegramSilentMatchers         []string
+	TelegramPinMatchers            []string
+	TelegramBotCommands            bool
+	TelegramAllowedUserIDs         []int
+	TelegramAllowedChatIDs         []int64
+	TelegramUpdatesWebhookURL      string
+	TelegramUpdatesWebhookSecret   string
+	TelegramGraphPrometheusURL     string
+	TelegramGraphRange             time.Duration
+	TelegramGraphMode              string
+	TelegramGraphMax               int
+	SlackWebhookURL                string
+	SlackAPIToken                  string
+	SlackChannel                   string
+	DiscordWebhooks                map[string]string
+	DiscordDefaultWebhook          string
+	DiscordUsername                string
+	MatrixHomeserverURL            string
+	MatrixAccessToken              string
+	MatrixRoomID                   string
+	WebhookURL                     string
+	WebhookBodyTemplate            *os.File
+	WebhookHeaders                 map[string]string
+	WebhookHMACSecret              string
+	WebhookSuccessStatusCodes      []int
+	EmailSMTPAddress               string
+	EmailTLSMode                   string
+	EmailInsecureSkipVerify        bool
+	EmailUsername                  string
+	EmailPassword                  string
+	EmailFrom                      string
+	EmailTo                        string
 	MetricsListenAddr              string
 	MetricsPath                    string
 	MetricsHCPath                  string
@@ -60,9 +205,18 @@ type Config struct {
 	DMSEnable                      bool
 	DMSChatID                      string
 	NotifyTemplate                 *os.File
+	NotifyTemplateMode             string
 	DebugMode                      bool
 	NotifyDryRun                   bool
+	NotifyWorkers                  int
+	NotifyTimeout                  time.Duration
 	AlertLabelChatID               string
+	AlertRoutingConfig             *os.File
+	QueuePath                      string
+	QueueDeadLetterPath            string
+	QueueMaxRetries                int
+	QueueInitialBackoff            time.Duration
+	QueueMaxBackoff                time.Duration
 
 	app *kingpin.Application
 }
@@ -91,8 +245,62 @@ func (c *Config) registerFlags() {
 	c.app.Flag("alertmanager.webhook-path", descAMWebhookPath).Default(defAMWebhookPath).StringVar(&c.AlertmanagerWebhookPath)
 	c.app.Flag("alertmanager.chat-id-query-string", descAMChatIDQS).Default(defAMChatIDQS).StringVar(&c.AlertmanagerChatIDQQueryString)
 	c.app.Flag("alertmanager.dead-mans-switch-path", descAMDMSPath).Default(defAMDMSPath).StringVar(&c.AlertmanagerDMSPath)
+	c.app.Flag("alertmanager.api-url", descAMAPIURL).StringVar(&c.AlertmanagerAPIURL)
+	c.app.Flag("alertmanager.poll-interval", descAMPollInterval).DurationVar(&c.AlertmanagerPollInterval)
+	c.app.Flag("alertmanager.poll-filter", descAMPollFilter).StringsVar(&c.AlertmanagerPollFilter)
+	c.app.Flag("alertmanager.poll-receiver", descAMPollReceiver).StringVar(&c.AlertmanagerPollReceiver)
+	c.app.Flag("grafana.enabled", descGrafanaEnabled).BoolVar(&c.GrafanaEnabled)
+	c.app.Flag("grafana.webhook-path", descGrafanaWebhookPath).Default(defGrafanaWebhookPath).StringVar(&c.GrafanaWebhookPath)
+	c.app.Flag("generic.webhook-path", descGenericWebhookPath).Default(defGenericWebhookPath).StringVar(&c.GenericWebhookPath)
+	c.app.Flag("generic.mapping-config-path", descGenericMappingPath).FileVar(&c.GenericMappingConfig)
+	c.app.Flag("pagerduty.enabled", descPDEnabled).BoolVar(&c.PagerDutyEnabled)
+	c.app.Flag("pagerduty.routing-key", descPDRoutingKey).StringsVar(&c.PagerDutyRoutingKeys)
+	c.app.Flag("pagerduty.webhook-path", descPDWebhookPath).Default(defPDWebhookPath).StringVar(&c.PagerDutyWebhookPath)
 	c.app.Flag("telegram.api-token", descTelegramAPIToken).StringVar(&c.TeletramAPIToken)
-	c.app.Flag("telegram.chat-id", descTelegramDefChatID).Int64Var(&c.TelegramChatID)
+	c.app.Flag("telegram.chat-id", descTelegramDefChatID).StringVar(&c.TelegramChatID)
+	c.app.Flag("telegram.bot", descTelegramBot).StringMapVar(&c.TelegramBots)
+	c.app.Flag("telegram.bot-chat-id", descTelegramBotChatID).StringMapVar(&c.TelegramBotChatIDs)
+	c.app.Flag("telegram.chat-rate-limit", descTelegramChatRate).Default(defTelegramChatRate).Float64Var(&c.TelegramChatRateLimit)
+	c.app.Flag("telegram.group-rate-limit", descTelegramGroupRate).Default(defTelegramGroupRate).Float64Var(&c.TelegramGroupRateLimit)
+	c.app.Flag("telegram.max-retries", descTelegramRetries).Default(defTelegramRetries).IntVar(&c.TelegramMaxRetries)
+	c.app.Flag("telegram.resolved-mode", descTelegramResMode).Default(defTelegramResMode).EnumVar(&c.TelegramResolvedMode, "new", "edit", "reply")
+	c.app.Flag("telegram.message-store-path", descTelegramMsgStore).StringVar(&c.TelegramMessageStorePath)
+	c.app.Flag("telegram.message-store-ttl", descTelegramMsgTTL).Default(defTelegramMsgTTL).DurationVar(&c.TelegramMessageStoreTTL)
+	c.app.Flag("telegram.chat-migrations-path", descTelegramMigrations).StringVar(&c.TelegramChatMigrationsPath)
+	c.app.Flag("telegram.alert-buttons", descTelegramButtons).BoolVar(&c.TelegramAlertButtons)
+	c.app.Flag("telegram.silence-duration", descTelegramSilences).Default("1h", "24h").DurationListVar(&c.TelegramSilenceDurations)
+	c.app.Flag("telegram.silent-matcher", descTelegramSilent).StringsVar(&c.TelegramSilentMatchers)
+	c.app.Flag("telegram.pin-matcher", descTelegramPin).StringsVar(&c.TelegramPinMatchers)
+	c.app.Flag("telegram.bot-commands", descTelegramCommands).BoolVar(&c.TelegramBotCommands)
+	c.app.Flag("telegram.allowed-user-id", descTelegramAllowUser).IntsVar(&c.TelegramAllowedUserIDs)
+	c.app.Flag("telegram.allowed-chat-id", descTelegramAllowChat).Int64ListVar(&c.TelegramAllowedChatIDs)
+	c.app.Flag("telegram.updates-webhook-url", descTelegramUpdatesURL).StringVar(&c.TelegramUpdatesWebhookURL)
+	c.app.Flag("telegram.updates-webhook-secret", descTelegramUpdSecret).StringVar(&c.TelegramUpdatesWebhookSecret)
+	c.app.Flag("telegram.graph-prometheus-url", descTelegramGraphURL).StringVar(&c.TelegramGraphPrometheusURL)
+	c.app.Flag("telegram.graph-range", descTelegramGraphRange).Default(defTelegramGraphRange).DurationVar(&c.TelegramGraphRange)
+	c.app.Flag("telegram.graph-mode", descTelegramGraphMode).Default(defTelegramGraphMode).EnumVar(&c.TelegramGraphMode, "caption", "reply")
+	c.app.Flag("telegram.graph-max", descTelegramGraphMax).Default(defTelegramGraphMax).IntVar(&c.TelegramGraphMax)
+	c.app.Flag("slack.webhook-url", descSlackWebhookURL).StringVar(&c.SlackWebhookURL)
+	c.app.Flag("slack.api-token", descSlackAPIToken).StringVar(&c.SlackAPIToken)
+	c.app.Flag("slack.channel", descSlackChannel).StringVar(&c.SlackChannel)
+	c.app.Flag("discord.webhook", descDiscordWebhook).StringMapVar(&c.DiscordWebhooks)
+	c.app.Flag("discord.default-webhook", descDiscordDefWebhook).Default(defDiscordDefWebhook).StringVar(&c.DiscordDefaultWebhook)
+	c.app.Flag("discord.username", descDiscordUsername).StringVar(&c.DiscordUsername)
+	c.app.Flag("matrix.homeserver-url", descMatrixHomeserver).StringVar(&c.MatrixHomeserverURL)
+	c.app.Flag("matrix.access-token", descMatrixAccessToken).StringVar(&c.MatrixAccessToken)
+	c.app.Flag("matrix.room-id", descMatrixRoomID).StringVar(&c.MatrixRoomID)
+	c.app.Flag("webhook.url", descWebhookURL).StringVar(&c.WebhookURL)
+	c.app.Flag("webhook.body-template-path", descWebhookBodyTmpl).FileVar(&c.WebhookBodyTemplate)
+	c.app.Flag("webhook.header", descWebhookHeader).StringMapVar(&c.WebhookHeaders)
+	c.app.Flag("webhook.hmac-secret", descWebhookHMACSecret).StringVar(&c.WebhookHMACSecret)
+	c.app.Flag("webhook.success-status-code", descWebhookSuccessCode).IntsVar(&c.WebhookSuccessStatusCodes)
+	c.app.Flag("email.smtp-address", descEmailSMTPAddr).StringVar(&c.EmailSMTPAddress)
+	c.app.Flag("email.tls-mode", descEmailTLSMode).Default(defEmailTLSMode).EnumVar(&c.EmailTLSMode, "starttls", "tls", "none")
+	c.app.Flag("email.insecure-skip-verify", descEmailInsecure).BoolVar(&c.EmailInsecureSkipVerify)
+	c.app.Flag("email.username", descEmailUsername).StringVar(&c.EmailUsername)
+	c.app.Flag("email.password", descEmailPassword).StringVar(&c.EmailPassword)
+	c.app.Flag("email.from", descEmailFrom).StringVar(&c.EmailFrom)
+	c.app.Flag("email.to", descEmailTo).StringVar(&c.EmailTo)
 	c.app.Flag("metrics.listen-address", descMetricsListenAddr).Default(defMetricsListenAddr).StringVar(&c.MetricsListenAddr)
 	c.app.Flag("metrics.path", descMetricsPath).Default(defMetricsPath).StringVar(&c.MetricsPath)
 	c.app.Flag("metrics.health-path", descMetricsHCPath).Default(defMetricsHCPath).StringVar(&c.MetricsHCPath)
@@ -101,19 +309,114 @@ func (c *Config) registerFlags() {
 	c.app.Flag("dead-mans-switch.chat-id", descDMSChatID).StringVar(&c.DMSChatID)
 	c.app.Flag("notify.dry-run", descNotifyDryRun).BoolVar(&c.NotifyDryRun)
 	c.app.Flag("notify.template-path", descNotifyTemplatePath).FileVar(&c.NotifyTemplate)
+	c.app.Flag("notify.template-mode", descNotifyTemplateMode).Default(defNotifyTemplateMode).EnumVar(&c.NotifyTemplateMode, "html", "markdownv2", "text")
+	c.app.Flag("notify.workers", descNotifyWorkers).Default(defNotifyWorkers).IntVar(&c.NotifyWorkers)
+	c.app.Flag("notify.timeout", descNotifyTimeout).Default(defNotifyTimeout).DurationVar(&c.NotifyTimeout)
 	c.app.Flag("alert.label-chat-id", descAlertLabelChatID).Default(defAlertLabelChatID).StringVar(&c.AlertLabelChatID)
+	c.app.Flag("alert.routing-config-path", descAlertRoutingPath).FileVar(&c.AlertRoutingConfig)
+	c.app.Flag("queue.path", descQueuePath).StringVar(&c.QueuePath)
+	c.app.Flag("queue.dead-letter-path", descQueueDLPath).StringVar(&c.QueueDeadLetterPath)
+	c.app.Flag("queue.max-retries", descQueueMaxRetries).Default(defQueueMaxRetries).IntVar(&c.QueueMaxRetries)
+	c.app.Flag("queue.initial-backoff", descQueueInitBackoff).Default(defQueueInitBackoff).DurationVar(&c.QueueInitialBackoff)
+	c.app.Flag("queue.max-backoff", descQueueMaxBackoff).Default(defQueueMaxBackoff).DurationVar(&c.QueueMaxBackoff)
 	c.app.Flag("debug", descDebug).BoolVar(&c.DebugMode)
 }
 
 func (c *Config) validate() error {
 	if !c.NotifyDryRun {
-		if c.TeletramAPIToken == "" {
+		slackEnabled := c.SlackWebhookURL != "" || c.SlackAPIToken != ""
+		discordEnabled := len(c.DiscordWebhooks) > 0
+		matrixEnabled := c.MatrixHomeserverURL != ""
+		webhookEnabled := c.WebhookURL != ""
+		emailEnabled := c.EmailSMTPAddress != ""
+
+		// Telegram is required unless other notifier is being used.
+		if c.TeletramAPIToken == "" && !slackEnabled && !discordEnabled && !matrixEnabled && !webhookEnabled && !emailEnabled {
 			return errors.New("telegram api token is required")
 		}
 
-		if c.TelegramChatID == 0 {
+		if c.TeletramAPIToken != "" && c.TelegramChatID == "" {
 			return errors.New("telegram default chat ID is required")
 		}
+
+		if _, _, err := telegram.ParseChatID(c.TelegramChatID); c.TeletramAPIToken != "" && err != nil {
+			return err
+		}
+
+		if len(c.TelegramBots) > 0 && c.TeletramAPIToken == "" {
+			return errors.New("telegram api token of the default bot is required when using multiple bots")
+		}
+
+		for bot := range c.TelegramBots {
+			if bot == "" || bot == defTelegramBot || strings.ContainsAny(bot, ":/") {
+				return fmt.Errorf("invalid telegram bot alias %q", bot)
+			}
+		}
+
+		for bot, chatID := range c.TelegramBotChatIDs {
+			if _, ok := c.TelegramBots[bot]; !ok {
+				return fmt.Errorf("telegram bot %q of the bot chat ID is missing", bot)
+			}
+			if _, _, err := telegram.ParseChatID(chatID); err != nil {
+				return err
+			}
+		}
+
+		if c.SlackAPIToken != "" && c.SlackWebhookURL == "" && c.SlackChannel == "" {
+			return errors.New("slack default channel is required when using the slack API")
+		}
+
+		if _, ok := c.DiscordWebhooks[c.DiscordDefaultWebhook]; discordEnabled && !ok {
+			return errors.New("discord default webhook alias is missing on the discord webhooks")
+		}
+
+		if matrixEnabled && (c.MatrixAccessToken == "" || c.MatrixRoomID == "") {
+			return errors.New("matrix access token and room ID are required when using matrix")
+		}
+
+		if emailEnabled && (c.EmailFrom == "" || c.EmailTo == "") {
+			return errors.New("email from and to are required when using email")
+		}
+
+		if c.TelegramUpdatesWebhookURL != "" {
+			u, err := url.Parse(c.TelegramUpdatesWebhookURL)
+			if err != nil || u.Scheme != "https" || u.Path == "" {
+				return errors.New("telegram updates webhook URL must be an HTTPS URL with a path")
+			}
+		}
+
+		if c.TelegramUpdatesWebhookSecret != "" && !telegramWebhookSecretRegexp.MatchString(c.TelegramUpdatesWebhookSecret) {
+			return errors.New("telegram updates webhook secret must have 1-256 characters of A-Z, a-z, 0-9, _ and -")
+		}
+
+		if c.TelegramChatRateLimit <= 0 || c.TelegramGroupRateLimit <= 0 {
+			return errors.New("telegram chat and group rate limits must be greater than 0")
+		}
+
+		if c.TelegramGraphPrometheusURL != "" && c.TelegramGraphMax <= 0 {
+			return errors.New("telegram graph max must be greater than 0")
+		}
+	}
+
+	if c.NotifyWorkers <= 0 {
+		return errors.New("notify workers must be greater than 0")
 	}
+
+	if c.QueueMaxRetries < 0 {
+		return errors.New("queue max retries can't be negative")
+	}
+
+	if c.AlertmanagerPollInterval < 0 {
+		return errors.New("alertmanager poll interval can't be negative")
+	}
+
+	if c.AlertmanagerPollInterval > 0 && c.AlertmanagerAPIURL == "" {
+		return errors.New("alertmanager API URL is required when polling the alerts")
+	}
+
+	if c.PagerDutyEnabled && len(c.PagerDutyRoutingKeys) == 0 {
+		return errors.New("at least one pagerduty routing key is required when using the pagerduty input")
+	}
+
 	return nil
 }
@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/oklog/run"
@@ -16,21 +20,36 @@ import (
 	metricsmiddleware "github.com/slok/go-http-metrics/middleware"
 	metricsmiddlewarestd "github.com/slok/go-http-metrics/middleware/std"
 
+	alertmanagerapi "github.com/slok/alertgram/internal/alertmanager"
 	"github.com/slok/alertgram/internal/deadmansswitch"
 	"github.com/slok/alertgram/internal/forward"
 	internalhttp "github.com/slok/alertgram/internal/http"
 	"github.com/slok/alertgram/internal/http/alertmanager"
+	"github.com/slok/alertgram/internal/http/generic"
+	"github.com/slok/alertgram/internal/http/grafana"
+	"github.com/slok/alertgram/internal/http/pagerduty"
 	"github.com/slok/alertgram/internal/log"
 	"github.com/slok/alertgram/internal/log/logrus"
 	metricsprometheus "github.com/slok/alertgram/internal/metrics/prometheus"
 	"github.com/slok/alertgram/internal/notify"
+	"github.com/slok/alertgram/internal/notify/discord"
+	"github.com/slok/alertgram/internal/notify/email"
+	"github.com/slok/alertgram/internal/notify/matrix"
+	"github.com/slok/alertgram/internal/notify/slack"
 	"github.com/slok/alertgram/internal/notify/telegram"
+	"github.com/slok/alertgram/internal/notify/webhook"
+	prometheusapi "github.com/slok/alertgram/internal/prometheus"
 )
 
 // Main is the main application.
 type Main struct {
 	cfg    *Config
 	logger log.Logger
+
+	// Set when the telegram notifier is created.
+	telegramCli      *tgbotapi.BotAPI
+	telegramMsgStore telegram.MessageStore
+	telegramMuter    telegram.ChatMuter
 }
 
 // Run runs the main application.
@@ -54,46 +73,217 @@ func (m *Main) Run() error {
 			return err
 		}
 		_ = m.cfg.NotifyTemplate.Close()
-		tmplRenderer, err = notify.NewHTMLTemplateRenderer(string(tmpl))
+		switch m.cfg.NotifyTemplateMode {
+		case "markdownv2":
+			tmplRenderer, err = notify.NewMarkdownV2TemplateRenderer(string(tmpl))
+		case "text":
+			tmplRenderer, err = notify.NewTextTemplateRenderer(string(tmpl))
+		default:
+			tmplRenderer, err = notify.NewHTMLTemplateRenderer(string(tmpl))
+		}
 		if err != nil {
 			return err
 		}
 		tmplRenderer = notify.NewMeasureTemplateRenderer("custom", metricsRecorder, tmplRenderer)
-		m.logger.Infof("using custom template at %s", m.cfg.NotifyTemplate.Name())
+		m.logger.Infof("using custom %s template at %s", m.cfg.NotifyTemplateMode, m.cfg.NotifyTemplate.Name())
 	} else {
 		tmplRenderer = notify.NewMeasureTemplateRenderer("default", metricsRecorder, notify.DefaultTemplateRenderer)
 	}
 
-	var notifier forward.Notifier
-	if m.cfg.NotifyDryRun {
-		notifier = notify.NewLogger(tmplRenderer, m.logger)
-	} else {
-		tgCli, err := tgbotapi.NewBotAPI(m.cfg.TeletramAPIToken)
+	notifiers, err := m.createNotifiers(tmplRenderer, metricsRecorder)
+	if err != nil {
+		return err
+	}
+
+	// Measure the notifiers and use the durable notifications queue if required.
+	queueCtx, queueCtxCancel := context.WithCancel(context.Background())
+	defer queueCtxCancel()
+	for i, notifier := range notifiers {
+		notifier = forward.NewMeasureNotifier(metricsRecorder, notifier)
+		if m.cfg.QueuePath != "" {
+			// Each notifier has its own queue.
+			deadLetterDir := ""
+			if m.cfg.QueueDeadLetterPath != "" {
+				deadLetterDir = filepath.Join(m.cfg.QueueDeadLetterPath, notifier.Type())
+			}
+			notifier, err = forward.NewQueueNotifier(queueCtx, forward.QueueConfig{
+				Dir:            filepath.Join(m.cfg.QueuePath, notifier.Type()),
+				DeadLetterDir:  deadLetterDir,
+				MaxRetries:     m.cfg.QueueMaxRetries,
+				InitialBackoff: m.cfg.QueueInitialBackoff,
+				MaxBackoff:     m.cfg.QueueMaxBackoff,
+				NotifyTimeout:  m.cfg.NotifyTimeout,
+				Notifier:       notifier,
+				Logger:         m.logger,
+			})
+			if err != nil {
+				return err
+			}
+			m.logger.Infof("using durable notifications queue at %s for %s notifier", m.cfg.QueuePath, notifier.Type())
+		}
+		notifiers[i] = notifier
+	}
+
+	// Load the alerts routing tree if required.
+	var routingTree *forward.Route
+	if m.cfg.AlertRoutingConfig != nil {
+		routingCfg, err := ioutil.ReadAll(m.cfg.AlertRoutingConfig)
+		if err != nil {
+			return err
+		}
+		_ = m.cfg.AlertRoutingConfig.Close()
+		routingTree, err = forward.LoadRoutingTree(routingCfg)
 		if err != nil {
 			return err
 		}
+		m.logger.Infof("using alerts routing configuration at %s", m.cfg.AlertRoutingConfig.Name())
+	}
 
-		notifier, err = telegram.NewNotifier(telegram.Config{
-			TemplateRenderer:      tmplRenderer,
-			Client:                tgCli,
-			DefaultTelegramChatID: m.cfg.TelegramChatID,
-			Logger:                m.logger,
+	// Load the generic JSON webhook alerts mapping if required.
+	var genericMapping *generic.Mapping
+	if m.cfg.GenericMappingConfig != nil {
+		mappingCfg, err := ioutil.ReadAll(m.cfg.GenericMappingConfig)
+		if err != nil {
+			return err
+		}
+		_ = m.cfg.GenericMappingConfig.Close()
+		genericMapping, err = generic.LoadMapping(mappingCfg)
+		if err != nil {
+			return err
+		}
+		m.logger.Infof("using generic webhook alerts mapping configuration at %s", m.cfg.GenericMappingConfig.Name())
+	}
+
+	// Dead man's switch.
+	dmsCtx, dmsCtxCancel := context.WithCancel(context.Background())
+	defer dmsCtxCancel()
+	var deadMansSwitchSvc deadmansswitch.Service = deadmansswitch.DisabledService // By default disabled.
+	if m.cfg.DMSEnable {
+		deadMansSwitchSvc, err = deadmansswitch.NewService(dmsCtx, deadmansswitch.Config{
+			CustomChatID: m.cfg.DMSChatID,
+			Notifiers:    notifiers,
+			Interval:     m.cfg.DMSInterval,
+			Logger:       m.logger,
 		})
 		if err != nil {
 			return err
 		}
+
+		deadMansSwitchSvc = deadmansswitch.NewMeasureService(metricsRecorder, deadMansSwitchSvc)
 	}
-	notifier = forward.NewMeasureNotifier(metricsRecorder, notifier)
 
 	var g run.Group
 
+	// Telegram bot updates (alert buttons presses and commands).
+	var tgUpdatesWebhook http.Handler
+	if m.telegramCli != nil && (m.cfg.TelegramAlertButtons || m.cfg.TelegramBotCommands) {
+		logger := m.logger.WithValues(log.KV{"service": "telegram-updates"})
+		var amCli alertmanagerapi.Client
+		if m.cfg.AlertmanagerAPIURL != "" {
+			amCli, err = alertmanagerapi.NewClient(alertmanagerapi.Config{URL: m.cfg.AlertmanagerAPIURL})
+			if err != nil {
+				return err
+			}
+		}
+
+		allowedChatIDs := m.cfg.TelegramAllowedChatIDs
+		if len(allowedChatIDs) == 0 && len(m.cfg.TelegramAllowedUserIDs) == 0 {
+			chatID, _, _ := telegram.ParseChatID(m.cfg.TelegramChatID)
+			allowedChatIDs = []int64{chatID}
+		}
+
+		handlers := telegram.UpdateHandlers{}
+		if m.cfg.TelegramAlertButtons {
+			handler, err := telegram.NewCallbackHandler(telegram.CallbackHandlerConfig{
+				Client:             telegram.NewCallbackClient(m.telegramCli),
+				AlertmanagerClient: amCli,
+				MessageStore:       m.telegramMsgStore,
+				AllowedUserIDs:     m.cfg.TelegramAllowedUserIDs,
+				AllowedChatIDs:     allowedChatIDs,
+				MetricsRecorder:    metricsRecorder,
+				Logger:             logger,
+			})
+			if err != nil {
+				return err
+			}
+			handlers = append(handlers, handler)
+		}
+
+		if m.cfg.TelegramBotCommands {
+			handler, err := telegram.NewCommandsHandler(telegram.CommandsHandlerConfig{
+				Client:                telegram.NewClient(m.telegramCli),
+				AlertmanagerClient:    amCli,
+				DeadMansSwitchService: deadMansSwitchSvc,
+				ChatMuter:             m.telegramMuter,
+				AllowedUserIDs:        m.cfg.TelegramAllowedUserIDs,
+				AllowedChatIDs:        allowedChatIDs,
+				BotUserName:           m.telegramCli.Self.UserName,
+				MetricsRecorder:       metricsRecorder,
+				Logger:                logger,
+			})
+			if err != nil {
+				return err
+			}
+			handlers = append(handlers, handler)
+		}
+
+		if m.cfg.TelegramUpdatesWebhookURL != "" {
+			// Telegram sends the updates to the webhook that is served by the alertmanager server.
+			// The secret token authenticates the updates sent by Telegram, if not set
+			// a new one is generated, the webhook is set again on every start.
+			secretToken := m.cfg.TelegramUpdatesWebhookSecret
+			if secretToken == "" {
+				secretToken, err = telegram.NewUpdatesWebhookSecret()
+				if err != nil {
+					return err
+				}
+			}
+			err := telegram.SetUpdatesWebhook(m.telegramCli, m.cfg.TelegramUpdatesWebhookURL, secretToken)
+			if err != nil {
+				return fmt.Errorf("could not set telegram updates webhook: %w", err)
+			}
+			tgUpdatesWebhook, err = telegram.NewUpdatesWebhookHandler(handlers, secretToken, logger)
+			if err != nil {
+				return err
+			}
+			logger.Infof("receiving telegram updates on %s", m.cfg.TelegramUpdatesWebhookURL)
+		} else {
+			// The updates can't be polled while a webhook is set.
+			_, err := m.telegramCli.RemoveWebhook()
+			if err != nil {
+				return fmt.Errorf("could not remove telegram updates webhook: %w", err)
+			}
+			poller, err := telegram.NewUpdatesPoller(telegram.UpdatesPollerConfig{
+				Client:  m.telegramCli,
+				Handler: handlers,
+				Logger:  logger,
+			})
+			if err != nil {
+				return err
+			}
+
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			g.Add(
+				func() error {
+					return poller.Run(ctx)
+				},
+				func(_ error) {
+					ctxCancel()
+				})
+		}
+	}
+
 	// Alertmanager webhook server.
 	{
 
 		// Alert forward.
 		forwardSvc, err := forward.NewService(forward.ServiceConfig{
 			AlertLabelChatID: m.cfg.AlertLabelChatID,
-			Notifiers:        []forward.Notifier{notifier},
+			RoutingTree:      routingTree,
+			Notifiers:        notifiers,
+			Workers:          m.cfg.NotifyWorkers,
+			NotifyTimeout:    m.cfg.NotifyTimeout,
+			MetricsRecorder:  metricsRecorder,
 			Logger:           m.logger,
 		})
 		if err != nil {
@@ -101,22 +291,36 @@ func (m *Main) Run() error {
 		}
 		forwardSvc = forward.NewMeasureService(metricsRecorder, forwardSvc)
 
-		// Dead man's switch.
-		ctx, ctxCancel := context.WithCancel(context.Background())
-		var deadMansSwitchSvc deadmansswitch.Service = deadmansswitch.DisabledService // By default disabled.
-		if m.cfg.DMSEnable {
-			deadMansSwitchSvc, err = deadmansswitch.NewService(ctx, deadmansswitch.Config{
-				CustomChatID: m.cfg.DMSChatID,
-				Notifiers:    []forward.Notifier{notifier},
-				Interval:     m.cfg.DMSInterval,
-				Logger:       m.logger,
+		// Alertmanager alerts poller.
+		if m.cfg.AlertmanagerPollInterval > 0 {
+			// Validate the filter matchers, Alertmanager uses the same format.
+			if _, err := parseMatchers(m.cfg.AlertmanagerPollFilter); err != nil {
+				return err
+			}
+			amCli, err := alertmanagerapi.NewClient(alertmanagerapi.Config{URL: m.cfg.AlertmanagerAPIURL})
+			if err != nil {
+				return err
+			}
+			poller, err := alertmanagerapi.NewPoller(alertmanagerapi.PollerConfig{
+				Client:         amCli,
+				ForwardService: forwardSvc,
+				Filter:         m.cfg.AlertmanagerPollFilter,
+				Receiver:       m.cfg.AlertmanagerPollReceiver,
+				Interval:       m.cfg.AlertmanagerPollInterval,
+				Logger:         m.logger,
 			})
 			if err != nil {
-				ctxCancel()
 				return err
 			}
 
-			deadMansSwitchSvc = deadmansswitch.NewMeasureService(metricsRecorder, deadMansSwitchSvc)
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			g.Add(
+				func() error {
+					return poller.Run(ctx)
+				},
+				func(_ error) {
+					ctxCancel()
+				})
 		}
 
 		// API server.
@@ -125,22 +329,72 @@ func (m *Main) Run() error {
 			Debug:                 m.cfg.DebugMode,
 			MetricsRecorder:       metricsRecorder,
 			WebhookPath:           m.cfg.AlertmanagerWebhookPath,
+			ChatIDQueryString:     m.cfg.AlertmanagerChatIDQQueryString,
 			DeadMansSwitchService: deadMansSwitchSvc,
 			DeadMansSwitchPath:    m.cfg.AlertmanagerDMSPath,
 			ForwardService:        forwardSvc,
 			Logger:                logger,
 		})
 		if err != nil {
-			ctxCancel()
 			return err
 		}
+		mux := http.NewServeMux()
+		if m.cfg.GrafanaEnabled {
+			gh, err := grafana.NewHandler(grafana.Config{
+				Debug:             m.cfg.DebugMode,
+				MetricsRecorder:   metricsRecorder,
+				WebhookPath:       m.cfg.GrafanaWebhookPath,
+				ChatIDQueryString: m.cfg.AlertmanagerChatIDQQueryString,
+				ForwardService:    forwardSvc,
+				Logger:            m.logger.WithValues(log.KV{"server": "grafana-handler"}),
+			})
+			if err != nil {
+				return err
+			}
+			mux.Handle(m.cfg.GrafanaWebhookPath, gh)
+		}
+		if m.cfg.PagerDutyEnabled {
+			ph, err := pagerduty.NewHandler(pagerduty.Config{
+				Debug:             m.cfg.DebugMode,
+				MetricsRecorder:   metricsRecorder,
+				WebhookPath:       m.cfg.PagerDutyWebhookPath,
+				ChatIDQueryString: m.cfg.AlertmanagerChatIDQQueryString,
+				RoutingKeys:       m.cfg.PagerDutyRoutingKeys,
+				ForwardService:    forwardSvc,
+				Logger:            m.logger.WithValues(log.KV{"server": "pagerduty-handler"}),
+			})
+			if err != nil {
+				return err
+			}
+			mux.Handle(m.cfg.PagerDutyWebhookPath, ph)
+		}
+		if genericMapping != nil {
+			jh, err := generic.NewHandler(generic.Config{
+				Debug:             m.cfg.DebugMode,
+				MetricsRecorder:   metricsRecorder,
+				WebhookPath:       m.cfg.GenericWebhookPath,
+				ChatIDQueryString: m.cfg.AlertmanagerChatIDQQueryString,
+				Mapping:           genericMapping,
+				ForwardService:    forwardSvc,
+				Logger:            m.logger.WithValues(log.KV{"server": "generic-handler"}),
+			})
+			if err != nil {
+				return err
+			}
+			mux.Handle(m.cfg.GenericWebhookPath, jh)
+		}
+		if tgUpdatesWebhook != nil {
+			u, _ := url.Parse(m.cfg.TelegramUpdatesWebhookURL)
+			mux.Handle(u.Path, tgUpdatesWebhook)
+		}
+		mux.Handle("/", h)
+		h = mux
 		server, err := internalhttp.NewServer(internalhttp.Config{
 			Handler:       h,
 			ListenAddress: m.cfg.AlertmanagerListenAddr,
 			Logger:        logger,
 		})
 		if err != nil {
-			ctxCancel()
 			return err
 		}
 
@@ -149,10 +403,17 @@ func (m *Main) Run() error {
 				return server.ListenAndServe()
 			},
 			func(_ error) {
-				ctxCancel()
+				dmsCtxCancel()
 				if err := server.DrainAndShutdown(); err != nil {
 					logger.Errorf("error while draining connections")
 				}
+
+				// The alerts are sent in background, wait until the forwarded alerts are sent.
+				ctx, cancel := context.WithTimeout(context.Background(), m.cfg.NotifyTimeout)
+				defer cancel()
+				if err := forwardSvc.Close(ctx); err != nil {
+					logger.Errorf("error while sending the forwarded alerts: %s", err)
+				}
 			})
 	}
 
@@ -207,6 +468,268 @@ func (m *Main) Run() error {
 	return g.Run()
 }
 
+// createNotifiers creates the notifiers that have been configured.
+func (m *Main) createNotifiers(tmplRenderer notify.TemplateRenderer, metricsRecorder *metricsprometheus.Recorder) ([]forward.Notifier, error) {
+	if m.cfg.NotifyDryRun {
+		return []forward.Notifier{notify.NewLogger(tmplRenderer, m.logger)}, nil
+	}
+
+	notifiers := []forward.Notifier{}
+
+	// The notifiers that only understand HTML can't use the custom template in other modes.
+	htmlTmplRenderer := tmplRenderer
+	parseMode := telegram.ParseModeHTML
+	if m.cfg.NotifyTemplate != nil {
+		switch m.cfg.NotifyTemplateMode {
+		case "markdownv2":
+			parseMode = telegram.ParseModeMarkdownV2
+			htmlTmplRenderer = notify.NewMeasureTemplateRenderer("default", metricsRecorder, notify.DefaultTemplateRenderer)
+		case "text":
+			parseMode = telegram.ParseModeText
+			htmlTmplRenderer = notify.NewMeasureTemplateRenderer("default", metricsRecorder, notify.DefaultTemplateRenderer)
+		}
+	}
+
+	// Telegram.
+	if m.cfg.TeletramAPIToken != "" {
+		chatMigrations := telegram.NewMemoryChatMigrations()
+		if m.cfg.TelegramChatMigrationsPath != "" {
+			var err error
+			chatMigrations, err = telegram.NewFileChatMigrations(m.cfg.TelegramChatMigrationsPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Silence buttons need the Alertmanager API.
+		var silenceDurations []time.Duration
+		if m.cfg.AlertmanagerAPIURL != "" {
+			silenceDurations = m.cfg.TelegramSilenceDurations
+		}
+
+		silentMatchers, err := parseMatchers(m.cfg.TelegramSilentMatchers)
+		if err != nil {
+			return nil, err
+		}
+		pinMatchers, err := parseMatchers(m.cfg.TelegramPinMatchers)
+		if err != nil {
+			return nil, err
+		}
+
+		// Alert graphs need the Prometheus API.
+		var graphRenderer telegram.GraphRenderer
+		if m.cfg.TelegramGraphPrometheusURL != "" {
+			promCli, err := prometheusapi.NewClient(prometheusapi.Config{URL: m.cfg.TelegramGraphPrometheusURL})
+			if err != nil {
+				return nil, err
+			}
+			graphRenderer, err = prometheusapi.NewGraphRenderer(prometheusapi.GraphRendererConfig{
+				Client: promCli,
+				Range:  m.cfg.TelegramGraphRange,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		muter := telegram.NewMemoryChatMuter()
+
+		// newBotNotifier creates the notifier of a bot, all the bots share the configuration
+		// but only the default bot has the alert buttons because it handles the bot updates.
+		newBotNotifier := func(bot string, token string, chatIDTarget string, alertButtons bool) (*tgbotapi.BotAPI, telegram.MessageStore, forward.Notifier, error) {
+			tgCli, err := tgbotapi.NewBotAPI(token)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("could not create %q telegram bot: %w", bot, err)
+			}
+
+			chatID, topicID, err := telegram.ParseChatID(chatIDTarget)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			msgStore := telegram.NewMemoryMessageStore(m.cfg.TelegramMessageStoreTTL)
+			if m.cfg.TelegramMessageStorePath != "" {
+				path := m.cfg.TelegramMessageStorePath
+				if bot != defTelegramBot {
+					ext := filepath.Ext(path)
+					path = strings.TrimSuffix(path, ext) + "-" + bot + ext
+				}
+				msgStore, err = telegram.NewFileMessageStore(path, m.cfg.TelegramMessageStoreTTL)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+			}
+
+			notifier, err := telegram.NewNotifier(telegram.Config{
+				TemplateRenderer:       tmplRenderer,
+				Client:                 telegram.NewClient(tgCli),
+				DefaultTelegramChatID:  chatID,
+				DefaultTelegramTopicID: topicID,
+				ChatMessagesPerSecond:  m.cfg.TelegramChatRateLimit,
+				GroupMessagesPerMinute: m.cfg.TelegramGroupRateLimit,
+				MaxRetries:             m.cfg.TelegramMaxRetries,
+				ResolvedMode:           telegram.ResolvedMode(m.cfg.TelegramResolvedMode),
+				ParseMode:              parseMode,
+				AlertButtons:           alertButtons,
+				SilenceDurations:       silenceDurations,
+				SilentMatchers:         silentMatchers,
+				PinMatchers:            pinMatchers,
+				GraphRenderer:          graphRenderer,
+				GraphMode:              telegram.GraphMode(m.cfg.TelegramGraphMode),
+				MaxGraphs:              m.cfg.TelegramGraphMax,
+				ChatMuter:              muter,
+				ChatMigrations:         chatMigrations,
+				MessageStore:           msgStore,
+				MetricsRecorder:        metricsRecorder,
+				Logger:                 m.logger.WithValues(log.KV{"telegramBot": bot}),
+			})
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			return tgCli, msgStore, notifier, nil
+		}
+
+		tgCli, msgStore, notifier, err := newBotNotifier(defTelegramBot, m.cfg.TeletramAPIToken, m.cfg.TelegramChatID, m.cfg.TelegramAlertButtons)
+		if err != nil {
+			return nil, err
+		}
+
+		// The extra bots are selected with their alias on the chat IDs.
+		if len(m.cfg.TelegramBots) > 0 {
+			bots := map[string]forward.Notifier{defTelegramBot: notifier}
+			for bot, token := range m.cfg.TelegramBots {
+				chatID := m.cfg.TelegramBotChatIDs[bot]
+				if chatID == "" {
+					chatID = m.cfg.TelegramChatID
+				}
+				_, _, bots[bot], err = newBotNotifier(bot, token, chatID, false)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			notifier, err = telegram.NewBotsNotifier(telegram.BotsNotifierConfig{
+				Bots:       bots,
+				DefaultBot: defTelegramBot,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		notifiers = append(notifiers, notifier)
+		m.telegramCli = tgCli
+		m.telegramMsgStore = msgStore
+		m.telegramMuter = muter
+	}
+
+	// Slack.
+	if m.cfg.SlackWebhookURL != "" || m.cfg.SlackAPIToken != "" {
+		notifier, err := slack.NewNotifier(slack.Config{
+			WebhookURL:       m.cfg.SlackWebhookURL,
+			APIToken:         m.cfg.SlackAPIToken,
+			DefaultChannel:   m.cfg.SlackChannel,
+			TemplateRenderer: notify.NewMeasureTemplateRenderer("slack-default", metricsRecorder, slack.DefaultTemplateRenderer),
+			Logger:           m.logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+
+	// Discord.
+	if len(m.cfg.DiscordWebhooks) > 0 {
+		notifier, err := discord.NewNotifier(discord.Config{
+			WebhookURLs:    m.cfg.DiscordWebhooks,
+			DefaultWebhook: m.cfg.DiscordDefaultWebhook,
+			Username:       m.cfg.DiscordUsername,
+			Logger:         m.logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+
+	// Matrix.
+	if m.cfg.MatrixHomeserverURL != "" {
+		notifier, err := matrix.NewNotifier(matrix.Config{
+			HomeserverURL:    m.cfg.MatrixHomeserverURL,
+			AccessToken:      m.cfg.MatrixAccessToken,
+			DefaultRoomID:    m.cfg.MatrixRoomID,
+			TemplateRenderer: htmlTmplRenderer,
+			Logger:           m.logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+
+	// Generic webhook.
+	if m.cfg.WebhookURL != "" {
+		bodyTmpl := ""
+		if m.cfg.WebhookBodyTemplate != nil {
+			tmpl, err := ioutil.ReadAll(m.cfg.WebhookBodyTemplate)
+			if err != nil {
+				return nil, err
+			}
+			_ = m.cfg.WebhookBodyTemplate.Close()
+			bodyTmpl = string(tmpl)
+		}
+
+		notifier, err := webhook.NewNotifier(webhook.Config{
+			URL:                m.cfg.WebhookURL,
+			BodyTemplate:       bodyTmpl,
+			Headers:            m.cfg.WebhookHeaders,
+			HMACSecret:         m.cfg.WebhookHMACSecret,
+			SuccessStatusCodes: m.cfg.WebhookSuccessStatusCodes,
+			Logger:             m.logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+
+	// Email.
+	if m.cfg.EmailSMTPAddress != "" {
+		notifier, err := email.NewNotifier(email.Config{
+			SMTPAddress:        m.cfg.EmailSMTPAddress,
+			TLSMode:            email.TLSMode(m.cfg.EmailTLSMode),
+			InsecureSkipVerify: m.cfg.EmailInsecureSkipVerify,
+			Username:           m.cfg.EmailUsername,
+			Password:           m.cfg.EmailPassword,
+			From:               m.cfg.EmailFrom,
+			DefaultRecipients:  m.cfg.EmailTo,
+			TemplateRenderer:   htmlTmplRenderer,
+			Logger:             m.logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+
+	return notifiers, nil
+}
+
+// parseMatchers parses Alertmanager style label matchers.
+func parseMatchers(ms []string) ([]*forward.Matcher, error) {
+	matchers := make([]*forward.Matcher, 0, len(ms))
+	for _, m := range ms {
+		matcher, err := forward.ParseMatcher(m)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	return matchers, nil
+}
+
 func main() {
 	m := Main{}
 	if err := m.Run(); err != nil {
@@ -0,0 +1,285 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/model"
+)
+
+var (
+	// ErrComm will be used when the communication to Alertmanager fails.
+	ErrComm = errors.New("error communicating with alertmanager")
+)
+
+// Matcher is a silence label matcher.
+type Matcher struct {
+	Name    string
+	Value   string
+	IsRegex bool
+	IsEqual bool
+}
+
+// Silence is an Alertmanager silence.
+type Silence struct {
+	Matchers  []Matcher
+	StartsAt  time.Time
+	EndsAt    time.Time
+	CreatedBy string
+	Comment   string
+}
+
+// Client knows how to use the Alertmanager API.
+type Client interface {
+	// GetAlerts returns the alerts (including the silenced and inhibited ones). The
+	// suppressed (silenced or inhibited) alerts are not firing, they have an unknown status.
+	GetAlerts(ctx context.Context) ([]model.Alert, error)
+	// GetAlertGroups returns the alert groups of the alerts that match the filter
+	// matchers and the receiver regex (optional). The suppressed (silenced or
+	// inhibited) alerts are not firing, they have an unknown status.
+	GetAlertGroups(ctx context.Context, filter []string, receiver string) ([]model.AlertGroup, error)
+	// CreateSilence creates a silence and returns its ID.
+	CreateSilence(ctx context.Context, s Silence) (string, error)
+}
+
+// Config is the configuration of the Client.
+type Config struct {
+	// URL is the Alertmanager URL (e.g `http://alertmanager:9093`).
+	URL string
+	// HTTPClient is the HTTP client used to communicate with Alertmanager.
+	HTTPClient *http.Client
+}
+
+func (c *Config) defaults() error {
+	if c.URL == "" {
+		return fmt.Errorf("alertmanager URL is required")
+	}
+	c.URL = strings.TrimSuffix(c.URL, "/")
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+
+	return nil
+}
+
+type client struct {
+	cfg Config
+}
+
+// NewClient returns a new Alertmanager v2 API client.
+//
+// More info here: https://github.com/prometheus/alertmanager/blob/master/api/v2/openapi.yaml.
+func NewClient(cfg Config) (Client, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &client{cfg: cfg}, nil
+}
+
+type apiAlert struct {
+	Fingerprint  string            `json:"fingerprint"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	GeneratorURL string            `json:"generatorURL"`
+	Status       struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// toModel maps the alert to the model, only the active alerts are firing, the
+// suppressed and unprocessed alerts have an unknown status.
+func (a apiAlert) toModel() model.Alert {
+	status := model.AlertStatusFiring
+	if a.Status.State != "active" {
+		status = model.AlertStatusUnknown
+	}
+
+	return model.Alert{
+		ID:           a.Fingerprint,
+		Name:         a.Labels["alertname"],
+		StartsAt:     a.StartsAt,
+		EndsAt:       a.EndsAt,
+		Status:       status,
+		Labels:       a.Labels,
+		Annotations:  a.Annotations,
+		GeneratorURL: a.GeneratorURL,
+	}
+}
+
+func (c client) GetAlerts(ctx context.Context) ([]model.Alert, error) {
+	body, err := c.do(ctx, http.MethodGet, "/api/v2/alerts", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	apiAlerts := []apiAlert{}
+	err = json.Unmarshal(body, &apiAlerts)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode alertmanager alerts: %w", err)
+	}
+
+	alerts := make([]model.Alert, 0, len(apiAlerts))
+	for _, a := range apiAlerts {
+		alerts = append(alerts, a.toModel())
+	}
+
+	return alerts, nil
+}
+
+type apiAlertGroup struct {
+	Labels   map[string]string `json:"labels"`
+	Receiver struct {
+		Name string `json:"name"`
+	} `json:"receiver"`
+	Alerts []apiAlert `json:"alerts"`
+}
+
+func (c client) GetAlertGroups(ctx context.Context, filter []string, receiver string) ([]model.AlertGroup, error) {
+	q := url.Values{}
+	for _, f := range filter {
+		q.Add("filter", f)
+	}
+	if receiver != "" {
+		q.Set("receiver", receiver)
+	}
+
+	path := "/api/v2/alerts/groups"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	body, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	apiGroups := []apiAlertGroup{}
+	err = json.Unmarshal(body, &apiGroups)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode alertmanager alert groups: %w", err)
+	}
+
+	groups := make([]model.AlertGroup, 0, len(apiGroups))
+	for _, g := range apiGroups {
+		alerts := make([]model.Alert, 0, len(g.Alerts))
+		for _, a := range g.Alerts {
+			alerts = append(alerts, a.toModel())
+		}
+
+		// The API doesn't have the group key, the groups are identified
+		// by the receiver and the group labels.
+		groups = append(groups, model.AlertGroup{
+			ID:          g.Receiver.Name + ":" + labelsString(g.Labels),
+			Labels:      g.Labels,
+			Alerts:      alerts,
+			Receiver:    g.Receiver.Name,
+			ExternalURL: c.cfg.URL,
+		})
+	}
+
+	return groups, nil
+}
+
+// labelsString returns the labels in a stable format, e.g: `{team="a", severity="critical"}`.
+func labelsString(labels map[string]string) string {
+	ls := make(prommodel.LabelSet, len(labels))
+	for k, v := range labels {
+		ls[prommodel.LabelName(k)] = prommodel.LabelValue(v)
+	}
+
+	return ls.String()
+}
+
+type apiMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type apiSilence struct {
+	Matchers  []apiMatcher `json:"matchers"`
+	StartsAt  time.Time    `json:"startsAt"`
+	EndsAt    time.Time    `json:"endsAt"`
+	CreatedBy string       `json:"createdBy"`
+	Comment   string       `json:"comment"`
+}
+
+func (c client) CreateSilence(ctx context.Context, s Silence) (string, error) {
+	if len(s.Matchers) == 0 {
+		return "", fmt.Errorf("silence without matchers: %w", internalerrors.ErrInvalidConfiguration)
+	}
+
+	as := apiSilence{
+		StartsAt:  s.StartsAt,
+		EndsAt:    s.EndsAt,
+		CreatedBy: s.CreatedBy,
+		Comment:   s.Comment,
+	}
+	for _, m := range s.Matchers {
+		as.Matchers = append(as.Matchers, apiMatcher(m))
+	}
+
+	data, err := json.Marshal(as)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal alertmanager silence: %w", err)
+	}
+
+	body, err := c.do(ctx, http.MethodPost, "/api/v2/silences", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	res := struct {
+		SilenceID string `json:"silenceID"`
+	}{}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return "", fmt.Errorf("could not decode alertmanager silence response: %w", err)
+	}
+
+	return res.SilenceID, nil
+}
+
+func (c client) do(ctx context.Context, method, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.URL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrComm, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrComm, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status code %d: %s", ErrComm, resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
@@ -0,0 +1,237 @@
+package alertmanager_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/alertmanager"
+	"github.com/slok/alertgram/internal/model"
+)
+
+func TestClientGetAlerts(t *testing.T) {
+	tests := map[string]struct {
+		srvStatus int
+		srvBody   string
+		expAlerts []model.Alert
+		expErr    error
+	}{
+		"The alerts should be mapped to the model.": {
+			srvStatus: http.StatusOK,
+			srvBody: `[{
+				"fingerprint": "fp1",
+				"startsAt": "2021-01-02T03:04:05Z",
+				"endsAt": "2021-01-02T04:04:05Z",
+				"labels": {"alertname": "ServicePodIsRestarting", "pod": "pod-1"},
+				"annotations": {"message": "restarting"},
+				"generatorURL": "https://prometheus.test/graph",
+				"status": {"state": "active"}
+			},
+			{
+				"fingerprint": "fp2",
+				"labels": {"alertname": "ServicePodIsRestarting", "pod": "pod-2"},
+				"status": {"state": "suppressed"}
+			}]`,
+			expAlerts: []model.Alert{
+				{
+					ID:           "fp1",
+					Name:         "ServicePodIsRestarting",
+					StartsAt:     time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+					EndsAt:       time.Date(2021, 1, 2, 4, 4, 5, 0, time.UTC),
+					Status:       model.AlertStatusFiring,
+					Labels:       map[string]string{"alertname": "ServicePodIsRestarting", "pod": "pod-1"},
+					Annotations:  map[string]string{"message": "restarting"},
+					GeneratorURL: "https://prometheus.test/graph",
+				},
+				{
+					ID:     "fp2",
+					Name:   "ServicePodIsRestarting",
+					Status: model.AlertStatusUnknown,
+					Labels: map[string]string{"alertname": "ServicePodIsRestarting", "pod": "pod-2"},
+				},
+			},
+		},
+
+		"A error from alertmanager should be processed with communication error.": {
+			srvStatus: http.StatusInternalServerError,
+			expErr:    alertmanager.ErrComm,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal("/api/v2/alerts", r.URL.Path)
+				w.WriteHeader(test.srvStatus)
+				_, _ = w.Write([]byte(test.srvBody))
+			}))
+			defer srv.Close()
+
+			cli, err := alertmanager.NewClient(alertmanager.Config{URL: srv.URL})
+			require.NoError(err)
+			gotAlerts, err := cli.GetAlerts(context.TODO())
+
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				assert.Equal(test.expAlerts, gotAlerts)
+			}
+		})
+	}
+}
+
+func TestClientGetAlertGroups(t *testing.T) {
+	tests := map[string]struct {
+		filter    []string
+		receiver  string
+		srvStatus int
+		srvBody   string
+		expQuery  string
+		expGroups []model.AlertGroup
+		expErr    error
+	}{
+		"The alert groups should be mapped to the model.": {
+			srvStatus: http.StatusOK,
+			srvBody: `[{
+				"labels": {"alertname": "ServicePodIsRestarting"},
+				"receiver": {"name": "telegram"},
+				"alerts": [
+					{
+						"fingerprint": "fp1",
+						"startsAt": "2021-01-02T03:04:05Z",
+						"endsAt": "2021-01-02T04:04:05Z",
+						"labels": {"alertname": "ServicePodIsRestarting", "pod": "pod-1"},
+						"annotations": {"message": "restarting"},
+						"generatorURL": "https://prometheus.test/graph",
+						"status": {"state": "active"}
+					},
+					{
+						"fingerprint": "fp2",
+						"startsAt": "2021-01-02T03:04:05Z",
+						"endsAt": "2021-01-02T04:04:05Z",
+						"labels": {"alertname": "ServicePodIsRestarting", "pod": "pod-2"},
+						"annotations": {},
+						"status": {"state": "suppressed"}
+					}
+				]
+			}]`,
+			expGroups: []model.AlertGroup{
+				{
+					ID:       `telegram:{alertname="ServicePodIsRestarting"}`,
+					Labels:   map[string]string{"alertname": "ServicePodIsRestarting"},
+					Receiver: "telegram",
+					Alerts: []model.Alert{
+						{
+							ID:           "fp1",
+							Name:         "ServicePodIsRestarting",
+							StartsAt:     time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+							EndsAt:       time.Date(2021, 1, 2, 4, 4, 5, 0, time.UTC),
+							Status:       model.AlertStatusFiring,
+							Labels:       map[string]string{"alertname": "ServicePodIsRestarting", "pod": "pod-1"},
+							Annotations:  map[string]string{"message": "restarting"},
+							GeneratorURL: "https://prometheus.test/graph",
+						},
+						{
+							ID:          "fp2",
+							Name:        "ServicePodIsRestarting",
+							StartsAt:    time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+							EndsAt:      time.Date(2021, 1, 2, 4, 4, 5, 0, time.UTC),
+							Status:      model.AlertStatusUnknown,
+							Labels:      map[string]string{"alertname": "ServicePodIsRestarting", "pod": "pod-2"},
+							Annotations: map[string]string{},
+						},
+					},
+				},
+			},
+		},
+
+		"The filter and the receiver should be sent to alertmanager.": {
+			filter:    []string{`team="a"`, `severity=~"critical|warning"`},
+			receiver:  "telegram.*",
+			srvStatus: http.StatusOK,
+			srvBody:   `[]`,
+			expQuery:  `filter=team%3D%22a%22&filter=severity%3D~%22critical%7Cwarning%22&receiver=telegram.%2A`,
+			expGroups: []model.AlertGroup{},
+		},
+
+		"A error from alertmanager should be processed with communication error.": {
+			srvStatus: http.StatusInternalServerError,
+			expErr:    alertmanager.ErrComm,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal("/api/v2/alerts/groups", r.URL.Path)
+				assert.Equal(test.expQuery, r.URL.RawQuery)
+				w.WriteHeader(test.srvStatus)
+				_, _ = w.Write([]byte(test.srvBody))
+			}))
+			defer srv.Close()
+
+			cli, err := alertmanager.NewClient(alertmanager.Config{URL: srv.URL})
+			require.NoError(err)
+			gotGroups, err := cli.GetAlertGroups(context.TODO(), test.filter, test.receiver)
+
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				// The external URL is the Alertmanager URL.
+				for i := range test.expGroups {
+					test.expGroups[i].ExternalURL = srv.URL
+				}
+				assert.Equal(test.expGroups, gotGroups)
+			}
+		})
+	}
+}
+
+func TestClientCreateSilence(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(body)
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("/api/v2/silences", r.URL.Path)
+		_, _ = w.Write([]byte(`{"silenceID": "silence-1"}`))
+	}))
+	defer srv.Close()
+
+	cli, err := alertmanager.NewClient(alertmanager.Config{URL: srv.URL + "/"})
+	require.NoError(err)
+	id, err := cli.CreateSilence(context.TODO(), alertmanager.Silence{
+		Matchers:  []alertmanager.Matcher{{Name: "alertname", Value: "ServicePodIsRestarting", IsEqual: true}},
+		StartsAt:  time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		EndsAt:    time.Date(2021, 1, 2, 4, 4, 5, 0, time.UTC),
+		CreatedBy: "@alice",
+		Comment:   "Silenced from Telegram",
+	})
+	require.NoError(err)
+
+	assert.Equal("silence-1", id)
+	expBody := `{
+		"matchers": [{"name": "alertname", "value": "ServicePodIsRestarting", "isRegex": false, "isEqual": true}],
+		"startsAt": "2021-01-02T03:04:05Z",
+		"endsAt": "2021-01-02T04:04:05Z",
+		"createdBy": "@alice",
+		"comment": "Silenced from Telegram"
+	}`
+	assert.JSONEq(expBody, gotBody)
+}
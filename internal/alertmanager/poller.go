@@ -0,0 +1,187 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+)
+
+// PollerConfig is the configuration of the Poller.
+type PollerConfig struct {
+	// Client is the Alertmanager client used to get the alerts.
+	Client Client
+	// ForwardService is the service that will forward the alerts.
+	ForwardService forward.Service
+	// Filter are the Alertmanager matchers of the alerts that will be forwarded
+	// (e.g `severity="critical"`), by default all.
+	Filter []string
+	// Receiver is the regex of the Alertmanager receivers of the alerts that will
+	// be forwarded, by default all.
+	Receiver string
+	// Interval is the interval between the polls, by default 30s.
+	Interval time.Duration
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *PollerConfig) defaults() error {
+	if c.Client == nil {
+		return fmt.Errorf("alertmanager client is required")
+	}
+
+	if c.ForwardService == nil {
+		return fmt.Errorf("forward service is required")
+	}
+
+	if c.Interval == 0 {
+		c.Interval = 30 * time.Second
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+// Poller gets the alerts from the Alertmanager API periodically and forwards
+// the alert groups that have new firing or resolved alerts since the previous poll.
+type Poller struct {
+	cfg    PollerConfig
+	client Client
+	logger log.Logger
+
+	// firing are the groups with their firing alerts on the previous poll.
+	firing map[string]model.AlertGroup
+}
+
+// NewPoller returns a new Poller.
+func NewPoller(cfg PollerConfig) (*Poller, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &Poller{
+		cfg:    cfg,
+		client: cfg.Client,
+		logger: cfg.Logger.WithValues(log.KV{"service": "alertmanager-poller"}),
+		firing: map[string]model.AlertGroup{},
+	}, nil
+}
+
+// Run polls the alerts until the context is done. The alerts that are firing on
+// the first poll will be forwarded.
+func (p *Poller) Run(ctx context.Context) error {
+	t := time.NewTicker(p.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		err := p.Poll(ctx)
+		if err != nil {
+			p.logger.Errorf("could not poll alertmanager alerts: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Poll gets the alerts and forwards the alert groups that have changed since the
+// previous poll. The alert groups that could not be forwarded will be retried on
+// the next poll.
+func (p *Poller) Poll(ctx context.Context) error {
+	groups, err := p.client.GetAlertGroups(ctx, p.cfg.Filter, p.cfg.Receiver)
+	if err != nil {
+		return err
+	}
+
+	current := map[string]model.AlertGroup{}
+	for _, g := range groups {
+		current[g.ID] = g
+	}
+
+	// The groups that are not anymore on Alertmanager have all their alerts resolved.
+	for id, g := range p.firing {
+		if _, ok := current[id]; !ok {
+			g.Alerts = nil
+			current[id] = g
+		}
+	}
+
+	firing := map[string]model.AlertGroup{}
+	for id, g := range current {
+		ag, changed := p.diff(g)
+		if changed {
+			err := p.cfg.ForwardService.Forward(ctx, forward.Properties{}, ag)
+			if err != nil {
+				p.logger.WithValues(log.KV{"alertGroup": id}).Errorf("could not forward alert group: %s", err)
+				// Keep the previous state so it's retried on the next poll.
+				if prev, ok := p.firing[id]; ok {
+					firing[id] = prev
+				}
+				continue
+			}
+		}
+
+		if f := ag.FiringAlerts(); len(f) > 0 {
+			g := *ag
+			g.Alerts = f
+			firing[id] = g
+		}
+	}
+	p.firing = firing
+
+	return nil
+}
+
+// diff returns the alert group with the firing alerts and the alerts resolved since
+// the previous poll, and if the group has new firing or resolved alerts. The suppressed
+// alerts are not firing but they are not resolved.
+func (p *Poller) diff(g model.AlertGroup) (*model.AlertGroup, bool) {
+	prevFiring := map[string]bool{}
+	for _, a := range p.firing[g.ID].Alerts {
+		prevFiring[a.ID] = true
+	}
+
+	changed := false
+	present := map[string]bool{}
+	alerts := []model.Alert{}
+	for _, a := range g.Alerts {
+		present[a.ID] = true
+		if !a.IsFiring() {
+			continue
+		}
+
+		alerts = append(alerts, a)
+		if !prevFiring[a.ID] {
+			changed = true
+		}
+	}
+
+	for _, a := range p.firing[g.ID].Alerts {
+		if present[a.ID] {
+			continue
+		}
+
+		a.Status = model.AlertStatusResolved
+		alerts = append(alerts, a)
+		changed = true
+	}
+
+	g.Alerts = alerts
+	g.Status = model.AlertStatusResolved
+	if g.HasFiring() {
+		g.Status = model.AlertStatusFiring
+	}
+
+	return &g, changed
+}
@@ -0,0 +1,184 @@
+package alertmanager_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/alertmanager"
+	"github.com/slok/alertgram/internal/forward"
+	alertmanagermock "github.com/slok/alertgram/internal/mocks/alertmanager"
+	forwardmock "github.com/slok/alertgram/internal/mocks/forward"
+	"github.com/slok/alertgram/internal/model"
+)
+
+func newPollAlert(id string, st model.AlertStatus) model.Alert {
+	return model.Alert{
+		ID:     id,
+		Name:   "ServicePodIsRestarting",
+		Status: st,
+		Labels: map[string]string{"alertname": "ServicePodIsRestarting", "pod": id},
+	}
+}
+
+func newPollGroup(alerts ...model.Alert) model.AlertGroup {
+	ag := model.AlertGroup{
+		ID:       `telegram:{alertname="ServicePodIsRestarting"}`,
+		Labels:   map[string]string{"alertname": "ServicePodIsRestarting"},
+		Alerts:   alerts,
+		Receiver: "telegram",
+		Status:   model.AlertStatusResolved,
+	}
+	if ag.HasFiring() {
+		ag.Status = model.AlertStatusFiring
+	}
+
+	return ag
+}
+
+func TestPollerPoll(t *testing.T) {
+	firing := model.AlertStatusFiring
+	resolved := model.AlertStatusResolved
+	suppressed := model.AlertStatusUnknown
+
+	tests := map[string]struct {
+		polls  [][]model.AlertGroup
+		mock   func(mf *forwardmock.Service)
+		expErr bool
+	}{
+		"The firing alerts of the first poll should be forwarded.": {
+			polls: [][]model.AlertGroup{
+				{newPollGroup(newPollAlert("a1", firing))},
+			},
+			mock: func(mf *forwardmock.Service) {
+				exp := newPollGroup(newPollAlert("a1", firing))
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp).Once().Return(nil)
+			},
+		},
+
+		"The groups without changes should not be forwarded again.": {
+			polls: [][]model.AlertGroup{
+				{newPollGroup(newPollAlert("a1", firing))},
+				{newPollGroup(newPollAlert("a1", firing))},
+			},
+			mock: func(mf *forwardmock.Service) {
+				exp := newPollGroup(newPollAlert("a1", firing))
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp).Once().Return(nil)
+			},
+		},
+
+		"The groups with new firing alerts should be forwarded with all the firing alerts.": {
+			polls: [][]model.AlertGroup{
+				{newPollGroup(newPollAlert("a1", firing))},
+				{newPollGroup(newPollAlert("a1", firing), newPollAlert("a2", firing))},
+			},
+			mock: func(mf *forwardmock.Service) {
+				exp1 := newPollGroup(newPollAlert("a1", firing))
+				exp2 := newPollGroup(newPollAlert("a1", firing), newPollAlert("a2", firing))
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp1).Once().Return(nil)
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp2).Once().Return(nil)
+			},
+		},
+
+		"The alerts that are not on alertmanager anymore should be forwarded as resolved.": {
+			polls: [][]model.AlertGroup{
+				{newPollGroup(newPollAlert("a1", firing), newPollAlert("a2", firing))},
+				{newPollGroup(newPollAlert("a1", firing))},
+			},
+			mock: func(mf *forwardmock.Service) {
+				exp1 := newPollGroup(newPollAlert("a1", firing), newPollAlert("a2", firing))
+				exp2 := newPollGroup(newPollAlert("a1", firing), newPollAlert("a2", resolved))
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp1).Once().Return(nil)
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp2).Once().Return(nil)
+			},
+		},
+
+		"The groups that are not on alertmanager anymore should be forwarded as resolved.": {
+			polls: [][]model.AlertGroup{
+				{newPollGroup(newPollAlert("a1", firing))},
+				{},
+				{},
+			},
+			mock: func(mf *forwardmock.Service) {
+				exp1 := newPollGroup(newPollAlert("a1", firing))
+				exp2 := newPollGroup(newPollAlert("a1", resolved))
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp1).Once().Return(nil)
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp2).Once().Return(nil)
+			},
+		},
+
+		"The suppressed alerts should not be forwarded as firing nor resolved.": {
+			polls: [][]model.AlertGroup{
+				{newPollGroup(newPollAlert("a1", firing), newPollAlert("a2", suppressed))},
+				{newPollGroup(newPollAlert("a1", suppressed), newPollAlert("a2", suppressed))},
+				{newPollGroup(newPollAlert("a2", suppressed))},
+			},
+			mock: func(mf *forwardmock.Service) {
+				exp := newPollGroup(newPollAlert("a1", firing))
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp).Once().Return(nil)
+			},
+		},
+
+		"The groups that could not be forwarded should be retried on the next poll.": {
+			polls: [][]model.AlertGroup{
+				{newPollGroup(newPollAlert("a1", firing))},
+				{newPollGroup(newPollAlert("a1", firing))},
+			},
+			mock: func(mf *forwardmock.Service) {
+				exp := newPollGroup(newPollAlert("a1", firing))
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp).Once().Return(errors.New("whatever"))
+				mf.On("Forward", mock.Anything, forward.Properties{}, &exp).Once().Return(nil)
+			},
+		},
+
+		"An error getting the alerts should fail.": {
+			polls:  [][]model.AlertGroup{nil},
+			mock:   func(mf *forwardmock.Service) {},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mc := &alertmanagermock.Client{}
+			filter := []string{`severity="critical"`}
+			for _, groups := range test.polls {
+				var err error
+				if groups == nil {
+					err = errors.New("whatever")
+				}
+				mc.On("GetAlertGroups", mock.Anything, filter, "telegram").Once().Return(groups, err)
+			}
+			mf := &forwardmock.Service{}
+			test.mock(mf)
+
+			// Execute.
+			p, err := alertmanager.NewPoller(alertmanager.PollerConfig{
+				Client:         mc,
+				ForwardService: mf,
+				Filter:         filter,
+				Receiver:       "telegram",
+			})
+			require.NoError(err)
+			for range test.polls {
+				err = p.Poll(context.TODO())
+			}
+
+			// Check.
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				mc.AssertExpectations(t)
+				mf.AssertExpectations(t)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package deadmansswitch
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/slok/alertgram/internal/forward"
@@ -20,6 +21,21 @@ type Service interface {
 	// PushSwitch will disable the dead man's switch when it's pushed and reset
 	// the interval for activation.
 	PushSwitch(ctx context.Context, alertGroup *model.AlertGroup) error
+	// Status returns the current status of the dead man's switch.
+	Status(ctx context.Context) (Status, error)
+}
+
+// Status is the status of a dead man's switch.
+type Status struct {
+	// Enabled is true if the dead man's switch is running.
+	Enabled bool
+	// Interval is the interval the switch needs to be pushed.
+	Interval time.Duration
+	// LastPush is the last time the switch was pushed (or started).
+	LastPush time.Time
+	// Active is true when the switch has been activated because it has
+	// not been pushed in the interval.
+	Active bool
 }
 
 // Config is the Service configuration.
@@ -42,6 +58,9 @@ type service struct {
 	dmsSwitch chan *model.AlertGroup
 	notifiers []forward.Notifier
 	logger    log.Logger
+
+	mu     sync.Mutex
+	status Status
 }
 
 // NewService returns a Dead mans's switch service.
@@ -57,6 +76,11 @@ func NewService(ctx context.Context, cfg Config) (Service, error) {
 		dmsSwitch: make(chan *model.AlertGroup, 1),
 		notifiers: cfg.Notifiers,
 		logger:    cfg.Logger.WithValues(log.KV{"service": "deadMansSwitch"}),
+		status: Status{
+			Enabled:  true,
+			Interval: cfg.Interval,
+			LastPush: time.Now(),
+		},
 	}
 	go s.startDMS(ctx)
 
@@ -70,9 +94,23 @@ func (s *service) PushSwitch(_ context.Context, alertGroup *model.AlertGroup) er
 	return nil
 }
 
+func (s *service) Status(_ context.Context) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status, nil
+}
+
+func (s *service) setActive(active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Active = active
+	if !active {
+		s.status.LastPush = time.Now()
+	}
+}
+
 func (s *service) activate(ctx context.Context) error {
 	dmsNotification := forward.Notification{
-		ChatID: s.cfg.CustomChatID,
 		AlertGroup: model.AlertGroup{
 			ID: "DeadMansSwitchActive",
 			Alerts: []model.Alert{
@@ -94,8 +132,14 @@ func (s *service) activate(ctx context.Context) error {
 		},
 	}
 
-	// TODO(slok): Add concurrency using workers.
+	notifierTypes := make([]string, 0, len(s.notifiers))
 	for _, not := range s.notifiers {
+		notifierTypes = append(notifierTypes, not.Type())
+	}
+
+	// TODO(slok): Add concurrency using workers.
+	for i, not := range s.notifiers {
+		dmsNotification.ChatID = forward.NotifierChatID(s.cfg.CustomChatID, notifierTypes[i], notifierTypes)
 		err := not.Notify(ctx, dmsNotification)
 		if err != nil {
 			s.logger.WithValues(log.KV{"notifier": not.Type(), "alertGroupID": dmsNotification.AlertGroup.ID}).
@@ -121,12 +165,14 @@ func (s *service) startDMS(ctx context.Context) {
 			return
 		case <-time.After(s.cfg.Interval):
 			logger.Infof("no switch pushed during interval wait, dead mans switch activated!")
+			s.setActive(true)
 			err := s.activate(ctx)
 			if err != nil {
 				logger.Errorf("something happened when activating the dead man's switch")
 			}
 		case <-s.dmsSwitch:
 			logger.Debugf("dead mans switch pushed, deactivated")
+			s.setActive(false)
 		}
 	}
 }
@@ -137,3 +183,4 @@ const DisabledService = dummyService(0)
 type dummyService int
 
 func (dummyService) PushSwitch(ctx context.Context, alertGroup *model.AlertGroup) error { return nil }
+func (dummyService) Status(ctx context.Context) (Status, error)                         { return Status{}, nil }
@@ -25,11 +25,11 @@ func TestServiceDeadMansSwitch(t *testing.T) {
 	}{
 		"If the alert is not received in the interval it should notify.": {
 			cfg: deadmansswitch.Config{
-				Interval: 10 * time.Millisecond,
+				Interval: 50 * time.Millisecond,
 			},
 			exec: func(svc deadmansswitch.Service) error {
 				// Give time to interval to act.
-				time.Sleep(15 * time.Millisecond)
+				time.Sleep(75 * time.Millisecond)
 				return nil
 			},
 			mock: func(ns []*forwardmock.Notifier) {
@@ -40,15 +40,36 @@ func TestServiceDeadMansSwitch(t *testing.T) {
 			},
 		},
 
+		"If the alert is not received in the interval it should notify the custom chat of the notifier.": {
+			cfg: deadmansswitch.Config{
+				CustomChatID: "email:ops@example.org",
+				Interval:     50 * time.Millisecond,
+			},
+			exec: func(svc deadmansswitch.Service) error {
+				// Give time to interval to act.
+				time.Sleep(75 * time.Millisecond)
+				return nil
+			},
+			mock: func(ns []*forwardmock.Notifier) {
+				isChat := func(chatID string) interface{} {
+					return mock.MatchedBy(func(n forward.Notification) bool { return n.ChatID == chatID })
+				}
+				ns[0].On("Type").Maybe().Return("telegram")
+				ns[0].On("Notify", mock.Anything, isChat("")).Once().Return(nil)
+				ns[1].On("Type").Maybe().Return("email")
+				ns[1].On("Notify", mock.Anything, isChat("ops@example.org")).Once().Return(nil)
+			},
+		},
+
 		"If the alert is received in the interval it should not notify.": {
 			cfg: deadmansswitch.Config{
-				Interval: 10 * time.Millisecond,
+				Interval: 50 * time.Millisecond,
 			},
 			exec: func(svc deadmansswitch.Service) error {
 				// Give time to interval to act.
-				time.Sleep(6 * time.Millisecond)
+				time.Sleep(30 * time.Millisecond)
 				err := svc.PushSwitch(context.TODO(), &model.AlertGroup{})
-				time.Sleep(6 * time.Millisecond)
+				time.Sleep(30 * time.Millisecond)
 				return err
 			},
 			mock: func(ns []*forwardmock.Notifier) {},
@@ -56,17 +77,17 @@ func TestServiceDeadMansSwitch(t *testing.T) {
 
 		"If the alert is received and then stops being received in the interval it should not notify.": {
 			cfg: deadmansswitch.Config{
-				Interval: 10 * time.Millisecond,
+				Interval: 50 * time.Millisecond,
 			},
 			exec: func(svc deadmansswitch.Service) error {
-				time.Sleep(6 * time.Millisecond)
+				time.Sleep(30 * time.Millisecond)
 				err := svc.PushSwitch(context.TODO(), &model.AlertGroup{})
 				if err != nil {
 					return err
 				}
-				time.Sleep(6 * time.Millisecond)
+				time.Sleep(30 * time.Millisecond)
 				err = svc.PushSwitch(context.TODO(), &model.AlertGroup{})
-				time.Sleep(15 * time.Millisecond)
+				time.Sleep(75 * time.Millisecond)
 				return err
 			},
 			mock: func(ns []*forwardmock.Notifier) {
@@ -103,3 +124,62 @@ func TestServiceDeadMansSwitch(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceDeadMansSwitchStatus(t *testing.T) {
+	tests := map[string]struct {
+		interval  time.Duration
+		exec      func(svc deadmansswitch.Service) error
+		processed func(start time.Time, status deadmansswitch.Status) bool
+		expActive bool
+	}{
+		"If the alert is received in the interval it should not be active.": {
+			interval: time.Hour,
+			exec: func(svc deadmansswitch.Service) error {
+				return svc.PushSwitch(context.TODO(), &model.AlertGroup{})
+			},
+			processed: func(start time.Time, status deadmansswitch.Status) bool { return status.LastPush.After(start) },
+			expActive: false,
+		},
+
+		"If the alert is not received in the interval it should be active.": {
+			interval:  10 * time.Millisecond,
+			exec:      func(svc deadmansswitch.Service) error { return nil },
+			processed: func(start time.Time, status deadmansswitch.Status) bool { return status.Active },
+			expActive: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mn := &forwardmock.Notifier{}
+			mn.On("Notify", mock.Anything, mock.Anything).Maybe().Return(nil)
+			mn.On("Type").Maybe().Return("")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			svc, err := deadmansswitch.NewService(ctx, deadmansswitch.Config{
+				Interval:  test.interval,
+				Notifiers: []forward.Notifier{mn},
+			})
+			require.NoError(err)
+			start := time.Now()
+			err = test.exec(svc)
+			require.NoError(err)
+
+			// Wait until the switch has processed the push or the interval.
+			require.Eventually(func() bool {
+				status, err := svc.Status(context.TODO())
+				return err == nil && test.processed(start, status)
+			}, time.Second, time.Millisecond)
+
+			status, err := svc.Status(context.TODO())
+			require.NoError(err)
+			assert.True(status.Enabled)
+			assert.Equal(test.interval, status.Interval)
+			assert.Equal(test.expActive, status.Active)
+		})
+	}
+}
@@ -31,3 +31,10 @@ func (m measureService) PushSwitch(ctx context.Context, ag *model.AlertGroup) (e
 	}(time.Now())
 	return m.next.PushSwitch(ctx, ag)
 }
+
+func (m measureService) Status(ctx context.Context) (st Status, err error) {
+	defer func(t0 time.Time) {
+		m.rec.ObserveDMSServiceOpDuration(ctx, "Status", err == nil, time.Since(t0))
+	}(time.Now())
+	return m.next.Status(ctx)
+}
@@ -0,0 +1,127 @@
+package forward
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slok/alertgram/internal/log"
+)
+
+// dispatchJob is a notification that needs to be sent by a notifier.
+type dispatchJob struct {
+	notifier     Notifier
+	notification Notification
+}
+
+// dispatcher sends the notifications concurrently using a bounded number of workers.
+// The jobs are distributed to the workers by notifier and chat, this way the
+// notifications of the same notifier to the same chat are sent in order, and a
+// slow notifier or chat doesn't delay the others (unless they share the worker).
+type dispatcher struct {
+	workers []chan dispatchJob
+	timeout time.Duration
+	metrics DispatchMetricsRecorder
+	logger  log.Logger
+
+	// mu protects the workers queues from being closed while dispatching.
+	mu      sync.RWMutex
+	closed  bool
+	working sync.WaitGroup
+}
+
+const dispatchWorkerQueueSize = 100
+
+// errDispatcherClosed is used when the notifications are dispatched after closing the dispatcher.
+var errDispatcherClosed = errors.New("dispatcher is closed")
+
+func newDispatcher(workers int, timeout time.Duration, metrics DispatchMetricsRecorder, logger log.Logger) *dispatcher {
+	d := &dispatcher{
+		workers: make([]chan dispatchJob, 0, workers),
+		timeout: timeout,
+		metrics: metrics,
+		logger:  logger,
+	}
+
+	for i := 0; i < workers; i++ {
+		jobs := make(chan dispatchJob, dispatchWorkerQueueSize)
+		d.workers = append(d.workers, jobs)
+		d.working.Add(1)
+		go d.work(jobs)
+	}
+
+	return d
+}
+
+// dispatch queues the notification on the notifier and chat worker, it will only wait
+// if the worker queue is full. The notification is sent in background, so the send
+// doesn't depend on the received context (e.g the alerts HTTP request).
+func (d *dispatcher) dispatch(ctx context.Context, notifierID int, notifier Notifier, n Notification) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return errDispatcherClosed
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strconv.Itoa(notifierID) + "/" + n.ChatID))
+	worker := d.workers[h.Sum32()%uint32(len(d.workers))]
+
+	d.metrics.AddForwardDispatchQueued(ctx, 1)
+	select {
+	case worker <- dispatchJob{notifier: notifier, notification: n}:
+	case <-ctx.Done():
+		d.metrics.AddForwardDispatchQueued(ctx, -1)
+		d.logger.WithValues(log.KV{"notifier": notifier.Type(), "alertGroupID": n.AlertGroup.ID, "chatID": n.ChatID}).
+			Errorf("could not dispatch alert group: %s", ctx.Err())
+	}
+
+	return nil
+}
+
+// close stops accepting notifications and waits until the workers have sent the
+// queued notifications or the context is done.
+func (d *dispatcher) close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, jobs := range d.workers {
+			close(jobs)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.working.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *dispatcher) work(jobs <-chan dispatchJob) {
+	defer d.working.Done()
+	for job := range jobs {
+		d.metrics.AddForwardDispatchQueued(context.Background(), -1)
+		d.metrics.AddForwardDispatchInFlight(context.Background(), 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+		err := job.notifier.Notify(ctx, job.notification)
+		cancel()
+		if err != nil {
+			d.logger.WithValues(log.KV{"notifier": job.notifier.Type(), "alertGroupID": job.notification.AlertGroup.ID, "chatID": job.notification.ChatID}).
+				Errorf("could not notify alert group: %s", err)
+		}
+
+		d.metrics.AddForwardDispatchInFlight(context.Background(), -1)
+	}
+}
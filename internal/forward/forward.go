@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/slok/alertgram/internal/internalerrors"
 	"github.com/slok/alertgram/internal/log"
@@ -23,13 +24,24 @@ type Properties struct {
 type Service interface {
 	// Forward knows how to forward alerts from an input to an output.
 	Forward(ctx context.Context, props Properties, alertGroup *model.AlertGroup) error
+	// Close stops forwarding alerts and waits until the forwarded alerts are sent
+	// or the context is done.
+	Close(ctx context.Context) error
 }
 
 // ServiceConfig is the service configuration.
 type ServiceConfig struct {
 	AlertLabelChatID string
-	Notifiers        []Notifier
-	Logger           log.Logger
+	// RoutingTree is the optional routing tree used to select the chats and
+	// notifiers of the alerts that don't have the chat ID label.
+	RoutingTree *Route
+	Notifiers   []Notifier
+	// Workers is the number of notifications that can be sent concurrently, by default 10.
+	Workers int
+	// NotifyTimeout is the maximum duration of a notification send, by default 30s.
+	NotifyTimeout   time.Duration
+	MetricsRecorder DispatchMetricsRecorder
+	Logger          log.Logger
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -37,6 +49,22 @@ func (c *ServiceConfig) defaults() error {
 		return errors.New("notifiers can't be empty")
 	}
 
+	if c.Workers == 0 {
+		c.Workers = 10
+	}
+
+	if c.Workers < 0 {
+		return errors.New("workers can't be negative")
+	}
+
+	if c.NotifyTimeout == 0 {
+		c.NotifyTimeout = 30 * time.Second
+	}
+
+	if c.MetricsRecorder == nil {
+		c.MetricsRecorder = dummyRecorder
+	}
+
 	if c.Logger == nil {
 		c.Logger = log.Dummy
 	}
@@ -45,9 +73,11 @@ func (c *ServiceConfig) defaults() error {
 }
 
 type service struct {
-	cfg       ServiceConfig
-	notifiers []Notifier
-	logger    log.Logger
+	cfg           ServiceConfig
+	notifiers     []Notifier
+	notifierTypes []string
+	dispatcher    *dispatcher
+	logger        log.Logger
 }
 
 // NewService returns a new forward.Service.
@@ -58,10 +88,13 @@ func NewService(cfg ServiceConfig) (Service, error) {
 		return nil, fmt.Errorf("could not create forward service instance because invalid configuration: %w", err)
 	}
 
+	logger := cfg.Logger.WithValues(log.KV{"service": "forward.Service"})
 	return &service{
-		cfg:       cfg,
-		notifiers: cfg.Notifiers,
-		logger:    cfg.Logger.WithValues(log.KV{"service": "forward.Service"}),
+		cfg:           cfg,
+		notifiers:     cfg.Notifiers,
+		notifierTypes: notifierTypes(cfg.Notifiers),
+		dispatcher:    newDispatcher(cfg.Workers, cfg.NotifyTimeout, cfg.MetricsRecorder, logger),
+		logger:        logger,
 	}, nil
 }
 
@@ -76,18 +109,22 @@ func (s service) Forward(ctx context.Context, props Properties, alertGroup *mode
 		return fmt.Errorf("alertgroup can't be empty: %w", ErrInvalidAlertGroup)
 	}
 
-	notifications, err := s.createNotifications(props, alertGroup)
-	if err != nil {
-		return fmt.Errorf("could not prepare notifications from the alerts: %w", err)
+	// Prepare the notifications of each notifier.
+	notificationsByNotifier := make([][]*Notification, 0, len(s.notifiers))
+	for i := range s.notifiers {
+		notifications, err := s.createNotifications(props, alertGroup, s.notifierTypes[i])
+		if err != nil {
+			return fmt.Errorf("could not prepare notifications from the alerts: %w", err)
+		}
+		notificationsByNotifier = append(notificationsByNotifier, notifications)
 	}
 
-	// TODO(slok): Add concurrency using workers.
-	for _, notifier := range s.notifiers {
-		for _, notification := range notifications {
-			err := notifier.Notify(ctx, *notification)
+	// Queue the notifications, these will be sent concurrently in background.
+	for i, notifier := range s.notifiers {
+		for _, notification := range notificationsByNotifier[i] {
+			err := s.dispatcher.dispatch(ctx, i, notifier, *notification)
 			if err != nil {
-				s.logger.WithValues(log.KV{"notifier": notifier.Type(), "alertGroupID": alertGroup.ID, "chatID": notification.ChatID}).
-					Errorf("could not notify alert group: %s", err)
+				return fmt.Errorf("could not dispatch the notifications: %w", err)
 			}
 		}
 	}
@@ -95,37 +132,64 @@ func (s service) Forward(ctx context.Context, props Properties, alertGroup *mode
 	return nil
 }
 
-func (s service) createNotifications(props Properties, alertGroup *model.AlertGroup) (ns []*Notification, err error) {
-	// Decompose the alerts in groups by chat IDs based on the
-	// alert chat ID labels. If the alerts don't have the chat ID
-	// label they will remain on the default group.
+func (s service) Close(ctx context.Context) error {
+	err := s.dispatcher.close(ctx)
+	if err != nil {
+		return fmt.Errorf("could not send the dispatched notifications: %w", err)
+	}
+
+	return nil
+}
+
+func (s service) createNotifications(props Properties, alertGroup *model.AlertGroup, notifierType string) (ns []*Notification, err error) {
+	// Decompose the alerts in groups by chat IDs, an alert can be sent to multiple chats.
+	// The chats are selected with this preference: the alert chat ID label, the
+	// routing tree and the default chat. If the alerts don't have chat ID for the
+	// notifier they will remain on the default group.
 	agByChatID := map[string]*model.AlertGroup{}
 	for _, a := range alertGroup.Alerts {
-		chatID := a.Labels[s.cfg.AlertLabelChatID]
-		ag, ok := agByChatID[chatID]
-		if !ok {
-			id := alertGroup.ID
-			if chatID != "" {
-				id = fmt.Sprintf("%s-%s", alertGroup.ID, chatID)
+		seen := map[string]bool{}
+		for _, target := range s.alertTargets(alertGroup.Receiver, a) {
+			if !target.allowsNotifier(notifierType) {
+				continue
 			}
-			ag = &model.AlertGroup{
-				ID:     id,
-				Labels: alertGroup.Labels,
+			chatID := target.notifierChatID(notifierType, s.notifierTypes)
+			if seen[chatID] {
+				continue
 			}
-			agByChatID[chatID] = ag
-		}
+			seen[chatID] = true
 
-		ag.Alerts = append(ag.Alerts, a)
+			ag, ok := agByChatID[chatID]
+			if !ok {
+				id := alertGroup.ID
+				if chatID != "" {
+					id = fmt.Sprintf("%s-%s", alertGroup.ID, chatID)
+				}
+				group := *alertGroup
+				group.ID = id
+				group.Alerts = nil
+				ag = &group
+				agByChatID[chatID] = ag
+			}
+
+			ag.Alerts = append(ag.Alerts, a)
+		}
 	}
 
 	// Create notifications based on the alertgroups.
 	notifications := []*Notification{}
 	for chatID, ag := range agByChatID {
+		// The group data is only valid for the group with all the alerts, the split
+		// groups are a subset of the alerts so the group data needs to be recalculated.
+		if len(ag.Alerts) != len(alertGroup.Alerts) {
+			splitAlertGroupData(ag)
+		}
+
 		// If no custom alert based chat then fallback to
 		// properties custom chat (normally received by upper
 		// layers by URL).
 		if chatID == "" {
-			chatID = props.CustomChatID
+			chatID = NotifierChatID(props.CustomChatID, notifierType, s.notifierTypes)
 		}
 		notifications = append(notifications, &Notification{
 			AlertGroup: *ag,
@@ -135,3 +199,65 @@ func (s service) createNotifications(props Properties, alertGroup *model.AlertGr
 
 	return notifications, nil
 }
+
+// splitAlertGroupData recalculates the data of the alert group from its alerts, the
+// truncated alerts are unknown so they can't be part of a split group.
+func splitAlertGroupData(ag *model.AlertGroup) {
+	switch {
+	case ag.HasFiring():
+		ag.Status = model.AlertStatusFiring
+	case ag.HasResolved():
+		ag.Status = model.AlertStatusResolved
+	default:
+		ag.Status = model.AlertStatusUnknown
+	}
+
+	labels := make([]map[string]string, 0, len(ag.Alerts))
+	annotations := make([]map[string]string, 0, len(ag.Alerts))
+	for _, a := range ag.Alerts {
+		labels = append(labels, a.Labels)
+		annotations = append(annotations, a.Annotations)
+	}
+	ag.CommonLabels = commonKVs(labels)
+	ag.CommonAnnotations = commonKVs(annotations)
+	ag.TruncatedAlerts = 0
+}
+
+// commonKVs returns the key-values that are on all the maps.
+func commonKVs(kvs []map[string]string) map[string]string {
+	common := map[string]string{}
+	if len(kvs) == 0 {
+		return common
+	}
+
+	for k, v := range kvs[0] {
+		common[k] = v
+	}
+	for _, kv := range kvs[1:] {
+		for k, v := range common {
+			if cv, ok := kv[k]; !ok || cv != v {
+				delete(common, k)
+			}
+		}
+	}
+
+	return common
+}
+
+// alertTargets returns the targets of the alert.
+func (s service) alertTargets(receiver string, a model.Alert) []routeTarget {
+	if chatID := a.Labels[s.cfg.AlertLabelChatID]; chatID != "" {
+		return []routeTarget{{chatID: chatID}}
+	}
+
+	if s.cfg.RoutingTree == nil {
+		return []routeTarget{{}}
+	}
+
+	targets := s.cfg.RoutingTree.targets(receiver, a.Labels)
+	if len(targets) == 0 {
+		return []routeTarget{{}}
+	}
+
+	return targets
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -16,6 +17,14 @@ import (
 
 var errTest = errors.New("whatever")
 
+func mustLoadRoutingTree(config string) *forward.Route {
+	r, err := forward.LoadRoutingTree([]byte(config))
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
 func TestServiceForward(t *testing.T) {
 	tests := map[string]struct {
 		cfg        forward.ServiceConfig
@@ -100,6 +109,8 @@ func TestServiceForward(t *testing.T) {
 				expNotChatDef := forward.Notification{
 					ChatID: "-1001234567890",
 					AlertGroup: model.AlertGroup{ID: "test-group",
+						CommonLabels:      map[string]string{"test_chat_id": ""},
+						CommonAnnotations: map[string]string{},
 						Alerts: []model.Alert{
 							{Name: "test-1", Labels: map[string]string{"test_chat_id": ""}},
 							{Name: "test-7", Labels: map[string]string{"test_chat_id": ""}},
@@ -110,6 +121,8 @@ func TestServiceForward(t *testing.T) {
 				expNotChat1 := forward.Notification{
 					ChatID: "chat1",
 					AlertGroup: model.AlertGroup{ID: "test-group-chat1",
+						CommonLabels:      map[string]string{"test_chat_id": "chat1"},
+						CommonAnnotations: map[string]string{},
 						Alerts: []model.Alert{
 							{Name: "test-3", Labels: map[string]string{"test_chat_id": "chat1"}},
 							{Name: "test-3", Labels: map[string]string{"test_chat_id": "chat1"}},
@@ -120,6 +133,8 @@ func TestServiceForward(t *testing.T) {
 				expNotChat2 := forward.Notification{
 					ChatID: "chat2",
 					AlertGroup: model.AlertGroup{ID: "test-group-chat2",
+						CommonLabels:      map[string]string{"test_chat_id": "chat2"},
+						CommonAnnotations: map[string]string{},
 						Alerts: []model.Alert{
 							{Name: "test-2", Labels: map[string]string{"test_chat_id": "chat2"}},
 							{Name: "test-4", Labels: map[string]string{"test_chat_id": "chat2"}},
@@ -129,6 +144,8 @@ func TestServiceForward(t *testing.T) {
 				expNotChat3 := forward.Notification{
 					ChatID: "chat3",
 					AlertGroup: model.AlertGroup{ID: "test-group-chat3",
+						CommonLabels:      map[string]string{"test_chat_id": "chat3"},
+						CommonAnnotations: map[string]string{},
 						Alerts: []model.Alert{
 							{Name: "test-6", Labels: map[string]string{"test_chat_id": "chat3"}},
 						},
@@ -142,6 +159,215 @@ func TestServiceForward(t *testing.T) {
 				}
 			},
 		},
+
+		"Alerts grouped by chat should keep the alert group data.": {
+			cfg: forward.ServiceConfig{
+				AlertLabelChatID: "test_chat_id",
+			},
+			alertGroup: &model.AlertGroup{
+				ID:                "test-group",
+				Labels:            map[string]string{"team": "a"},
+				Receiver:          "telegram",
+				Status:            model.AlertStatusFiring,
+				CommonLabels:      map[string]string{"team": "a"},
+				CommonAnnotations: map[string]string{"runbook": "http://runbooks.test"},
+				ExternalURL:       "http://alertmanager.test",
+				TruncatedAlerts:   3,
+				Alerts: []model.Alert{
+					{Name: "test-1", Labels: map[string]string{"test_chat_id": "chat1"}},
+				},
+			},
+			mock: func(ns []*forwardmock.Notifier) {
+				expNotification := forward.Notification{
+					ChatID: "chat1",
+					AlertGroup: model.AlertGroup{
+						ID:                "test-group-chat1",
+						Labels:            map[string]string{"team": "a"},
+						Receiver:          "telegram",
+						Status:            model.AlertStatusFiring,
+						CommonLabels:      map[string]string{"team": "a"},
+						CommonAnnotations: map[string]string{"runbook": "http://runbooks.test"},
+						ExternalURL:       "http://alertmanager.test",
+						TruncatedAlerts:   3,
+						Alerts: []model.Alert{
+							{Name: "test-1", Labels: map[string]string{"test_chat_id": "chat1"}},
+						},
+					},
+				}
+				for _, n := range ns {
+					n.On("Notify", mock.Anything, expNotification).Once().Return(nil)
+				}
+			},
+		},
+
+		"Alerts split by chat should have the group data of their alerts.": {
+			cfg: forward.ServiceConfig{
+				AlertLabelChatID: "test_chat_id",
+			},
+			alertGroup: &model.AlertGroup{
+				ID:                "test-group",
+				Receiver:          "telegram",
+				Status:            model.AlertStatusFiring,
+				CommonLabels:      map[string]string{"team": "a"},
+				CommonAnnotations: map[string]string{},
+				TruncatedAlerts:   3,
+				Alerts: []model.Alert{
+					{Name: "test-1", Status: model.AlertStatusFiring, Labels: map[string]string{"team": "a", "env": "prod", "test_chat_id": "chat1"}, Annotations: map[string]string{"runbook": "http://runbooks.test"}},
+					{Name: "test-2", Status: model.AlertStatusResolved, Labels: map[string]string{"team": "a", "env": "dev", "test_chat_id": "chat2"}},
+					{Name: "test-3", Status: model.AlertStatusResolved, Labels: map[string]string{"team": "a", "env": "dev", "test_chat_id": "chat2"}},
+				},
+			},
+			mock: func(ns []*forwardmock.Notifier) {
+				expNotification1 := forward.Notification{
+					ChatID: "chat1",
+					AlertGroup: model.AlertGroup{
+						ID:                "test-group-chat1",
+						Receiver:          "telegram",
+						Status:            model.AlertStatusFiring,
+						CommonLabels:      map[string]string{"team": "a", "env": "prod", "test_chat_id": "chat1"},
+						CommonAnnotations: map[string]string{"runbook": "http://runbooks.test"},
+						Alerts: []model.Alert{
+							{Name: "test-1", Status: model.AlertStatusFiring, Labels: map[string]string{"team": "a", "env": "prod", "test_chat_id": "chat1"}, Annotations: map[string]string{"runbook": "http://runbooks.test"}},
+						},
+					},
+				}
+				expNotification2 := forward.Notification{
+					ChatID: "chat2",
+					AlertGroup: model.AlertGroup{
+						ID:                "test-group-chat2",
+						Receiver:          "telegram",
+						Status:            model.AlertStatusResolved,
+						CommonLabels:      map[string]string{"team": "a", "env": "dev", "test_chat_id": "chat2"},
+						CommonAnnotations: map[string]string{},
+						Alerts: []model.Alert{
+							{Name: "test-2", Status: model.AlertStatusResolved, Labels: map[string]string{"team": "a", "env": "dev", "test_chat_id": "chat2"}},
+							{Name: "test-3", Status: model.AlertStatusResolved, Labels: map[string]string{"team": "a", "env": "dev", "test_chat_id": "chat2"}},
+						},
+					},
+				}
+				for _, n := range ns {
+					n.On("Notify", mock.Anything, expNotification1).Once().Return(nil)
+					n.On("Notify", mock.Anything, expNotification2).Once().Return(nil)
+				}
+			},
+		},
+
+		"Alerts should be routed by the alert group receiver.": {
+			cfg: forward.ServiceConfig{
+				RoutingTree: mustLoadRoutingTree(`
+route:
+  chat_ids: ["chat-default"]
+  routes:
+    - receivers: ["team1", "team2"]
+      chat_ids: ["chat-teams"]
+`),
+			},
+			alertGroup: &model.AlertGroup{
+				ID:       "test-group",
+				Receiver: "team2",
+				Alerts:   []model.Alert{{Name: "test"}},
+			},
+			mock: func(ns []*forwardmock.Notifier) {
+				expNotification := forward.Notification{
+					ChatID: "chat-teams",
+					AlertGroup: model.AlertGroup{
+						ID:       "test-group-chat-teams",
+						Receiver: "team2",
+						Alerts:   []model.Alert{{Name: "test"}},
+					},
+				}
+				for _, n := range ns {
+					n.On("Notify", mock.Anything, expNotification).Once().Return(nil)
+				}
+			},
+		},
+
+		"Alerts should be routed to the chats and notifiers of the routing tree.": {
+			cfg: forward.ServiceConfig{
+				AlertLabelChatID: "chat_id",
+				RoutingTree: mustLoadRoutingTree(`
+route:
+  chat_ids: ["chat-default", "email:ops@test"]
+  routes:
+    - matchers: ['severity="critical"']
+      chat_ids: ["chat-critical"]
+      continue: true
+    - matchers: ['team=~"team1|team2"']
+      chat_ids: ["chat-teams"]
+      notifiers: ["telegram"]
+    - matchers: ['team!=""']
+      notifiers: ["email"]
+`),
+			},
+			props: forward.Properties{
+				CustomChatID: "-1001234567890",
+			},
+			alertGroup: &model.AlertGroup{
+				ID: "test-group",
+				Alerts: []model.Alert{
+					{Name: "test-1", Labels: map[string]string{"severity": "critical", "team": "team1"}},
+					{Name: "test-2", Labels: map[string]string{"team": "team3"}},
+					{Name: "test-3", Labels: map[string]string{}},
+					{Name: "test-4", Labels: map[string]string{"chat_id": "chat-label", "team": "team1"}},
+				},
+			},
+			mock: func(ns []*forwardmock.Notifier) {
+				al1 := model.Alert{Name: "test-1", Labels: map[string]string{"severity": "critical", "team": "team1"}}
+				al2 := model.Alert{Name: "test-2", Labels: map[string]string{"team": "team3"}}
+				al3 := model.Alert{Name: "test-3", Labels: map[string]string{}}
+				al4 := model.Alert{Name: "test-4", Labels: map[string]string{"chat_id": "chat-label", "team": "team1"}}
+				newNotification := func(groupID, chatID string, commonLabels map[string]string, alerts ...model.Alert) forward.Notification {
+					return forward.Notification{
+						ChatID: chatID,
+						AlertGroup: model.AlertGroup{
+							ID:                groupID,
+							Alerts:            alerts,
+							CommonLabels:      commonLabels,
+							CommonAnnotations: map[string]string{},
+						},
+					}
+				}
+
+				// Telegram notifier, uses the chat IDs without notifier type.
+				ns[0].On("Type").Maybe().Return("telegram")
+				ns[0].On("Notify", mock.Anything, newNotification("test-group-chat-critical", "chat-critical", al1.Labels, al1)).Once().Return(nil)
+				ns[0].On("Notify", mock.Anything, newNotification("test-group-chat-teams", "chat-teams", al1.Labels, al1)).Once().Return(nil)
+				ns[0].On("Notify", mock.Anything, newNotification("test-group-chat-default", "chat-default", al3.Labels, al3)).Once().Return(nil)
+				ns[0].On("Notify", mock.Anything, newNotification("test-group", "-1001234567890", al3.Labels, al3)).Once().Return(nil)
+				ns[0].On("Notify", mock.Anything, newNotification("test-group-chat-label", "chat-label", al4.Labels, al4)).Once().Return(nil)
+
+				// Email notifier, uses the email chat IDs and the chat IDs of the email only routes.
+				ns[1].On("Type").Maybe().Return("email")
+				ns[1].On("Notify", mock.Anything, newNotification("test-group", "", map[string]string{}, al1, al3, al4)).Once().Return(nil)
+				ns[1].On("Notify", mock.Anything, newNotification("test-group-chat-default", "chat-default", al2.Labels, al2)).Once().Return(nil)
+				ns[1].On("Notify", mock.Anything, newNotification("test-group-ops@test", "ops@test", map[string]string{}, al2, al3)).Once().Return(nil)
+			},
+		},
+
+		"A notifier that doesn't finish should be cancelled after the timeout.": {
+			cfg: forward.ServiceConfig{
+				NotifyTimeout: 10 * time.Millisecond,
+			},
+			alertGroup: &model.AlertGroup{
+				ID:     "test-group",
+				Alerts: []model.Alert{model.Alert{Name: "test"}},
+			},
+			mock: func(ns []*forwardmock.Notifier) {
+				expNotification := forward.Notification{
+					AlertGroup: model.AlertGroup{
+						ID:     "test-group",
+						Alerts: []model.Alert{model.Alert{Name: "test"}},
+					},
+				}
+				for _, n := range ns {
+					n.On("Notify", mock.Anything, expNotification).Once().Return(context.DeadlineExceeded).Run(func(args mock.Arguments) {
+						ctx := args.Get(0).(context.Context)
+						<-ctx.Done()
+					})
+					n.On("Type").Maybe().Return("")
+				}
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -152,6 +378,8 @@ func TestServiceForward(t *testing.T) {
 			mn1 := &forwardmock.Notifier{}
 			mn2 := &forwardmock.Notifier{}
 			test.mock([]*forwardmock.Notifier{mn1, mn2})
+			mn1.On("Type").Maybe().Return("")
+			mn2.On("Type").Maybe().Return("")
 
 			test.cfg.Notifiers = []forward.Notifier{mn1, mn2}
 			svc, err := forward.NewService(test.cfg)
@@ -159,6 +387,8 @@ func TestServiceForward(t *testing.T) {
 
 			err = svc.Forward(context.TODO(), test.props, test.alertGroup)
 
+			// The notifications are sent in background, closing waits until they are sent.
+			require.NoError(svc.Close(context.TODO()))
 			if test.expErr != nil && assert.Error(err) {
 				assert.True(errors.Is(err, test.expErr))
 			} else if assert.NoError(err) {
@@ -168,3 +398,126 @@ func TestServiceForward(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceForwardDoesntWaitNotifications(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ag := &model.AlertGroup{ID: "test-group", Alerts: []model.Alert{{Name: "test"}}}
+	release := make(chan struct{})
+	notified := make(chan error, 1)
+	mn := &forwardmock.Notifier{}
+	mn.On("Type").Maybe().Return("test")
+	mn.On("Notify", mock.Anything, mock.Anything).Once().Return(nil).Run(func(args mock.Arguments) {
+		<-release
+		notified <- args.Get(0).(context.Context).Err()
+	})
+
+	svc, err := forward.NewService(forward.ServiceConfig{Notifiers: []forward.Notifier{mn}})
+	require.NoError(err)
+
+	// Forward should return without waiting the send, and the send should not
+	// be cancelled when the forward context is done (e.g the request finished).
+	ctx, cancel := context.WithCancel(context.Background())
+	err = svc.Forward(ctx, forward.Properties{}, ag)
+	require.NoError(err)
+	cancel()
+	close(release)
+
+	select {
+	case err := <-notified:
+		assert.NoError(err)
+	case <-time.After(time.Second):
+		assert.Fail("notification not sent")
+	}
+	require.NoError(svc.Close(context.TODO()))
+}
+
+func TestServiceClose(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ag := &model.AlertGroup{ID: "test-group", Alerts: []model.Alert{{Name: "test"}}}
+	release := make(chan struct{})
+	mn := &forwardmock.Notifier{}
+	mn.On("Type").Maybe().Return("test")
+	mn.On("Notify", mock.Anything, mock.Anything).Times(3).Return(nil).Run(func(mock.Arguments) { <-release })
+
+	svc, err := forward.NewService(forward.ServiceConfig{Notifiers: []forward.Notifier{mn}, Workers: 1})
+	require.NoError(err)
+	for i := 0; i < 3; i++ {
+		require.NoError(svc.Forward(context.TODO(), forward.Properties{}, ag))
+	}
+
+	// Closing should wait until the queued notifications are sent, or the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = svc.Close(ctx)
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+
+	close(release)
+	require.NoError(svc.Close(context.TODO()))
+	mn.AssertExpectations(t)
+
+	// Closed services should not forward the alerts.
+	err = svc.Forward(context.TODO(), forward.Properties{}, ag)
+	assert.Error(err)
+}
+
+func TestNotifierChatID(t *testing.T) {
+	tests := map[string]struct {
+		target        string
+		notifierType  string
+		notifierTypes []string
+		expChatID     string
+	}{
+		"An empty target should use the notifier default chat.": {
+			target:        "",
+			notifierType:  "telegram",
+			notifierTypes: []string{"telegram", "email"},
+			expChatID:     "",
+		},
+
+		"A target with the notifier type should be used by the notifier without the type.": {
+			target:        "email:ops@example.org",
+			notifierType:  "email",
+			notifierTypes: []string{"telegram", "email"},
+			expChatID:     "ops@example.org",
+		},
+
+		"A target with other notifier type should use the notifier default chat.": {
+			target:        "email:ops@example.org",
+			notifierType:  "telegram",
+			notifierTypes: []string{"telegram", "email"},
+			expChatID:     "",
+		},
+
+		"A target without notifier type should be used by Telegram.": {
+			target:        "team1:-1001234567890",
+			notifierType:  "telegram",
+			notifierTypes: []string{"telegram", "email"},
+			expChatID:     "team1:-1001234567890",
+		},
+
+		"A target without notifier type should not be used by other notifiers.": {
+			target:        "-1001234567890",
+			notifierType:  "slack",
+			notifierTypes: []string{"telegram", "slack"},
+			expChatID:     "",
+		},
+
+		"A target without notifier type should be used by the only notifier.": {
+			target:        "!room:matrix.example.org",
+			notifierType:  "matrix",
+			notifierTypes: []string{"matrix"},
+			expChatID:     "!room:matrix.example.org",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			chatID := forward.NotifierChatID(test.target, test.notifierType, test.notifierTypes)
+			assert.Equal(t, test.expChatID, chatID)
+		})
+	}
+}
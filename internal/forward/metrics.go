@@ -32,6 +32,26 @@ func (m measureService) Forward(ctx context.Context, props Properties, ag *model
 	return m.next.Forward(ctx, props, ag)
 }
 
+func (m measureService) Close(ctx context.Context) (err error) {
+	defer func(t0 time.Time) {
+		m.rec.ObserveForwardServiceOpDuration(ctx, "Close", err == nil, time.Since(t0))
+	}(time.Now())
+	return m.next.Close(ctx)
+}
+
+// DispatchMetricsRecorder knows how to record metrics on forward.Service notifications dispatching.
+type DispatchMetricsRecorder interface {
+	AddForwardDispatchQueued(ctx context.Context, quantity int)
+	AddForwardDispatchInFlight(ctx context.Context, quantity int)
+}
+
+type dummyDispatchMetricsRecorder int
+
+const dummyRecorder = dummyDispatchMetricsRecorder(0)
+
+func (dummyDispatchMetricsRecorder) AddForwardDispatchQueued(context.Context, int)   {}
+func (dummyDispatchMetricsRecorder) AddForwardDispatchInFlight(context.Context, int) {}
+
 // NotifierMetricsRecorder knows how to record metrics on forward.Notifier.
 type NotifierMetricsRecorder interface {
 	ObserveForwardNotifierOpDuration(ctx context.Context, notifierType string, op string, success bool, t time.Duration)
@@ -2,6 +2,7 @@ package forward
 
 import (
 	"context"
+	"strings"
 
 	"github.com/slok/alertgram/internal/model"
 )
@@ -21,3 +22,46 @@ type Notifier interface {
 	Notify(ctx context.Context, notification Notification) error
 	Type() string
 }
+
+// telegramNotifierType is the notifier type of the chat IDs that don't have
+// notifier type, Telegram is the main notifier.
+const telegramNotifierType = "telegram"
+
+// NotifierChatID returns the chat ID of the notifier from a chat target. The targets can
+// select the notifier with its type as a prefix (e.g `email:oncall@example.org`), and the
+// targets without notifier type are for Telegram, or for the notifier if there is only one
+// notifier type. If the target is not for the notifier, the chat ID will be empty so the
+// notifier uses its default chat.
+func NotifierChatID(target, notifierType string, notifierTypes []string) string {
+	if target == "" {
+		return ""
+	}
+
+	types := map[string]bool{}
+	for _, t := range notifierTypes {
+		types[t] = true
+	}
+
+	if i := strings.Index(target, ":"); i > 0 && types[target[:i]] {
+		if target[:i] != notifierType {
+			return ""
+		}
+		return target[i+1:]
+	}
+
+	if len(types) > 1 && notifierType != telegramNotifierType {
+		return ""
+	}
+
+	return target
+}
+
+// notifierTypes returns the types of the notifiers.
+func notifierTypes(notifiers []Notifier) []string {
+	types := make([]string, 0, len(notifiers))
+	for _, n := range notifiers {
+		types = append(types, n.Type())
+	}
+
+	return types
+}
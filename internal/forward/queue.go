@@ -0,0 +1,376 @@
+package forward
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+)
+
+// QueueConfig is the configuration of the queue notifier.
+type QueueConfig struct {
+	// Dir is the directory where the pending notifications will be stored.
+	Dir string
+	// DeadLetterDir is the directory where the notifications that could not
+	// be sent will be stored, by default `{Dir}/dead-letter`.
+	DeadLetterDir string
+	// MaxRetries is the number of times a notification will be retried before
+	// moving it to the dead letter directory, 0 means no retries.
+	MaxRetries int
+	// InitialBackoff is the wait time before the first retry, every retry will double
+	// the wait time, by default 1s.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum wait time between retries, by default 5m.
+	MaxBackoff time.Duration
+	// NotifyTimeout is the maximum duration of a notification send, by default 30s.
+	NotifyTimeout time.Duration
+	// Notifier is the notifier that will send the queued notifications.
+	Notifier Notifier
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *QueueConfig) defaults() error {
+	if c.Dir == "" {
+		return errors.New("queue directory is required")
+	}
+
+	if c.Notifier == nil {
+		return errors.New("notifier is required")
+	}
+
+	if c.DeadLetterDir == "" {
+		c.DeadLetterDir = filepath.Join(c.Dir, "dead-letter")
+	}
+
+	if c.MaxRetries < 0 {
+		return errors.New("max retries can't be negative")
+	}
+
+	if c.InitialBackoff == 0 {
+		c.InitialBackoff = time.Second
+	}
+
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = 5 * time.Minute
+	}
+
+	if c.NotifyTimeout == 0 {
+		c.NotifyTimeout = 30 * time.Second
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+type queueNotifier struct {
+	cfg        QueueConfig
+	pendingDir string
+	next       Notifier
+	wakeup     chan struct{}
+	logger     log.Logger
+
+	mu      sync.Mutex
+	seq     uint64
+	sending map[string]bool
+}
+
+// NewQueueNotifier returns a notifier that stores the notifications on disk (write-ahead)
+// and sends them in background using the wrapped notifier. The notifications of the same
+// chat are sent in order, and the chats are sent concurrently, so a chat that is retrying
+// doesn't delay the others. The notifications that fail will be retried with an exponential
+// backoff and when the retries are exhausted they will be moved to the dead letter directory.
+//
+// When created, it will replay the pending notifications of previous executions. The
+// background process stops when the received context is done.
+func NewQueueNotifier(ctx context.Context, cfg QueueConfig) (Notifier, error) {
+	err := cfg.defaults()
+	if err != nil {
+		err := fmt.Errorf("%w: %s", internalerrors.ErrInvalidConfiguration, err)
+		return nil, fmt.Errorf("could not create queue notifier instance because invalid configuration: %w", err)
+	}
+
+	q := &queueNotifier{
+		cfg:        cfg,
+		pendingDir: filepath.Join(cfg.Dir, "pending"),
+		next:       cfg.Notifier,
+		wakeup:     make(chan struct{}, 1),
+		sending:    map[string]bool{},
+		logger:     cfg.Logger.WithValues(log.KV{"notifier": "queue", "queuedNotifier": cfg.Notifier.Type()}),
+	}
+
+	for _, dir := range []string{q.pendingDir, cfg.DeadLetterDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("could not create queue directory: %w", err)
+		}
+	}
+
+	go q.run(ctx)
+
+	return q, nil
+}
+
+func (q *queueNotifier) Notify(ctx context.Context, n Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("could not marshal notification: %w", err)
+	}
+
+	q.mu.Lock()
+	q.seq++
+	name := fmt.Sprintf("%020d-%06d-%s.json", time.Now().UnixNano(), q.seq%1000000, chatKey(n.ChatID))
+	q.mu.Unlock()
+
+	if err := writeFileAtomic(filepath.Join(q.pendingDir, name), data); err != nil {
+		return fmt.Errorf("could not queue notification: %w", err)
+	}
+
+	// Wake up the sender if required.
+	select {
+	case q.wakeup <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (q *queueNotifier) Type() string { return q.next.Type() }
+
+// run processes the queue until the context is done.
+func (q *queueNotifier) run(ctx context.Context) {
+	q.logger.Infof("queue started")
+
+	for {
+		q.processPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			q.logger.Infof("context done, stopping queue")
+			return
+		case <-q.wakeup:
+		}
+	}
+}
+
+// processPending starts the senders of the chats that have pending notifications
+// and are not being sent.
+func (q *queueNotifier) processPending(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	files, err := q.pendingFiles()
+	if err != nil {
+		q.logger.Errorf("could not list pending notifications: %s", err)
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, f := range files {
+		key := fileChatKey(f)
+		if q.sending[key] {
+			continue
+		}
+		q.sending[key] = true
+		go q.sendChat(ctx, key)
+	}
+}
+
+// sendChat sends all the pending notifications of the chat in order.
+func (q *queueNotifier) sendChat(ctx context.Context, key string) {
+	for {
+		files, err := q.pendingChatFiles(key)
+		if err != nil {
+			q.logger.Errorf("could not list pending notifications: %s", err)
+			return
+		}
+
+		// No more notifications of the chat, the new ones will start a new sender.
+		if len(files) == 0 {
+			return
+		}
+
+		for _, f := range files {
+			if ok := q.deliver(ctx, f); !ok {
+				q.mu.Lock()
+				delete(q.sending, key)
+				q.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// pendingChatFiles returns the pending notification files of the chat, if there
+// are no files the chat is marked as not being sent. This is done atomically
+// so a new notification is never missed by the running sender and a new one.
+func (q *queueNotifier) pendingChatFiles(key string) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	files, err := q.pendingFiles()
+	if err != nil {
+		delete(q.sending, key)
+		return nil, err
+	}
+
+	chatFiles := []string{}
+	for _, f := range files {
+		if fileChatKey(f) == key {
+			chatFiles = append(chatFiles, f)
+		}
+	}
+
+	if len(chatFiles) == 0 {
+		delete(q.sending, key)
+	}
+
+	return chatFiles, nil
+}
+
+// deliver sends the notification of the file until it's sent or the retries are exhausted,
+// returns false if the delivery has been interrupted by the context.
+func (q *queueNotifier) deliver(ctx context.Context, file string) bool {
+	logger := q.logger.WithValues(log.KV{"file": filepath.Base(file)})
+
+	n, err := readNotification(file)
+	if err != nil {
+		logger.Errorf("could not read queued notification: %s", err)
+		q.deadLetter(file, logger)
+		return true
+	}
+
+	backoff := q.cfg.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		nctx, cancel := context.WithTimeout(ctx, q.cfg.NotifyTimeout)
+		err := q.next.Notify(nctx, n)
+		cancel()
+
+		// Don't trust the notifier result if the context is done.
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if err == nil {
+			if err := os.Remove(file); err != nil {
+				logger.Errorf("could not remove sent notification: %s", err)
+			}
+			return true
+		}
+
+		// Invalid configurations will not be fixed by retrying.
+		if errors.Is(err, internalerrors.ErrInvalidConfiguration) || attempt >= q.cfg.MaxRetries {
+			logger.Errorf("could not notify alert group after %d attempts: %s", attempt+1, err)
+			q.deadLetter(file, logger)
+			return true
+		}
+
+		logger.Warningf("could not notify alert group, retrying in %s: %s", backoff, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > q.cfg.MaxBackoff {
+			backoff = q.cfg.MaxBackoff
+		}
+	}
+}
+
+func (q *queueNotifier) deadLetter(file string, logger log.Logger) {
+	err := os.Rename(file, filepath.Join(q.cfg.DeadLetterDir, filepath.Base(file)))
+	if err != nil {
+		logger.Errorf("could not move notification to dead letter directory: %s", err)
+		return
+	}
+	logger.Warningf("notification moved to dead letter directory")
+}
+
+// pendingFiles returns the pending notification files sorted by the queue order.
+func (q *queueNotifier) pendingFiles() ([]string, error) {
+	entries, err := ioutil.ReadDir(q.pendingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		files = append(files, filepath.Join(q.pendingDir, e.Name()))
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// chatKey returns the key of the chat used on the notification file names.
+func chatKey(chatID string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(chatID))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// fileChatKey returns the chat key of the notification file.
+func fileChatKey(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), ".json")
+	return name[strings.LastIndex(name, "-")+1:]
+}
+
+func readNotification(file string) (Notification, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return Notification{}, err
+	}
+
+	var n Notification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return Notification{}, err
+	}
+
+	return n, nil
+}
+
+// writeFileAtomic writes the file in a temporary file and then renames it, this
+// way the readers will never see half written files.
+func writeFileAtomic(file string, data []byte) error {
+	tmp := file + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,197 @@
+package forward_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	forwardmock "github.com/slok/alertgram/internal/mocks/forward"
+	"github.com/slok/alertgram/internal/model"
+)
+
+func countFiles(t *testing.T, dir string) int {
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	return len(files)
+}
+
+func TestQueueNotifier(t *testing.T) {
+	notification := forward.Notification{
+		ChatID: "-1001234567890",
+		AlertGroup: model.AlertGroup{
+			ID:     "test-group",
+			Alerts: []model.Alert{{ID: "test-alert", Name: "test", Labels: map[string]string{"lk": "lv"}}},
+		},
+	}
+
+	tests := map[string]struct {
+		cfg           forward.QueueConfig
+		prevQueued    int
+		mock          func(n *forwardmock.Notifier)
+		expPending    int
+		expDeadLetter int
+	}{
+		"A queued notification should be sent and removed from the queue.": {
+			mock: func(n *forwardmock.Notifier) {
+				n.On("Notify", mock.Anything, notification).Once().Return(nil)
+			},
+		},
+
+		"A queued notification that fails should be retried.": {
+			cfg: forward.QueueConfig{
+				MaxRetries: 5,
+			},
+			mock: func(n *forwardmock.Notifier) {
+				n.On("Notify", mock.Anything, notification).Twice().Return(errTest)
+				n.On("Notify", mock.Anything, notification).Once().Return(nil)
+			},
+		},
+
+		"A queued notification that fails without retries should be moved to the dead letter directory.": {
+			mock: func(n *forwardmock.Notifier) {
+				n.On("Notify", mock.Anything, notification).Once().Return(errTest)
+			},
+			expDeadLetter: 1,
+		},
+
+		"A queued notification that fails after the retries should be moved to the dead letter directory.": {
+			cfg: forward.QueueConfig{
+				MaxRetries: 2,
+			},
+			mock: func(n *forwardmock.Notifier) {
+				n.On("Notify", mock.Anything, notification).Times(3).Return(errTest)
+			},
+			expDeadLetter: 1,
+		},
+
+		"A queued notification with an invalid configuration should not be retried.": {
+			cfg: forward.QueueConfig{
+				MaxRetries: 5,
+			},
+			mock: func(n *forwardmock.Notifier) {
+				err := fmt.Errorf("wrong chat: %w", internalerrors.ErrInvalidConfiguration)
+				n.On("Notify", mock.Anything, notification).Once().Return(err)
+			},
+			expDeadLetter: 1,
+		},
+
+		"A queued notification that doesn't finish should be cancelled after the timeout and retried.": {
+			cfg: forward.QueueConfig{
+				MaxRetries:    5,
+				NotifyTimeout: 10 * time.Millisecond,
+			},
+			mock: func(n *forwardmock.Notifier) {
+				n.On("Notify", mock.Anything, notification).Once().Return(context.DeadlineExceeded).Run(func(args mock.Arguments) {
+					<-args.Get(0).(context.Context).Done()
+				})
+				n.On("Notify", mock.Anything, notification).Once().Return(nil)
+			},
+		},
+
+		"The notifications queued on previous executions should be replayed.": {
+			prevQueued: 2,
+			mock: func(n *forwardmock.Notifier) {
+				n.On("Notify", mock.Anything, notification).Times(3).Return(nil)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			dir, err := ioutil.TempDir("", "alertgram-queue")
+			require.NoError(err)
+			defer os.RemoveAll(dir)
+			test.cfg.Dir = dir
+			test.cfg.InitialBackoff = time.Millisecond
+
+			// Queue notifications without processing them (context already done).
+			if test.prevQueued > 0 {
+				mn := &forwardmock.Notifier{}
+				mn.On("Type").Maybe().Return("test")
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				cfg := test.cfg
+				cfg.Notifier = mn
+				q, err := forward.NewQueueNotifier(ctx, cfg)
+				require.NoError(err)
+				for i := 0; i < test.prevQueued; i++ {
+					require.NoError(q.Notify(context.TODO(), notification))
+				}
+			}
+
+			mn := &forwardmock.Notifier{}
+			mn.On("Type").Maybe().Return("test")
+			test.mock(mn)
+			test.cfg.Notifier = mn
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			q, err := forward.NewQueueNotifier(ctx, test.cfg)
+			require.NoError(err)
+			err = q.Notify(context.TODO(), notification)
+			require.NoError(err)
+
+			// Give time to the queue to process the notifications.
+			time.Sleep(50 * time.Millisecond)
+
+			mn.AssertExpectations(t)
+			assert.Equal(test.expPending, countFiles(t, filepath.Join(dir, "pending")))
+			assert.Equal(test.expDeadLetter, countFiles(t, filepath.Join(dir, "dead-letter")))
+		})
+	}
+}
+
+func TestQueueNotifierChatsDontBlockEachOther(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "alertgram-queue")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	blocked := forward.Notification{ChatID: "chat-1", AlertGroup: model.AlertGroup{ID: "group1"}}
+	other := forward.Notification{ChatID: "chat-2", AlertGroup: model.AlertGroup{ID: "group2"}}
+	release := make(chan struct{})
+	sent := make(chan struct{})
+	mn := &forwardmock.Notifier{}
+	mn.On("Type").Maybe().Return("test")
+	mn.On("Notify", mock.Anything, blocked).Once().Return(nil).Run(func(mock.Arguments) { <-release })
+	mn.On("Notify", mock.Anything, other).Once().Return(nil).Run(func(mock.Arguments) { close(sent) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q, err := forward.NewQueueNotifier(ctx, forward.QueueConfig{Dir: dir, Notifier: mn})
+	require.NoError(err)
+	require.NoError(q.Notify(context.TODO(), blocked))
+	require.NoError(q.Notify(context.TODO(), other))
+
+	// The other chat notification should be sent while the first chat is sending.
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		require.Fail("notification of the other chat not sent")
+	}
+	close(release)
+}
+
+func TestQueueNotifierNegativeRetries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alertgram-queue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = forward.NewQueueNotifier(context.TODO(), forward.QueueConfig{Dir: dir, MaxRetries: -1, Notifier: &forwardmock.Notifier{}})
+	assert.True(t, errors.Is(err, internalerrors.ErrInvalidConfiguration))
+}
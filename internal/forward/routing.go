@@ -0,0 +1,311 @@
+package forward
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MatchType is the type of a label matcher.
+type MatchType int
+
+const (
+	// MatchEqual matches when the label value is equal to the matcher value.
+	MatchEqual MatchType = iota
+	// MatchNotEqual matches when the label value is not equal to the matcher value.
+	MatchNotEqual
+	// MatchRegexp matches when the label value matches the matcher regex.
+	MatchRegexp
+	// MatchNotRegexp matches when the label value doesn't match the matcher regex.
+	MatchNotRegexp
+)
+
+var matchTypeOperators = map[MatchType]string{
+	MatchEqual:     "=",
+	MatchNotEqual:  "!=",
+	MatchRegexp:    "=~",
+	MatchNotRegexp: "!~",
+}
+
+func (m MatchType) String() string { return matchTypeOperators[m] }
+
+// Matcher knows how to match alerts by their labels.
+type Matcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+
+	re *regexp.Regexp
+}
+
+// NewMatcher returns a new label matcher, the regex matchers are anchored
+// like Alertmanager does.
+func NewMatcher(t MatchType, name, value string) (*Matcher, error) {
+	if name == "" {
+		return nil, errors.New("matcher label name can't be empty")
+	}
+
+	m := &Matcher{Type: t, Name: name, Value: value}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher regex: %w", err)
+		}
+		m.re = re
+	}
+
+	return m, nil
+}
+
+var matcherRegexp = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*(.*?)\s*$`)
+
+// ParseMatcher parses Alertmanager style label matchers,
+// e.g: `severity="critical"`, `team=~"team1|team2"`, `env!=dev`.
+func ParseMatcher(s string) (*Matcher, error) {
+	parts := matcherRegexp.FindStringSubmatch(s)
+	if parts == nil {
+		return nil, fmt.Errorf("invalid matcher %q", s)
+	}
+
+	value := parts[3]
+	if strings.HasPrefix(value, `"`) {
+		v, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q value: %w", s, err)
+		}
+		value = v
+	}
+
+	var t MatchType
+	for mt, op := range matchTypeOperators {
+		if op == parts[2] {
+			t = mt
+		}
+	}
+
+	return NewMatcher(t, parts[1], value)
+}
+
+// Matches returns true if the labels match.
+func (m Matcher) Matches(labels map[string]string) bool {
+	v := labels[m.Name]
+	switch m.Type {
+	case MatchEqual:
+		return v == m.Value
+	case MatchNotEqual:
+		return v != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(v)
+	case MatchNotRegexp:
+		return !m.re.MatchString(v)
+	}
+
+	return false
+}
+
+func (m Matcher) String() string { return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value) }
+
+// Route is a node of the routing tree that selects the chats and the notifiers of
+// the alerts, it works like Alertmanager's routing tree:
+//
+// - An alert enters the tree on the root route that matches all the alerts.
+// - If the route matches, the alert will try matching the children routes in order.
+// - The alert stops on the first matching child unless the child has `Continue`.
+// - If none of the children match, the alert will use the current route.
+//
+// The chat IDs and the notifiers that are not set are inherited from the parent route.
+type Route struct {
+	// Matchers are the matchers the alert labels need to match, all of them.
+	Matchers []*Matcher
+	// Receivers are the alert group receivers (e.g Alertmanager receiver) the
+	// alert group needs to have, one of them. If empty all the receivers match.
+	Receivers []string
+	// ChatIDs are the chats where the matched alerts will be sent.
+	ChatIDs []string
+	// Notifiers are the notifier types that will send the matched alerts, if
+	// empty all the notifiers will be used.
+	Notifiers []string
+	// Continue will continue matching the next sibling routes when
+	// this route matches.
+	Continue bool
+	// Routes are the children routes.
+	Routes []*Route
+}
+
+// routeTarget is the destination of an alert after being routed.
+type routeTarget struct {
+	chatID    string
+	notifiers []string
+}
+
+// allowsNotifier returns true if the target can be notified by the notifier type.
+func (r routeTarget) allowsNotifier(notifierType string) bool {
+	if len(r.notifiers) == 0 {
+		return true
+	}
+
+	for _, allowed := range r.notifiers {
+		if allowed == notifierType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notifierChatID returns the chat ID of the target for the notifier type, the chat IDs of
+// the targets that only allow one notifier are for that notifier.
+func (r routeTarget) notifierChatID(notifierType string, notifierTypes []string) string {
+	if len(r.notifiers) == 1 && r.notifiers[0] == notifierType {
+		notifierTypes = []string{notifierType}
+	}
+
+	return NotifierChatID(r.chatID, notifierType, notifierTypes)
+}
+
+// targets returns the targets of the alert group receiver and the alert labels,
+// if the alert doesn't match the route it will return nil.
+func (r *Route) targets(receiver string, labels map[string]string) []routeTarget {
+	routes := r.match(receiver, labels, Route{})
+
+	targets := []routeTarget{}
+	for _, route := range routes {
+		if len(route.ChatIDs) == 0 {
+			targets = append(targets, routeTarget{notifiers: route.Notifiers})
+			continue
+		}
+
+		for _, chatID := range route.ChatIDs {
+			targets = append(targets, routeTarget{chatID: chatID, notifiers: route.Notifiers})
+		}
+	}
+
+	return targets
+}
+
+// match returns the matched routes with the inherited settings from the parent.
+func (r *Route) match(receiver string, labels map[string]string, parent Route) []Route {
+	if !r.matchesReceiver(receiver) {
+		return nil
+	}
+
+	for _, m := range r.Matchers {
+		if !m.Matches(labels) {
+			return nil
+		}
+	}
+
+	current := Route{ChatIDs: r.ChatIDs, Notifiers: r.Notifiers}
+	if len(current.ChatIDs) == 0 {
+		current.ChatIDs = parent.ChatIDs
+	}
+	if len(current.Notifiers) == 0 {
+		current.Notifiers = parent.Notifiers
+	}
+
+	matched := []Route{}
+	for _, child := range r.Routes {
+		routes := child.match(receiver, labels, current)
+		if len(routes) == 0 {
+			continue
+		}
+
+		matched = append(matched, routes...)
+		if !child.Continue {
+			break
+		}
+	}
+
+	if len(matched) == 0 {
+		return []Route{current}
+	}
+
+	return matched
+}
+
+func (r *Route) matchesReceiver(receiver string) bool {
+	if len(r.Receivers) == 0 {
+		return true
+	}
+
+	for _, rcv := range r.Receivers {
+		if rcv == receiver {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routeConfig is the format of a route on the routing configuration file.
+type routeConfig struct {
+	Matchers  []string      `yaml:"matchers"`
+	Receivers []string      `yaml:"receivers"`
+	ChatIDs   []string      `yaml:"chat_ids"`
+	Notifiers []string      `yaml:"notifiers"`
+	Continue  bool          `yaml:"continue"`
+	Routes    []routeConfig `yaml:"routes"`
+}
+
+// LoadRoutingTree loads the routing tree from YAML data, e.g:
+//
+//	route:
+//	  chat_ids: ["-1001234567890"]
+//	  routes:
+//	    - matchers: ['severity="critical"']
+//	      chat_ids: ["-1009876543210"]
+//	      continue: true
+//	    - matchers: ['team=~"team1|team2"', 'env!="dev"']
+//	      notifiers: ["telegram"]
+//	    - receivers: ["team3"]
+//	      chat_ids: ["-1001111111111"]
+func LoadRoutingTree(data []byte) (*Route, error) {
+	cfg := struct {
+		Route *routeConfig `yaml:"route"`
+	}{}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("could not load routing configuration: %w", err)
+	}
+
+	if cfg.Route == nil {
+		return nil, errors.New("routing configuration root route is missing")
+	}
+
+	route, err := cfg.Route.toRoute()
+	if err != nil {
+		return nil, fmt.Errorf("invalid routing configuration: %w", err)
+	}
+
+	return route, nil
+}
+
+func (r routeConfig) toRoute() (*Route, error) {
+	route := &Route{
+		Receivers: r.Receivers,
+		ChatIDs:   r.ChatIDs,
+		Notifiers: r.Notifiers,
+		Continue:  r.Continue,
+	}
+
+	for _, m := range r.Matchers {
+		matcher, err := ParseMatcher(m)
+		if err != nil {
+			return nil, err
+		}
+		route.Matchers = append(route.Matchers, matcher)
+	}
+
+	for _, childCfg := range r.Routes {
+		child, err := childCfg.toRoute()
+		if err != nil {
+			return nil, err
+		}
+		route.Routes = append(route.Routes, child)
+	}
+
+	return route, nil
+}
@@ -0,0 +1,148 @@
+package forward_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/alertgram/internal/forward"
+)
+
+func TestParseMatcher(t *testing.T) {
+	tests := map[string]struct {
+		matcher    string
+		labels     map[string]string
+		expMatches bool
+		expErr     bool
+	}{
+		"Equal matcher should match equal values.": {
+			matcher:    `severity="critical"`,
+			labels:     map[string]string{"severity": "critical"},
+			expMatches: true,
+		},
+
+		"Equal matcher without quotes should match equal values.": {
+			matcher:    `severity = critical`,
+			labels:     map[string]string{"severity": "critical"},
+			expMatches: true,
+		},
+
+		"Equal matcher should not match different values.": {
+			matcher:    `severity="critical"`,
+			labels:     map[string]string{"severity": "warning"},
+			expMatches: false,
+		},
+
+		"Not equal matcher should match different values.": {
+			matcher:    `severity!="critical"`,
+			labels:     map[string]string{"severity": "warning"},
+			expMatches: true,
+		},
+
+		"Not equal matcher should match missing labels.": {
+			matcher:    `severity!="critical"`,
+			labels:     map[string]string{},
+			expMatches: true,
+		},
+
+		"Regex matcher should match anchored values.": {
+			matcher:    `team=~"team1|team2"`,
+			labels:     map[string]string{"team": "team2"},
+			expMatches: true,
+		},
+
+		"Regex matcher should not match partial values.": {
+			matcher:    `team=~"team"`,
+			labels:     map[string]string{"team": "team2"},
+			expMatches: false,
+		},
+
+		"Not regex matcher should match values that don't match the regex.": {
+			matcher:    `team!~"team1|team2"`,
+			labels:     map[string]string{"team": "team3"},
+			expMatches: true,
+		},
+
+		"Invalid matcher should fail.": {
+			matcher: `team~"team1"`,
+			expErr:  true,
+		},
+
+		"Invalid matcher regex should fail.": {
+			matcher: `team=~"team1("`,
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			m, err := forward.ParseMatcher(test.matcher)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expMatches, m.Matches(test.labels))
+			}
+		})
+	}
+}
+
+func TestLoadRoutingTree(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		expErr bool
+	}{
+		"A valid routing configuration should load.": {
+			config: `
+route:
+  chat_ids: ["-1001234567890"]
+  routes:
+    - matchers: ['severity="critical"']
+      chat_ids: ["-1009876543210"]
+      continue: true
+    - matchers: ['team=~"team1|team2"', 'env!="dev"']
+      notifiers: ["telegram"]
+    - receivers: ["team3"]
+      chat_ids: ["-1001111111111"]
+`,
+		},
+
+		"A routing configuration without root route should fail.": {
+			config: `routes: []`,
+			expErr: true,
+		},
+
+		"A routing configuration with unknown fields should fail.": {
+			config: `
+route:
+  chat_id: "-1001234567890"
+`,
+			expErr: true,
+		},
+
+		"A routing configuration with invalid matchers should fail.": {
+			config: `
+route:
+  routes:
+    - matchers: ['severity']
+`,
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := forward.LoadRoutingTree([]byte(test.config))
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
@@ -50,9 +50,15 @@ func getBaseAlerts() *model.AlertGroup {
 	al3.Status = model.AlertStatusResolved
 
 	return &model.AlertGroup{
-		ID:     "test-group",
-		Labels: map[string]string{"glK1": "glV1", "glK2": "glV2"},
-		Alerts: []model.Alert{al1, al2, al3},
+		ID:                "test-group",
+		Labels:            map[string]string{"glK1": "glV1", "glK2": "glV2"},
+		Alerts:            []model.Alert{al1, al2, al3},
+		Receiver:          "test-recv",
+		Status:            model.AlertStatusFiring,
+		CommonLabels:      map[string]string{"gclK1": "gclV1", "gclK2": "gclV2"},
+		CommonAnnotations: map[string]string{"gcaK1": "gcaV1", "gcaK2": "gcaV2"},
+		ExternalURL:       "http://test.com",
+		TruncatedAlerts:   2,
 	}
 }
 
@@ -87,8 +93,9 @@ func getBaseAlertmanagerAlerts() webhook.Message {
 			CommonAnnotations: map[string]string{"gcaK1": "gcaV1", "gcaK2": "gcaV2"},
 			ExternalURL:       "http://test.com",
 		},
-		Version:  "4",
-		GroupKey: "test-group",
+		Version:         "4",
+		GroupKey:        "test-group",
+		TruncatedAlerts: 2,
 	}
 }
 
@@ -43,9 +43,15 @@ func (a alertGroupV4) toDomain() (*model.AlertGroup, error) {
 	}
 
 	ag := &model.AlertGroup{
-		ID:     a.GroupKey,
-		Labels: a.GroupLabels,
-		Alerts: alerts,
+		ID:                a.GroupKey,
+		Labels:            a.GroupLabels,
+		Alerts:            alerts,
+		Receiver:          a.Receiver,
+		Status:            alertStatusToDomain(a.Status),
+		CommonLabels:      a.CommonLabels,
+		CommonAnnotations: a.CommonAnnotations,
+		ExternalURL:       a.ExternalURL,
+		TruncatedAlerts:   int(a.TruncatedAlerts),
 	}
 
 	return ag, nil
@@ -0,0 +1,293 @@
+package generic_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/http/generic"
+	"github.com/slok/alertgram/internal/internalerrors"
+	forwardmock "github.com/slok/alertgram/internal/mocks/forward"
+	"github.com/slok/alertgram/internal/model"
+)
+
+var t0 = time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+
+const ciMapping = `
+alerts: $.jobs
+group_id: $.pipeline.id
+id: "@.id"
+name: "@.name"
+status: "@.status"
+firing_status: ["failed"]
+resolved_status: ["success"]
+starts_at: "@.started_at"
+ends_at: "@.finished_at"
+generator_url: "@.web_url"
+labels:
+  project: $.project.name
+  stage: "@.stage"
+annotations:
+  message: "@['failure reason']"
+  runner: "@.runner"
+`
+
+const ciAlertsJSON = `{
+  "pipeline": {"id": 1234},
+  "project": {"name": "alertgram"},
+  "jobs": [
+    {
+      "id": 11,
+      "name": "unit-test",
+      "stage": "test",
+      "status": "failed",
+      "started_at": "2021-03-10T11:50:00Z",
+      "web_url": "http://ci.test/jobs/11",
+      "failure reason": "tests failed",
+      "runner": {"id": 3, "tags": ["docker"]}
+    },
+    {
+      "id": 12,
+      "name": "build",
+      "stage": "build",
+      "status": "SUCCESS",
+      "started_at": 1615376400,
+      "finished_at": 1615376700.5
+    }
+  ]
+}`
+
+func getBaseAlerts() *model.AlertGroup {
+	return &model.AlertGroup{
+		ID:     "1234",
+		Status: model.AlertStatusFiring,
+		Alerts: []model.Alert{
+			{
+				ID:           "11",
+				Name:         "unit-test",
+				StartsAt:     t0.Add(-10 * time.Minute),
+				Status:       model.AlertStatusFiring,
+				Labels:       map[string]string{"alertname": "unit-test", "project": "alertgram", "stage": "test"},
+				Annotations:  map[string]string{"message": "tests failed", "runner": `{"id":3,"tags":["docker"]}`},
+				GeneratorURL: "http://ci.test/jobs/11",
+			},
+			{
+				ID:          "12",
+				Name:        "build",
+				StartsAt:    t0.Add(-20 * time.Minute),
+				EndsAt:      t0.Add(-15*time.Minute + 500*time.Millisecond),
+				Status:      model.AlertStatusResolved,
+				Labels:      map[string]string{"alertname": "build", "project": "alertgram", "stage": "build"},
+				Annotations: map[string]string{},
+			},
+		},
+	}
+}
+
+func TestHandleAlerts(t *testing.T) {
+	tests := map[string]struct {
+		config    generic.Config
+		mapping   string
+		urlPath   string
+		alertJSON string
+		mock      func(t *testing.T, msvc *forwardmock.Service)
+		expCode   int
+	}{
+		"Generic webhook alerts request should be handled correctly (with defaults).": {
+			mapping:   ciMapping,
+			urlPath:   "/generic/alerts",
+			alertJSON: ciAlertsJSON,
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlerts()
+				expProps := forward.Properties{}
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(nil)
+			},
+			expCode: http.StatusOK,
+		},
+
+		"Generic webhook alerts request should be handled correctly (with custom params).": {
+			config: generic.Config{
+				WebhookPath:       "/test-alerts",
+				ChatIDQueryString: "custom-telegram-chat-id",
+			},
+			mapping:   ciMapping,
+			urlPath:   "/test-alerts?custom-telegram-chat-id=-1009876543210",
+			alertJSON: ciAlertsJSON,
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlerts()
+				expProps := forward.Properties{
+					CustomChatID: "-1009876543210",
+				}
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(nil)
+			},
+			expCode: http.StatusOK,
+		},
+
+		"Generic webhook alerts request without alerts, IDs and status should be a single firing alert identified by its labels.": {
+			mapping: `
+name: $.check
+labels:
+  host: $.host
+`,
+			urlPath:   "/generic/alerts",
+			alertJSON: `{"check": "backup", "host": "db1"}`,
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				labels := map[string]string{"alertname": "backup", "host": "db1"}
+				id := prommodel.LabelSet{"alertname": "backup", "host": "db1"}.Fingerprint().String()
+				expAlerts := &model.AlertGroup{
+					ID:     id,
+					Status: model.AlertStatusFiring,
+					Alerts: []model.Alert{{
+						ID:          id,
+						Name:        "backup",
+						Status:      model.AlertStatusFiring,
+						Labels:      labels,
+						Annotations: map[string]string{},
+					}},
+				}
+				msvc.On("Forward", mock.Anything, forward.Properties{}, expAlerts).Once().Return(nil)
+			},
+			expCode: http.StatusOK,
+		},
+
+		"Generic webhook internal errors should be propagated to clients (forwarding).": {
+			mapping:   ciMapping,
+			urlPath:   "/generic/alerts",
+			alertJSON: ciAlertsJSON,
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlerts()
+				expProps := forward.Properties{}
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(errors.New("whatever"))
+			},
+			expCode: http.StatusInternalServerError,
+		},
+
+		"Generic webhook configuration errors should be propagated to clients (forwarding).": {
+			mapping:   ciMapping,
+			urlPath:   "/generic/alerts",
+			alertJSON: ciAlertsJSON,
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlerts()
+				expProps := forward.Properties{}
+				err := fmt.Errorf("custom error: %w", internalerrors.ErrInvalidConfiguration)
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(err)
+			},
+			expCode: http.StatusBadRequest,
+		},
+
+		"Generic webhook configuration errors on notification should be propagated to clients (alerts mapping).": {
+			mapping:   ciMapping,
+			urlPath:   "/generic/alerts",
+			alertJSON: `{"jobs": "none"}`,
+			mock:      func(t *testing.T, msvc *forwardmock.Service) {},
+			expCode:   http.StatusBadRequest,
+		},
+
+		"Generic webhook configuration errors on notification should be propagated to clients (time mapping).": {
+			mapping:   ciMapping,
+			urlPath:   "/generic/alerts",
+			alertJSON: `{"jobs": [{"id": 1, "name": "test", "started_at": "yesterday"}]}`,
+			mock:      func(t *testing.T, msvc *forwardmock.Service) {},
+			expCode:   http.StatusBadRequest,
+		},
+
+		"Generic webhook configuration errors on notification should be propagated to clients (JSON formatting).": {
+			mapping:   ciMapping,
+			urlPath:   "/generic/alerts",
+			alertJSON: "{",
+			mock:      func(t *testing.T, msvc *forwardmock.Service) {},
+			expCode:   http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			msvc := &forwardmock.Service{}
+			test.mock(t, msvc)
+
+			// Execute.
+			mapping, err := generic.LoadMapping([]byte(test.mapping))
+			require.NoError(err)
+			test.config.Mapping = mapping
+			test.config.ForwardService = msvc
+			h, _ := generic.NewHandler(test.config)
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+			req, err := http.NewRequest(http.MethodPost, srv.URL+test.urlPath, strings.NewReader(test.alertJSON))
+			require.NoError(err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(err)
+
+			// Check.
+			assert.Equal(test.expCode, resp.StatusCode)
+			msvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLoadMapping(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		expErr  bool
+	}{
+		"A valid mapping should load.": {
+			mapping: ciMapping,
+		},
+
+		"A mapping without name should fail.": {
+			mapping: `id: $.id`,
+			expErr:  true,
+		},
+
+		"A mapping with unknown fields should fail.": {
+			mapping: "name: $.name\nseverity: $.severity",
+			expErr:  true,
+		},
+
+		"A mapping with an expression without root should fail.": {
+			mapping: `name: name`,
+			expErr:  true,
+		},
+
+		"A mapping with an invalid index should fail.": {
+			mapping: `name: $.names[first]`,
+			expErr:  true,
+		},
+
+		"A mapping with an unclosed index should fail.": {
+			mapping: `name: $.names[0`,
+			expErr:  true,
+		},
+
+		"A mapping with an empty field should fail.": {
+			mapping: `name: $..name`,
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := generic.LoadMapping([]byte(test.mapping))
+
+			if test.expErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
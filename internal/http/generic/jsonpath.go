@@ -0,0 +1,107 @@
+package generic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled JSONPath-style expression that selects a value from
+// decoded JSON data. It supports a subset of JSONPath:
+//
+//	$.alerts[0].labels['app.kubernetes.io/name']
+//
+// `$` is the root of the received document and `@` is the current alert.
+type jsonPath struct {
+	expr    string
+	current bool
+	steps   []interface{}
+}
+
+// compileJSONPath compiles a JSONPath-style expression, the steps are object
+// fields (`.field`, `['field']`) and array indexes (`[0]`, `[-1]`).
+func compileJSONPath(expr string) (*jsonPath, error) {
+	p := &jsonPath{expr: expr}
+	switch {
+	case strings.HasPrefix(expr, "$"):
+	case strings.HasPrefix(expr, "@"):
+		p.current = true
+	default:
+		return nil, fmt.Errorf("invalid expression %q: must start with '$' or '@'", expr)
+	}
+
+	s := expr[1:]
+	for s != "" {
+		switch s[0] {
+		case '.':
+			end := strings.IndexAny(s[1:], ".[")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			field := s[1 : end+1]
+			if field == "" {
+				return nil, fmt.Errorf("invalid expression %q: empty field", expr)
+			}
+			p.steps = append(p.steps, field)
+			s = s[end+1:]
+		case '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid expression %q: missing ']'", expr)
+			}
+			step := s[1:end]
+			if len(step) >= 2 && (step[0] == '\'' || step[0] == '"') && step[len(step)-1] == step[0] {
+				p.steps = append(p.steps, step[1:len(step)-1])
+			} else {
+				i, err := strconv.Atoi(step)
+				if err != nil {
+					return nil, fmt.Errorf("invalid expression %q: invalid index %q", expr, step)
+				}
+				p.steps = append(p.steps, i)
+			}
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid expression %q: unexpected %q", expr, s[0])
+		}
+	}
+
+	return p, nil
+}
+
+// get returns the selected value, if the value is missing it will return false.
+func (p *jsonPath) get(root, current interface{}) (interface{}, bool) {
+	v := root
+	if p.current {
+		v = current
+	}
+
+	for _, step := range p.steps {
+		switch step := step.(type) {
+		case string:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			v, ok = obj[step]
+			if !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			if step < 0 {
+				step += len(arr)
+			}
+			if step < 0 || step >= len(arr) {
+				return nil, false
+			}
+			v = arr[step]
+		}
+	}
+
+	return v, v != nil
+}
+
+func (p *jsonPath) String() string { return p.expr }
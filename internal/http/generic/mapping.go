@@ -0,0 +1,317 @@
+package generic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/slok/alertgram/internal/model"
+)
+
+// Mapping knows how to map a received JSON document into an alert group.
+type Mapping struct {
+	alerts         *jsonPath
+	groupID        *jsonPath
+	id             *jsonPath
+	name           *jsonPath
+	status         *jsonPath
+	firingStatus   []string
+	resolvedStatus []string
+	startsAt       *jsonPath
+	endsAt         *jsonPath
+	generatorURL   *jsonPath
+	labels         map[string]*jsonPath
+	annotations    map[string]*jsonPath
+}
+
+// mappingConfig is the format of the mapping configuration file.
+type mappingConfig struct {
+	Alerts         string            `yaml:"alerts"`
+	GroupID        string            `yaml:"group_id"`
+	ID             string            `yaml:"id"`
+	Name           string            `yaml:"name"`
+	Status         string            `yaml:"status"`
+	FiringStatus   []string          `yaml:"firing_status"`
+	ResolvedStatus []string          `yaml:"resolved_status"`
+	StartsAt       string            `yaml:"starts_at"`
+	EndsAt         string            `yaml:"ends_at"`
+	GeneratorURL   string            `yaml:"generator_url"`
+	Labels         map[string]string `yaml:"labels"`
+	Annotations    map[string]string `yaml:"annotations"`
+}
+
+// LoadMapping loads the mapping from YAML data. The values are JSONPath-style
+// expressions where `$` is the received document and `@` the current alert, e.g:
+//
+//	alerts: $.jobs
+//	group_id: $.pipeline.id
+//	id: "@.id"
+//	name: "@.name"
+//	status: "@.status"
+//	firing_status: ["failed"]
+//	resolved_status: ["success"]
+//	starts_at: "@.started_at"
+//	ends_at: "@.finished_at"
+//	generator_url: "@.web_url"
+//	labels:
+//	  project: $.project.name
+//	annotations:
+//	  message: "@.failure_reason"
+//
+// If `alerts` is not set, the document is a single alert. The status of the alerts
+// is firing when it's not set, and `firing_status`, `resolved_status` default to
+// `firing` and `resolved`.
+func LoadMapping(data []byte) (*Mapping, error) {
+	cfg := mappingConfig{}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("could not load mapping configuration: %w", err)
+	}
+
+	m, err := cfg.toMapping()
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping configuration: %w", err)
+	}
+
+	return m, nil
+}
+
+func (c mappingConfig) toMapping() (*Mapping, error) {
+	if c.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	m := &Mapping{
+		firingStatus:   c.FiringStatus,
+		resolvedStatus: c.ResolvedStatus,
+		labels:         map[string]*jsonPath{},
+		annotations:    map[string]*jsonPath{},
+	}
+	if len(m.firingStatus) == 0 {
+		m.firingStatus = []string{"firing"}
+	}
+	if len(m.resolvedStatus) == 0 {
+		m.resolvedStatus = []string{"resolved"}
+	}
+
+	paths := []struct {
+		expr string
+		path **jsonPath
+	}{
+		{expr: c.Alerts, path: &m.alerts},
+		{expr: c.GroupID, path: &m.groupID},
+		{expr: c.ID, path: &m.id},
+		{expr: c.Name, path: &m.name},
+		{expr: c.Status, path: &m.status},
+		{expr: c.StartsAt, path: &m.startsAt},
+		{expr: c.EndsAt, path: &m.endsAt},
+		{expr: c.GeneratorURL, path: &m.generatorURL},
+	}
+	for _, p := range paths {
+		if p.expr == "" {
+			continue
+		}
+		path, err := compileJSONPath(p.expr)
+		if err != nil {
+			return nil, err
+		}
+		*p.path = path
+	}
+
+	for k, expr := range c.Labels {
+		p, err := compileJSONPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		m.labels[k] = p
+	}
+
+	for k, expr := range c.Annotations {
+		p, err := compileJSONPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		m.annotations[k] = p
+	}
+
+	return m, nil
+}
+
+// toDomain maps the received JSON document into an alert group.
+func (m *Mapping) toDomain(doc interface{}) (*model.AlertGroup, error) {
+	items := []interface{}{doc}
+	if m.alerts != nil {
+		v, _ := m.alerts.get(doc, doc)
+		switch v := v.(type) {
+		case []interface{}:
+			items = v
+		case map[string]interface{}:
+			items = []interface{}{v}
+		default:
+			return nil, fmt.Errorf("alerts %q are not an array", m.alerts)
+		}
+	}
+
+	alerts := make([]model.Alert, 0, len(items))
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		alert, err := m.toAlert(doc, item)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, *alert)
+		ids = append(ids, alert.ID)
+	}
+
+	// By default the group is identified by its alerts.
+	groupID := m.str(m.groupID, doc, doc)
+	if groupID == "" {
+		groupID = strings.Join(ids, ",")
+	}
+
+	ag := &model.AlertGroup{
+		ID:     groupID,
+		Alerts: alerts,
+		Status: model.AlertStatusResolved,
+	}
+	if ag.HasFiring() {
+		ag.Status = model.AlertStatusFiring
+	}
+
+	return ag, nil
+}
+
+func (m *Mapping) toAlert(doc, item interface{}) (*model.Alert, error) {
+	a := &model.Alert{
+		ID:           m.str(m.id, doc, item),
+		Name:         m.str(m.name, doc, item),
+		Status:       model.AlertStatusFiring,
+		Labels:       map[string]string{},
+		Annotations:  map[string]string{},
+		GeneratorURL: m.str(m.generatorURL, doc, item),
+	}
+
+	for k, p := range m.labels {
+		if v := m.str(p, doc, item); v != "" {
+			a.Labels[k] = v
+		}
+	}
+	for k, p := range m.annotations {
+		if v := m.str(p, doc, item); v != "" {
+			a.Annotations[k] = v
+		}
+	}
+
+	// Like Prometheus alerts, the alert name is on the labels.
+	if _, ok := a.Labels[prommodel.AlertNameLabel]; !ok && a.Name != "" {
+		a.Labels[prommodel.AlertNameLabel] = a.Name
+	}
+
+	if m.status != nil {
+		a.Status = m.toStatus(m.str(m.status, doc, item))
+	}
+
+	var err error
+	a.StartsAt, err = m.time(m.startsAt, doc, item)
+	if err != nil {
+		return nil, err
+	}
+	a.EndsAt, err = m.time(m.endsAt, doc, item)
+	if err != nil {
+		return nil, err
+	}
+
+	// By default the alert is identified by its labels.
+	if a.ID == "" {
+		a.ID = labelsFingerprint(a.Labels)
+	}
+
+	return a, nil
+}
+
+func (m *Mapping) toStatus(st string) model.AlertStatus {
+	for _, s := range m.firingStatus {
+		if strings.EqualFold(s, st) {
+			return model.AlertStatusFiring
+		}
+	}
+
+	for _, s := range m.resolvedStatus {
+		if strings.EqualFold(s, st) {
+			return model.AlertStatusResolved
+		}
+	}
+
+	return model.AlertStatusUnknown
+}
+
+// str returns the selected value as a string, the objects and arrays are
+// returned in JSON format.
+func (m *Mapping) str(p *jsonPath, doc, item interface{}) string {
+	if p == nil {
+		return ""
+	}
+
+	v, ok := p.get(doc, item)
+	if !ok {
+		return ""
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// time returns the selected value as a time, the value can be an RFC3339
+// string or a Unix timestamp in seconds.
+func (m *Mapping) time(p *jsonPath, doc, item interface{}) (time.Time, error) {
+	if p == nil {
+		return time.Time{}, nil
+	}
+
+	v, ok := p.get(doc, item)
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	switch v := v.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %q time: %w", p, err)
+		}
+		return t, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %q time: %w", p, err)
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid %q time: must be an RFC3339 string or a Unix timestamp", p)
+	}
+}
+
+func labelsFingerprint(labels map[string]string) string {
+	ls := make(prommodel.LabelSet, len(labels))
+	for k, v := range labels {
+		ls[prommodel.LabelName(k)] = prommodel.LabelValue(v)
+	}
+
+	return ls.Fingerprint().String()
+}
@@ -0,0 +1,205 @@
+package grafana_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/http/grafana"
+	"github.com/slok/alertgram/internal/internalerrors"
+	forwardmock "github.com/slok/alertgram/internal/mocks/forward"
+	"github.com/slok/alertgram/internal/model"
+)
+
+var t0 = time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+
+const grafanaAlertsJSON = `{
+  "receiver": "alertgram",
+  "status": "firing",
+  "orgId": 1,
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "HighLatency", "team": "backend"},
+      "annotations": {"summary": "High latency", "panelURL": "http://custom-panel.test"},
+      "startsAt": "2021-03-10T11:50:00Z",
+      "endsAt": "0001-01-01T00:00:00Z",
+      "generatorURL": "http://grafana.test/alerting/grafana/abc/view",
+      "fingerprint": "57c6d9296de2ad39",
+      "silenceURL": "http://grafana.test/alerting/silence/new",
+      "dashboardURL": "http://grafana.test/d/dashboard",
+      "panelURL": "http://grafana.test/d/dashboard?viewPanel=1",
+      "values": {"B": 1.5},
+      "valueString": "[ var='B' labels={} value=1.5 ]",
+      "imageURL": "http://grafana.test/image.png"
+    },
+    {
+      "status": "resolved",
+      "labels": {"alertname": "HighErrors", "team": "backend"},
+      "annotations": {},
+      "startsAt": "2021-03-10T11:40:00Z",
+      "endsAt": "2021-03-10T11:55:00Z",
+      "generatorURL": "",
+      "fingerprint": "9e6d1f1a9c3b7e21"
+    }
+  ],
+  "groupLabels": {"team": "backend"},
+  "commonLabels": {"team": "backend"},
+  "commonAnnotations": {},
+  "externalURL": "http://grafana.test/",
+  "version": "%s",
+  "groupKey": "{}:{team=\"backend\"}",
+  "truncatedAlerts": 0,
+  "title": "[FIRING:1] backend",
+  "state": "alerting",
+  "message": "**Firing**"
+}`
+
+func getBaseAlerts() *model.AlertGroup {
+	return &model.AlertGroup{
+		ID:                `{}:{team="backend"}`,
+		Labels:            map[string]string{"team": "backend"},
+		Receiver:          "alertgram",
+		Status:            model.AlertStatusFiring,
+		CommonLabels:      map[string]string{"team": "backend"},
+		CommonAnnotations: map[string]string{},
+		ExternalURL:       "http://grafana.test/",
+		Alerts: []model.Alert{
+			{
+				ID:       "57c6d9296de2ad39",
+				Name:     "HighLatency",
+				StartsAt: t0.Add(-10 * time.Minute),
+				EndsAt:   time.Time{},
+				Status:   model.AlertStatusFiring,
+				Labels:   map[string]string{"alertname": "HighLatency", "team": "backend"},
+				Annotations: map[string]string{
+					"summary":      "High latency",
+					"valueString":  "[ var='B' labels={} value=1.5 ]",
+					"panelURL":     "http://custom-panel.test",
+					"dashboardURL": "http://grafana.test/d/dashboard",
+					"silenceURL":   "http://grafana.test/alerting/silence/new",
+					"imageURL":     "http://grafana.test/image.png",
+				},
+				GeneratorURL: "http://grafana.test/alerting/grafana/abc/view",
+			},
+			{
+				ID:          "9e6d1f1a9c3b7e21",
+				Name:        "HighErrors",
+				StartsAt:    t0.Add(-20 * time.Minute),
+				EndsAt:      t0.Add(-5 * time.Minute),
+				Status:      model.AlertStatusResolved,
+				Labels:      map[string]string{"alertname": "HighErrors", "team": "backend"},
+				Annotations: map[string]string{},
+			},
+		},
+	}
+}
+
+func TestHandleAlerts(t *testing.T) {
+	tests := map[string]struct {
+		config           grafana.Config
+		urlPath          string
+		webhookAlertJSON string
+		mock             func(t *testing.T, msvc *forwardmock.Service)
+		expCode          int
+	}{
+		"Grafana webhook alerts request should be handled correctly (with defaults).": {
+			urlPath:          "/grafana/alerts",
+			webhookAlertJSON: fmt.Sprintf(grafanaAlertsJSON, "1"),
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlerts()
+				expProps := forward.Properties{}
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(nil)
+			},
+			expCode: http.StatusOK,
+		},
+
+		"Grafana webhook alerts request should be handled correctly (with custom params).": {
+			config: grafana.Config{
+				WebhookPath:       "/test-alerts",
+				ChatIDQueryString: "custom-telegram-chat-id",
+			},
+			urlPath:          "/test-alerts?custom-telegram-chat-id=-1009876543210",
+			webhookAlertJSON: fmt.Sprintf(grafanaAlertsJSON, "1"),
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlerts()
+				expProps := forward.Properties{
+					CustomChatID: "-1009876543210",
+				}
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(nil)
+			},
+			expCode: http.StatusOK,
+		},
+
+		"Grafana webhook internal errors should be propagated to clients (forwarding).": {
+			urlPath:          "/grafana/alerts",
+			webhookAlertJSON: fmt.Sprintf(grafanaAlertsJSON, "1"),
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlerts()
+				expProps := forward.Properties{}
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(errors.New("whatever"))
+			},
+			expCode: http.StatusInternalServerError,
+		},
+
+		"Grafana webhook configuration errors should be propagated to clients (forwarding).": {
+			urlPath:          "/grafana/alerts",
+			webhookAlertJSON: fmt.Sprintf(grafanaAlertsJSON, "1"),
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlerts()
+				expProps := forward.Properties{}
+				err := fmt.Errorf("custom error: %w", internalerrors.ErrInvalidConfiguration)
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(err)
+			},
+			expCode: http.StatusBadRequest,
+		},
+
+		"Grafana webhook configuration errors on notification should be propagated to clients (alert mapping).": {
+			urlPath:          "/grafana/alerts",
+			webhookAlertJSON: fmt.Sprintf(grafanaAlertsJSON, "2"),
+			mock:             func(t *testing.T, msvc *forwardmock.Service) {},
+			expCode:          http.StatusBadRequest,
+		},
+
+		"Grafana webhook configuration errors on notification should be propagated to clients (JSON formatting).": {
+			urlPath:          "/grafana/alerts",
+			webhookAlertJSON: "{",
+			mock:             func(t *testing.T, msvc *forwardmock.Service) {},
+			expCode:          http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			msvc := &forwardmock.Service{}
+			test.mock(t, msvc)
+
+			// Execute.
+			test.config.ForwardService = msvc
+			h, _ := grafana.NewHandler(test.config)
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+			req, err := http.NewRequest(http.MethodPost, srv.URL+test.urlPath, strings.NewReader(test.webhookAlertJSON))
+			require.NoError(err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(err)
+
+			// Check.
+			assert.Equal(test.expCode, resp.StatusCode)
+			msvc.AssertExpectations(t)
+		})
+	}
+}
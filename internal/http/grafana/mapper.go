@@ -0,0 +1,128 @@
+package grafana
+
+import (
+	"errors"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+
+	"github.com/slok/alertgram/internal/model"
+)
+
+// Annotations where the Grafana specific alert data is mapped, so it can be used
+// by the templates like any other annotation.
+const (
+	AnnotationValueString  = "valueString"
+	AnnotationPanelURL     = "panelURL"
+	AnnotationDashboardURL = "dashboardURL"
+	AnnotationSilenceURL   = "silenceURL"
+	AnnotationImageURL     = "imageURL"
+)
+
+// alertGroupV1 are the alertGroup received by the webhook
+// It uses the V1 version of the Grafana unified alerting webhook format.
+//
+// https://grafana.com/docs/grafana/latest/alerting/manage-notifications/webhook-notifier/#body
+type alertGroupV1 struct {
+	Receiver          string            `json:"receiver"`
+	Status            string            `json:"status"`
+	OrgID             int64             `json:"orgId"`
+	Alerts            []alertV1         `json:"alerts"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+	Title             string            `json:"title"`
+	State             string            `json:"state"`
+	Message           string            `json:"message"`
+}
+
+type alertV1 struct {
+	Status       string             `json:"status"`
+	Labels       map[string]string  `json:"labels"`
+	Annotations  map[string]string  `json:"annotations"`
+	StartsAt     time.Time          `json:"startsAt"`
+	EndsAt       time.Time          `json:"endsAt"`
+	Values       map[string]float64 `json:"values"`
+	ValueString  string             `json:"valueString"`
+	GeneratorURL string             `json:"generatorURL"`
+	Fingerprint  string             `json:"fingerprint"`
+	SilenceURL   string             `json:"silenceURL"`
+	DashboardURL string             `json:"dashboardURL"`
+	PanelURL     string             `json:"panelURL"`
+	ImageURL     string             `json:"imageURL"`
+}
+
+func (a alertGroupV1) toDomain() (*model.AlertGroup, error) {
+	if a.Version != "1" {
+		return nil, errors.New("not supported alert group version")
+	}
+
+	// Map alerts.
+	alerts := make([]model.Alert, 0, len(a.Alerts))
+	for _, alert := range a.Alerts {
+		modelAlert := model.Alert{
+			ID:           alert.Fingerprint,
+			Name:         alert.Labels[prommodel.AlertNameLabel],
+			StartsAt:     alert.StartsAt,
+			EndsAt:       alert.EndsAt,
+			Status:       alertStatusToDomain(alert.Status),
+			Labels:       alert.Labels,
+			Annotations:  alert.annotations(),
+			GeneratorURL: alert.GeneratorURL,
+		}
+		alerts = append(alerts, modelAlert)
+	}
+
+	ag := &model.AlertGroup{
+		ID:                a.GroupKey,
+		Labels:            a.GroupLabels,
+		Alerts:            alerts,
+		Receiver:          a.Receiver,
+		Status:            alertStatusToDomain(a.Status),
+		CommonLabels:      a.CommonLabels,
+		CommonAnnotations: a.CommonAnnotations,
+		ExternalURL:       a.ExternalURL,
+		TruncatedAlerts:   a.TruncatedAlerts,
+	}
+
+	return ag, nil
+}
+
+// annotations returns the alert annotations with the Grafana specific data, the
+// alert annotations have priority over the Grafana data.
+func (a alertV1) annotations() map[string]string {
+	grafanaData := map[string]string{
+		AnnotationValueString:  a.ValueString,
+		AnnotationPanelURL:     a.PanelURL,
+		AnnotationDashboardURL: a.DashboardURL,
+		AnnotationSilenceURL:   a.SilenceURL,
+		AnnotationImageURL:     a.ImageURL,
+	}
+
+	annotations := make(map[string]string, len(a.Annotations)+len(grafanaData))
+	for k, v := range grafanaData {
+		if v != "" {
+			annotations[k] = v
+		}
+	}
+	for k, v := range a.Annotations {
+		annotations[k] = v
+	}
+
+	return annotations
+}
+
+func alertStatusToDomain(st string) model.AlertStatus {
+	switch prommodel.AlertStatus(st) {
+	case prommodel.AlertFiring:
+		return model.AlertStatusFiring
+	case prommodel.AlertResolved:
+		return model.AlertStatusResolved
+	default:
+		return model.AlertStatusUnknown
+	}
+}
@@ -0,0 +1,122 @@
+package pagerduty
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	prommodel "github.com/prometheus/common/model"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+)
+
+// response is the PagerDuty Events API response, the clients
+// could depend on it.
+type response struct {
+	Status   string   `json:"status"`
+	Message  string   `json:"message"`
+	DedupKey string   `json:"dedup_key,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+func (w *webhookHandler) HandleEvents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		event := eventV2{}
+		err := ctx.ShouldBindJSON(&event)
+		if err != nil {
+			w.logger.Errorf("error unmarshalling JSON: %s", err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, response{Status: "invalid event", Message: "Event object is invalid", Errors: []string{err.Error()}})
+			return
+		}
+
+		if !w.validRoutingKey(event.RoutingKey) {
+			w.logger.Warningf("event with an invalid routing key")
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, response{Status: "invalid event", Message: "Event object is invalid", Errors: []string{"'routing_key' is invalid"}})
+			return
+		}
+
+		if errs := event.validate(); len(errs) > 0 {
+			w.logger.Errorf("invalid event: %v", errs)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, response{Status: "invalid event", Message: "Event object is invalid", Errors: errs})
+			return
+		}
+
+		var alert model.Alert
+		switch event.EventAction {
+		case eventActionTrigger:
+			alert, err = event.toAlert()
+			if err != nil {
+				w.logger.Errorf("error mapping to domain models: %s", err)
+				_ = ctx.AbortWithError(http.StatusBadRequest, err).SetType(gin.ErrorTypePublic)
+				return
+			}
+		case eventActionResolve:
+			alert = w.resolvedAlert(event.DedupKey)
+		default:
+			// The alerts don't have an acknowledged state.
+			w.logger.WithValues(log.KV{"dedupKey": event.DedupKey}).Debugf("ignoring acknowledge event")
+			ctx.JSON(http.StatusAccepted, response{Status: "success", Message: "Event processed", DedupKey: event.DedupKey})
+			return
+		}
+
+		props := forward.Properties{
+			CustomChatID: ctx.Query(w.cfg.ChatIDQueryString),
+		}
+		ag := &model.AlertGroup{ID: alert.ID, Alerts: []model.Alert{alert}, Status: alert.Status}
+		err = w.forwarder.Forward(ctx.Request.Context(), props, ag)
+		if err != nil {
+			w.logger.Errorf("error forwarding alert: %s", err)
+
+			if errors.Is(err, internalerrors.ErrInvalidConfiguration) {
+				_ = ctx.AbortWithError(http.StatusBadRequest, err).SetType(gin.ErrorTypePublic)
+				return
+			}
+
+			_ = ctx.AbortWithError(http.StatusInternalServerError, err).SetType(gin.ErrorTypePublic)
+			return
+		}
+
+		w.rememberAlert(alert)
+		ctx.JSON(http.StatusAccepted, response{Status: "success", Message: "Event processed", DedupKey: alert.ID})
+	}
+}
+
+// resolvedAlert returns the resolved alert of the dedup key, the resolve events
+// only have the dedup key, so the triggered alert data is used if we have it.
+func (w *webhookHandler) resolvedAlert(dedupKey string) model.Alert {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	alert, ok := w.triggered[dedupKey]
+	if !ok {
+		alert = model.Alert{
+			ID:          dedupKey,
+			Name:        dedupKey,
+			Labels:      map[string]string{prommodel.AlertNameLabel: dedupKey},
+			Annotations: map[string]string{},
+		}
+	}
+	alert.Status = model.AlertStatusResolved
+
+	return alert
+}
+
+// rememberAlert remembers the triggered alerts until they are resolved.
+func (w *webhookHandler) rememberAlert(alert model.Alert) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !alert.IsFiring() {
+		delete(w.triggered, alert.ID)
+		return
+	}
+
+	if _, ok := w.triggered[alert.ID]; !ok && len(w.triggered) >= maxTriggeredAlerts {
+		w.logger.Warningf("too many triggered alerts, the alert %q data will not be used when resolved", alert.ID)
+		return
+	}
+	w.triggered[alert.ID] = alert
+}
@@ -0,0 +1,175 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+
+	"github.com/slok/alertgram/internal/model"
+)
+
+// Event actions.
+const (
+	eventActionTrigger     = "trigger"
+	eventActionAcknowledge = "acknowledge"
+	eventActionResolve     = "resolve"
+)
+
+// Labels where the PagerDuty event payload is mapped.
+const (
+	LabelSeverity  = "severity"
+	LabelSource    = "source"
+	LabelComponent = "component"
+	LabelGroup     = "group"
+	LabelClass     = "class"
+)
+
+// eventV2 is the event received by the endpoint.
+// It uses the V2 version of the PagerDuty Events API.
+//
+// https://developer.pagerduty.com/docs/events-api-v2/trigger-events/
+type eventV2 struct {
+	RoutingKey  string        `json:"routing_key"`
+	EventAction string        `json:"event_action"`
+	DedupKey    string        `json:"dedup_key"`
+	Payload     *eventPayload `json:"payload"`
+	Images      []eventImage  `json:"images"`
+	Links       []eventLink   `json:"links"`
+	Client      string        `json:"client"`
+	ClientURL   string        `json:"client_url"`
+}
+
+type eventPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component"`
+	Group         string                 `json:"group"`
+	Class         string                 `json:"class"`
+	CustomDetails map[string]interface{} `json:"custom_details"`
+}
+
+type eventImage struct {
+	Src  string `json:"src"`
+	Href string `json:"href"`
+	Alt  string `json:"alt"`
+}
+
+type eventLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// validate validates the event like the PagerDuty Events API, it returns
+// all the errors of the event.
+func (e eventV2) validate() []string {
+	errs := []string{}
+	switch e.EventAction {
+	case eventActionTrigger:
+		if e.Payload == nil {
+			return append(errs, "'payload' is missing or blank")
+		}
+		if e.Payload.Summary == "" {
+			errs = append(errs, "'payload.summary' is missing or blank")
+		}
+		if e.Payload.Source == "" {
+			errs = append(errs, "'payload.source' is missing or blank")
+		}
+		switch e.Payload.Severity {
+		case "critical", "error", "warning", "info":
+		default:
+			errs = append(errs, "'payload.severity' is invalid (must be one of the following: 'critical', 'warning', 'error' or 'info')")
+		}
+		if e.Payload.Timestamp != "" {
+			if _, err := time.Parse(time.RFC3339, e.Payload.Timestamp); err != nil {
+				errs = append(errs, "'payload.timestamp' is invalid (must be an ISO 8601 date)")
+			}
+		}
+	case eventActionAcknowledge, eventActionResolve:
+		if e.DedupKey == "" {
+			errs = append(errs, "'dedup_key' is missing or blank")
+		}
+	default:
+		errs = append(errs, "'event_action' is invalid (must be one of the following: 'trigger', 'acknowledge' or 'resolve')")
+	}
+
+	return errs
+}
+
+// toAlert maps the trigger event into an alert, if the event doesn't have a
+// dedup key, the alert will be identified by its labels.
+func (e eventV2) toAlert() (model.Alert, error) {
+	if e.EventAction != eventActionTrigger || e.Payload == nil {
+		return model.Alert{}, errors.New("only trigger events can be mapped to alerts")
+	}
+
+	p := e.Payload
+	labels := map[string]string{
+		prommodel.AlertNameLabel: p.Summary,
+		LabelSeverity:            p.Severity,
+		LabelSource:              p.Source,
+	}
+	optLabels := map[string]string{LabelComponent: p.Component, LabelGroup: p.Group, LabelClass: p.Class}
+	for k, v := range optLabels {
+		if v != "" {
+			labels[k] = v
+		}
+	}
+
+	annotations := map[string]string{}
+	for k, v := range p.CustomDetails {
+		if s, ok := v.(string); ok {
+			annotations[k] = s
+			continue
+		}
+		data, _ := json.Marshal(v)
+		annotations[k] = string(data)
+	}
+	for i, l := range e.Links {
+		key := l.Text
+		if key == "" {
+			key = "link " + strconv.Itoa(i+1)
+		}
+		annotations[key] = l.Href
+	}
+	for i, img := range e.Images {
+		key := img.Alt
+		if key == "" {
+			key = "image " + strconv.Itoa(i+1)
+		}
+		annotations[key] = img.Src
+	}
+
+	var startsAt time.Time
+	if p.Timestamp != "" {
+		t, err := time.Parse(time.RFC3339, p.Timestamp)
+		if err != nil {
+			return model.Alert{}, fmt.Errorf("invalid timestamp: %w", err)
+		}
+		startsAt = t
+	}
+
+	id := e.DedupKey
+	if id == "" {
+		ls := make(prommodel.LabelSet, len(labels))
+		for k, v := range labels {
+			ls[prommodel.LabelName(k)] = prommodel.LabelValue(v)
+		}
+		id = ls.Fingerprint().String()
+	}
+
+	return model.Alert{
+		ID:           id,
+		Name:         p.Summary,
+		StartsAt:     startsAt,
+		Status:       model.AlertStatusFiring,
+		Labels:       labels,
+		Annotations:  annotations,
+		GeneratorURL: e.ClientURL,
+	}, nil
+}
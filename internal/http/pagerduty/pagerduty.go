@@ -0,0 +1,118 @@
+package pagerduty
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/slok/go-http-metrics/metrics"
+	metricsmiddleware "github.com/slok/go-http-metrics/middleware"
+	metricsmiddlewaregin "github.com/slok/go-http-metrics/middleware/gin"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+)
+
+// Config is the configuration of the WebhookHandler.
+type Config struct {
+	MetricsRecorder   metrics.Recorder
+	WebhookPath       string
+	ChatIDQueryString string
+	ForwardService    forward.Service
+	Debug             bool
+	Logger            log.Logger
+	// RoutingKeys are the accepted events routing keys, the events with
+	// other routing keys will be rejected.
+	RoutingKeys []string
+}
+
+func (c *Config) defaults() error {
+	if c.WebhookPath == "" {
+		c.WebhookPath = "/v2/enqueue"
+	}
+
+	if c.ForwardService == nil {
+		return fmt.Errorf("forward can't be nil")
+	}
+
+	if len(c.RoutingKeys) == 0 {
+		return fmt.Errorf("at least one routing key is required")
+	}
+
+	if c.ChatIDQueryString == "" {
+		c.ChatIDQueryString = "chat-id"
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+// maxTriggeredAlerts is the maximum number of triggered alerts that are
+// remembered to map the resolve events that only have the dedup key.
+const maxTriggeredAlerts = 10000
+
+// More info here: https://developer.pagerduty.com/docs/events-api-v2/trigger-events/.
+type webhookHandler struct {
+	cfg       Config
+	engine    *gin.Engine
+	forwarder forward.Service
+	logger    log.Logger
+
+	mu        sync.Mutex
+	triggered map[string]model.Alert
+}
+
+// validRoutingKey returns true if the routing key is one of the accepted routing keys.
+func (w *webhookHandler) validRoutingKey(key string) bool {
+	valid := false
+	for _, k := range w.cfg.RoutingKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// NewHandler is an HTTP handler that knows how to handle
+// PagerDuty Events API v2 events.
+func NewHandler(cfg Config) (http.Handler, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Debug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	w := &webhookHandler{
+		cfg:       cfg,
+		engine:    gin.New(),
+		forwarder: cfg.ForwardService,
+		logger:    cfg.Logger,
+		triggered: map[string]model.Alert{},
+	}
+
+	// Metrics middleware.
+	mdlw := metricsmiddleware.New(metricsmiddleware.Config{
+		Service:  "pagerduty-api",
+		Recorder: cfg.MetricsRecorder,
+	})
+	w.engine.Use(metricsmiddlewaregin.Handler("", mdlw))
+
+	// Register routes.
+	w.routes()
+
+	return w.engine, nil
+}
+
+func (w *webhookHandler) routes() {
+	w.engine.POST(w.cfg.WebhookPath, w.HandleEvents())
+}
@@ -0,0 +1,259 @@
+package pagerduty_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/http/pagerduty"
+	"github.com/slok/alertgram/internal/internalerrors"
+	forwardmock "github.com/slok/alertgram/internal/mocks/forward"
+	"github.com/slok/alertgram/internal/model"
+)
+
+const triggerEvent = `{
+  "routing_key": "samplekeyhere",
+  "event_action": "trigger",
+  "dedup_key": "disk-full-db1",
+  "payload": {
+    "summary": "Disk full on db1",
+    "source": "db1.test",
+    "severity": "critical",
+    "timestamp": "2021-03-10T11:50:00Z",
+    "component": "postgres",
+    "group": "prod-datapipe",
+    "class": "disk",
+    "custom_details": {"free space": "1%", "mounts": ["/", "/data"]}
+  },
+  "images": [{"src": "http://graphs.test/disk.png", "alt": "disk graph"}],
+  "links": [{"href": "http://runbooks.test/disk", "text": "runbook"}, {"href": "http://db1.test"}],
+  "client": "Monitoring",
+  "client_url": "http://monitoring.test/db1"
+}`
+
+const resolveEvent = `{"routing_key": "samplekeyhere", "event_action": "resolve", "dedup_key": "disk-full-db1"}`
+
+func getBaseAlert() model.Alert {
+	return model.Alert{
+		ID:       "disk-full-db1",
+		Name:     "Disk full on db1",
+		StartsAt: time.Date(2021, 3, 10, 11, 50, 0, 0, time.UTC),
+		Status:   model.AlertStatusFiring,
+		Labels: map[string]string{
+			"alertname": "Disk full on db1",
+			"severity":  "critical",
+			"source":    "db1.test",
+			"component": "postgres",
+			"group":     "prod-datapipe",
+			"class":     "disk",
+		},
+		Annotations: map[string]string{
+			"free space": "1%",
+			"mounts":     `["/","/data"]`,
+			"runbook":    "http://runbooks.test/disk",
+			"link 2":     "http://db1.test",
+			"disk graph": "http://graphs.test/disk.png",
+		},
+		GeneratorURL: "http://monitoring.test/db1",
+	}
+}
+
+func getBaseAlertGroup(status model.AlertStatus) *model.AlertGroup {
+	a := getBaseAlert()
+	a.Status = status
+	return &model.AlertGroup{ID: a.ID, Alerts: []model.Alert{a}, Status: status}
+}
+
+func TestHandleEvents(t *testing.T) {
+	tests := map[string]struct {
+		config   pagerduty.Config
+		urlPath  string
+		events   []string
+		mock     func(t *testing.T, msvc *forwardmock.Service)
+		expCodes []int
+		expBody  string
+	}{
+		"PagerDuty trigger event should be handled correctly (with defaults).": {
+			urlPath: "/v2/enqueue",
+			events:  []string{triggerEvent},
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlertGroup(model.AlertStatusFiring)
+				msvc.On("Forward", mock.Anything, forward.Properties{}, expAlerts).Once().Return(nil)
+			},
+			expCodes: []int{http.StatusAccepted},
+			expBody:  `{"status":"success","message":"Event processed","dedup_key":"disk-full-db1"}`,
+		},
+
+		"PagerDuty trigger event should be handled correctly (with custom params).": {
+			config: pagerduty.Config{
+				WebhookPath:       "/test-enqueue",
+				ChatIDQueryString: "custom-telegram-chat-id",
+			},
+			urlPath: "/test-enqueue?custom-telegram-chat-id=-1009876543210",
+			events:  []string{triggerEvent},
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlertGroup(model.AlertStatusFiring)
+				expProps := forward.Properties{CustomChatID: "-1009876543210"}
+				msvc.On("Forward", mock.Anything, expProps, expAlerts).Once().Return(nil)
+			},
+			expCodes: []int{http.StatusAccepted},
+			expBody:  `{"status":"success","message":"Event processed","dedup_key":"disk-full-db1"}`,
+		},
+
+		"PagerDuty trigger event without dedup key should be identified by its labels.": {
+			urlPath: "/v2/enqueue",
+			events:  []string{`{"routing_key": "samplekeyhere", "event_action": "trigger", "payload": {"summary": "Backup failed", "source": "db1", "severity": "error"}}`},
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				labels := map[string]string{"alertname": "Backup failed", "severity": "error", "source": "db1"}
+				id := prommodel.LabelSet{"alertname": "Backup failed", "severity": "error", "source": "db1"}.Fingerprint().String()
+				expAlerts := &model.AlertGroup{
+					ID:     id,
+					Status: model.AlertStatusFiring,
+					Alerts: []model.Alert{{
+						ID:          id,
+						Name:        "Backup failed",
+						Status:      model.AlertStatusFiring,
+						Labels:      labels,
+						Annotations: map[string]string{},
+					}},
+				}
+				msvc.On("Forward", mock.Anything, forward.Properties{}, expAlerts).Once().Return(nil)
+			},
+			expCodes: []int{http.StatusAccepted},
+		},
+
+		"PagerDuty resolve event should be resolved with the triggered alert data.": {
+			urlPath: "/v2/enqueue",
+			events:  []string{triggerEvent, resolveEvent},
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				msvc.On("Forward", mock.Anything, forward.Properties{}, getBaseAlertGroup(model.AlertStatusFiring)).Once().Return(nil)
+				msvc.On("Forward", mock.Anything, forward.Properties{}, getBaseAlertGroup(model.AlertStatusResolved)).Once().Return(nil)
+			},
+			expCodes: []int{http.StatusAccepted, http.StatusAccepted},
+			expBody:  `{"status":"success","message":"Event processed","dedup_key":"disk-full-db1"}`,
+		},
+
+		"PagerDuty resolve event of an unknown alert should be resolved with the dedup key.": {
+			urlPath: "/v2/enqueue",
+			events:  []string{resolveEvent},
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := &model.AlertGroup{
+					ID:     "disk-full-db1",
+					Status: model.AlertStatusResolved,
+					Alerts: []model.Alert{{
+						ID:          "disk-full-db1",
+						Name:        "disk-full-db1",
+						Status:      model.AlertStatusResolved,
+						Labels:      map[string]string{"alertname": "disk-full-db1"},
+						Annotations: map[string]string{},
+					}},
+				}
+				msvc.On("Forward", mock.Anything, forward.Properties{}, expAlerts).Once().Return(nil)
+			},
+			expCodes: []int{http.StatusAccepted},
+		},
+
+		"PagerDuty acknowledge event should be accepted and ignored.": {
+			urlPath:  "/v2/enqueue",
+			events:   []string{`{"routing_key": "samplekeyhere", "event_action": "acknowledge", "dedup_key": "disk-full-db1"}`},
+			mock:     func(t *testing.T, msvc *forwardmock.Service) {},
+			expCodes: []int{http.StatusAccepted},
+			expBody:  `{"status":"success","message":"Event processed","dedup_key":"disk-full-db1"}`,
+		},
+
+		"PagerDuty internal errors should be propagated to clients (forwarding).": {
+			urlPath: "/v2/enqueue",
+			events:  []string{triggerEvent},
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlertGroup(model.AlertStatusFiring)
+				msvc.On("Forward", mock.Anything, forward.Properties{}, expAlerts).Once().Return(errors.New("whatever"))
+			},
+			expCodes: []int{http.StatusInternalServerError},
+		},
+
+		"PagerDuty configuration errors should be propagated to clients (forwarding).": {
+			urlPath: "/v2/enqueue",
+			events:  []string{triggerEvent},
+			mock: func(t *testing.T, msvc *forwardmock.Service) {
+				expAlerts := getBaseAlertGroup(model.AlertStatusFiring)
+				err := fmt.Errorf("custom error: %w", internalerrors.ErrInvalidConfiguration)
+				msvc.On("Forward", mock.Anything, forward.Properties{}, expAlerts).Once().Return(err)
+			},
+			expCodes: []int{http.StatusBadRequest},
+		},
+
+		"PagerDuty invalid events should be propagated to clients (validation).": {
+			urlPath:  "/v2/enqueue",
+			events:   []string{`{"routing_key": "samplekeyhere", "event_action": "trigger", "payload": {"summary": "Backup failed", "severity": "high"}}`},
+			mock:     func(t *testing.T, msvc *forwardmock.Service) {},
+			expCodes: []int{http.StatusBadRequest},
+			expBody: `{"status":"invalid event","message":"Event object is invalid","errors":[` +
+				`"'payload.source' is missing or blank",` +
+				`"'payload.severity' is invalid (must be one of the following: 'critical', 'warning', 'error' or 'info')"]}`,
+		},
+
+		"PagerDuty invalid events should be propagated to clients (resolve without dedup key).": {
+			urlPath:  "/v2/enqueue",
+			events:   []string{`{"routing_key": "samplekeyhere", "event_action": "resolve"}`},
+			mock:     func(t *testing.T, msvc *forwardmock.Service) {},
+			expCodes: []int{http.StatusBadRequest},
+		},
+
+		"PagerDuty events with an invalid routing key should be rejected.": {
+			urlPath:  "/v2/enqueue",
+			events:   []string{strings.Replace(triggerEvent, "samplekeyhere", "otherkey", 1)},
+			mock:     func(t *testing.T, msvc *forwardmock.Service) {},
+			expCodes: []int{http.StatusBadRequest},
+			expBody:  `{"status":"invalid event","message":"Event object is invalid","errors":["'routing_key' is invalid"]}`,
+		},
+
+		"PagerDuty invalid events should be propagated to clients (JSON formatting).": {
+			urlPath:  "/v2/enqueue",
+			events:   []string{"{"},
+			mock:     func(t *testing.T, msvc *forwardmock.Service) {},
+			expCodes: []int{http.StatusBadRequest},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			msvc := &forwardmock.Service{}
+			test.mock(t, msvc)
+
+			// Execute.
+			test.config.ForwardService = msvc
+			test.config.RoutingKeys = []string{"samplekeyhere"}
+			h, err := pagerduty.NewHandler(test.config)
+			require.NoError(err)
+			var gotBody string
+			for i, event := range test.events {
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPost, test.urlPath, strings.NewReader(event))
+				h.ServeHTTP(w, req)
+
+				require.Equal(test.expCodes[i], w.Code)
+				gotBody = w.Body.String()
+			}
+
+			// Check.
+			if test.expBody != "" {
+				assert.JSONEq(test.expBody, gotBody)
+			}
+			msvc.AssertExpectations(t)
+		})
+	}
+}
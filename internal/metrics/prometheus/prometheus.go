@@ -12,6 +12,7 @@ import (
 	"github.com/slok/alertgram/internal/deadmansswitch"
 	"github.com/slok/alertgram/internal/forward"
 	"github.com/slok/alertgram/internal/notify"
+	"github.com/slok/alertgram/internal/notify/telegram"
 )
 
 const prefix = "alertgram"
@@ -23,8 +24,15 @@ type Recorder struct {
 
 	forwardServiceOpDurHistogram        *prometheus.HistogramVec
 	forwardNotifierOpDurHistogram       *prometheus.HistogramVec
+	forwardDispatchQueuedGauge          prometheus.Gauge
+	forwardDispatchInFlightGauge        prometheus.Gauge
 	templateRendererOpDurHistogram      *prometheus.HistogramVec
 	deadmansswitchServiceOpDurHistogram *prometheus.HistogramVec
+	telegramThrottledSendsCounter       prometheus.Counter
+	telegramSendDelayHistogram          *prometheus.HistogramVec
+	telegramCallbacksCounter            *prometheus.CounterVec
+	telegramCommandsCounter             *prometheus.CounterVec
+	telegramChatMigrationsCounter       prometheus.Counter
 }
 
 // New returns a new Prometheus recorder for the app.
@@ -42,6 +50,20 @@ func New(reg prometheus.Registerer) *Recorder {
 			Buckets:   prometheus.DefBuckets,
 		}, []string{"operation", "success"}),
 
+		forwardDispatchQueuedGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Subsystem: "forward",
+			Name:      "dispatch_queued_notifications",
+			Help:      "The number of notifications waiting to be sent.",
+		}),
+
+		forwardDispatchInFlightGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Subsystem: "forward",
+			Name:      "dispatch_inflight_notifications",
+			Help:      "The number of notifications being sent.",
+		}),
+
 		forwardNotifierOpDurHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: prefix,
 			Subsystem: "notifier",
@@ -65,14 +87,57 @@ func New(reg prometheus.Registerer) *Recorder {
 			Help:      "The duration of the operation in dead man's switch service.",
 			Buckets:   []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
 		}, []string{"operation", "success"}),
+
+		telegramThrottledSendsCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: prefix,
+			Subsystem: "telegram",
+			Name:      "throttled_sends_total",
+			Help:      "The total number of messages throttled by Telegram.",
+		}),
+
+		telegramSendDelayHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prefix,
+			Subsystem: "telegram",
+			Name:      "send_delay_seconds",
+			Help:      "The duration the messages have been delayed before being sent to Telegram.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"reason"}),
+
+		telegramCallbacksCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Subsystem: "telegram",
+			Name:      "callbacks_total",
+			Help:      "The total number of Telegram alert buttons presses handled.",
+		}, []string{"action", "success"}),
+
+		telegramCommandsCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Subsystem: "telegram",
+			Name:      "commands_total",
+			Help:      "The total number of Telegram bot commands handled.",
+		}, []string{"command", "success"}),
+
+		telegramChatMigrationsCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: prefix,
+			Subsystem: "telegram",
+			Name:      "chat_migrations_total",
+			Help:      "The total number of Telegram chats migrated to a new chat (e.g group upgraded to supergroup).",
+		}),
 	}
 
 	// Register all the metrics.
 	reg.MustRegister(
 		r.forwardServiceOpDurHistogram,
 		r.forwardNotifierOpDurHistogram,
+		r.forwardDispatchQueuedGauge,
+		r.forwardDispatchInFlightGauge,
 		r.templateRendererOpDurHistogram,
 		r.deadmansswitchServiceOpDurHistogram,
+		r.telegramThrottledSendsCounter,
+		r.telegramSendDelayHistogram,
+		r.telegramCallbacksCounter,
+		r.telegramCommandsCounter,
+		r.telegramChatMigrationsCounter,
 	)
 
 	return r
@@ -88,6 +153,16 @@ func (r Recorder) ObserveForwardServiceOpDuration(ctx context.Context, op string
 	r.forwardServiceOpDurHistogram.WithLabelValues(op, strconv.FormatBool(success)).Observe(t.Seconds())
 }
 
+// AddForwardDispatchQueued satisfies forward.DispatchMetricsRecorder interface.
+func (r Recorder) AddForwardDispatchQueued(ctx context.Context, quantity int) {
+	r.forwardDispatchQueuedGauge.Add(float64(quantity))
+}
+
+// AddForwardDispatchInFlight satisfies forward.DispatchMetricsRecorder interface.
+func (r Recorder) AddForwardDispatchInFlight(ctx context.Context, quantity int) {
+	r.forwardDispatchInFlightGauge.Add(float64(quantity))
+}
+
 // ObserveTemplateRendererOpDuration satisfies notify.TemplateRendererMetricsRecorder interface.
 func (r Recorder) ObserveTemplateRendererOpDuration(ctx context.Context, rendererType string, op string, success bool, t time.Duration) {
 	r.templateRendererOpDurHistogram.WithLabelValues(rendererType, op, strconv.FormatBool(success)).Observe(t.Seconds())
@@ -98,9 +173,36 @@ func (r Recorder) ObserveDMSServiceOpDuration(ctx context.Context, op string, su
 	r.deadmansswitchServiceOpDurHistogram.WithLabelValues(op, strconv.FormatBool(success)).Observe(t.Seconds())
 }
 
+// IncTelegramThrottledSends satisfies telegram.MetricsRecorder interface.
+func (r Recorder) IncTelegramThrottledSends(ctx context.Context) {
+	r.telegramThrottledSendsCounter.Inc()
+}
+
+// ObserveTelegramSendDelay satisfies telegram.MetricsRecorder interface.
+func (r Recorder) ObserveTelegramSendDelay(ctx context.Context, reason string, t time.Duration) {
+	r.telegramSendDelayHistogram.WithLabelValues(reason).Observe(t.Seconds())
+}
+
+// IncTelegramCallbacks satisfies telegram.MetricsRecorder interface.
+func (r Recorder) IncTelegramCallbacks(ctx context.Context, action string, success bool) {
+	r.telegramCallbacksCounter.WithLabelValues(action, strconv.FormatBool(success)).Inc()
+}
+
+// IncTelegramCommands satisfies telegram.MetricsRecorder interface.
+func (r Recorder) IncTelegramCommands(ctx context.Context, command string, success bool) {
+	r.telegramCommandsCounter.WithLabelValues(command, strconv.FormatBool(success)).Inc()
+}
+
+// IncTelegramChatMigrations satisfies telegram.MetricsRecorder interface.
+func (r Recorder) IncTelegramChatMigrations(ctx context.Context) {
+	r.telegramChatMigrationsCounter.Inc()
+}
+
 // Ensure that the recorder implements the different interfaces of the app.
 var _ forward.NotifierMetricsRecorder = &Recorder{}
 var _ forward.ServiceMetricsRecorder = &Recorder{}
+var _ forward.DispatchMetricsRecorder = &Recorder{}
 var _ deadmansswitch.ServiceMetricsRecorder = &Recorder{}
 var _ notify.TemplateRendererMetricsRecorder = &Recorder{}
+var _ telegram.MetricsRecorder = &Recorder{}
 var _ httpmetrics.Recorder = &Recorder{}
@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	deadmansswitch "github.com/slok/alertgram/internal/deadmansswitch"
+
 	model "github.com/slok/alertgram/internal/model"
 )
 
@@ -28,3 +30,24 @@ func (_m *Service) PushSwitch(ctx context.Context, alertGroup *model.AlertGroup)
 
 	return r0
 }
+
+// Status provides a mock function with given fields: ctx
+func (_m *Service) Status(ctx context.Context) (deadmansswitch.Status, error) {
+	ret := _m.Called(ctx)
+
+	var r0 deadmansswitch.Status
+	if rf, ok := ret.Get(0).(func(context.Context) deadmansswitch.Status); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(deadmansswitch.Status)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
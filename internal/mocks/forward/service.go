@@ -16,6 +16,20 @@ type Service struct {
 	mock.Mock
 }
 
+// Close provides a mock function with given fields: ctx
+func (_m *Service) Close(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Forward provides a mock function with given fields: ctx, props, alertGroup
 func (_m *Service) Forward(ctx context.Context, props forward.Properties, alertGroup *model.AlertGroup) error {
 	ret := _m.Called(ctx, props, alertGroup)
@@ -3,6 +3,9 @@
 package mocks
 
 import (
+	context "context"
+	url "net/url"
+
 	mock "github.com/stretchr/testify/mock"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
@@ -13,6 +16,27 @@ type Client struct {
 	mock.Mock
 }
 
+// MakeRequest provides a mock function with given fields: endpoint, params
+func (_m *Client) MakeRequest(endpoint string, params url.Values) (tgbotapi.APIResponse, error) {
+	ret := _m.Called(endpoint, params)
+
+	var r0 tgbotapi.APIResponse
+	if rf, ok := ret.Get(0).(func(string, url.Values) tgbotapi.APIResponse); ok {
+		r0 = rf(endpoint, params)
+	} else {
+		r0 = ret.Get(0).(tgbotapi.APIResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, url.Values) error); ok {
+		r1 = rf(endpoint, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Send provides a mock function with given fields: c
 func (_m *Client) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
 	ret := _m.Called(c)
@@ -33,3 +57,24 @@ func (_m *Client) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
 
 	return r0, r1
 }
+
+// UploadFile provides a mock function with given fields: ctx, endpoint, params, fieldname, file
+func (_m *Client) UploadFile(ctx context.Context, endpoint string, params map[string]string, fieldname string, file interface{}) (tgbotapi.APIResponse, error) {
+	ret := _m.Called(ctx, endpoint, params, fieldname, file)
+
+	var r0 tgbotapi.APIResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]string, string, interface{}) tgbotapi.APIResponse); ok {
+		r0 = rf(ctx, endpoint, params, fieldname, file)
+	} else {
+		r0 = ret.Get(0).(tgbotapi.APIResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, map[string]string, string, interface{}) error); ok {
+		r1 = rf(ctx, endpoint, params, fieldname, file)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
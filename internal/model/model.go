@@ -53,6 +53,19 @@ type AlertGroup struct {
 	Labels map[string]string
 	// Alerts are all the alerts in the group (firing, resolved, unknown...).
 	Alerts []Alert
+	// Receiver is the receiver of the group on the alerts source (e.g Alertmanager receiver).
+	Receiver string
+	// Status is the status of the group, firing if any of the alerts is firing.
+	Status AlertStatus
+	// CommonLabels are the labels shared by all the alerts of the group.
+	CommonLabels map[string]string
+	// CommonAnnotations are the annotations shared by all the alerts of the group.
+	CommonAnnotations map[string]string
+	// ExternalURL is the URL of the alerts source (e.g Alertmanager URL).
+	ExternalURL string
+	// TruncatedAlerts is the number of alerts of the group that have not been
+	// received because the group had too many alerts.
+	TruncatedAlerts int
 }
 
 // FiringAlerts returns the firing alerts.
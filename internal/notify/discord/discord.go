@@ -0,0 +1,330 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+)
+
+var (
+	// ErrComm will be used when the communication to Discord fails.
+	ErrComm = errors.New("error communicating with discord")
+)
+
+// Embed colors.
+const (
+	colorFiring   = 0xE74C3C
+	colorResolved = 0x2ECC71
+	colorUnknown  = 0x95A5A6
+)
+
+// Discord limits.
+// More info here: https://discord.com/developers/docs/resources/channel#embed-limits.
+const (
+	maxEmbedsPerMessage = 10
+	maxEmbedsSize       = 6000
+	maxTitleLength      = 256
+	maxDescLength       = 4096
+	maxFields           = 25
+	maxFieldNameLength  = 256
+	maxFieldValueLength = 1024
+)
+
+// Config is the configuration of the Notifier.
+type Config struct {
+	// WebhookURLs are the Discord webhook URLs indexed by an alias, the
+	// notifications chat ID will be used as the alias to select the webhook.
+	WebhookURLs map[string]string
+	// DefaultWebhook is the alias of the webhook that will be used by default.
+	DefaultWebhook string
+	// Username is the optional username of the webhook messages, if not
+	// set, the webhook default username will be used.
+	Username string
+	// HTTPClient is the HTTP client used to communicate with Discord.
+	HTTPClient *http.Client
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *Config) defaults() error {
+	if len(c.WebhookURLs) == 0 {
+		return fmt.Errorf("discord webhook URLs are required")
+	}
+
+	if _, ok := c.WebhookURLs[c.DefaultWebhook]; !ok {
+		return fmt.Errorf("discord default webhook %q is missing", c.DefaultWebhook)
+	}
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+type notifier struct {
+	cfg     Config
+	httpCli *http.Client
+	logger  log.Logger
+}
+
+// NewNotifier returns a notifier is a Discord notifier
+// that knows how to send alerts to Discord webhooks.
+func NewNotifier(cfg Config) (forward.Notifier, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &notifier{
+		cfg:     cfg,
+		httpCli: cfg.HTTPClient,
+		logger:  cfg.Logger.WithValues(log.KV{"notifier": "discord"}),
+	}, nil
+}
+
+func (n notifier) Notify(ctx context.Context, notification forward.Notification) error {
+	ag := notification.AlertGroup
+
+	logger := n.logger.WithValues(log.KV{"alertGroup": ag.ID, "alertsNumber": len(ag.Alerts)})
+	select {
+	case <-ctx.Done():
+		logger.Infof("context cancelled, not notifying alerts")
+		return nil
+	default:
+	}
+
+	alias := notification.ChatID
+	if alias == "" {
+		alias = n.cfg.DefaultWebhook
+	}
+	url, ok := n.cfg.WebhookURLs[alias]
+	if !ok {
+		return fmt.Errorf("unknown discord webhook %q: %w", alias, internalerrors.ErrInvalidConfiguration)
+	}
+	logger = logger.WithValues(log.KV{"discordWebhook": alias})
+
+	for _, msg := range n.createMessages(ag) {
+		err := n.send(ctx, url, msg)
+		if err != nil {
+			err = fmt.Errorf("%w: %s", ErrComm, err)
+			return fmt.Errorf("error sending discord message: %w", err)
+		}
+	}
+	logger.Infof("discord message sent")
+
+	return nil
+}
+
+func (n notifier) Type() string { return "discord" }
+
+// message is a Discord webhook message.
+//
+// More info here: https://discord.com/developers/docs/resources/webhook#execute-webhook.
+type message struct {
+	Username string  `json:"username,omitempty"`
+	Content  string  `json:"content,omitempty"`
+	Embeds   []embed `json:"embeds,omitempty"`
+}
+
+type embed struct {
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       int          `json:"color"`
+	Fields      []embedField `json:"fields,omitempty"`
+}
+
+func (e embed) size() int {
+	size := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	for _, f := range e.Fields {
+		size += f.size()
+	}
+	return size
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+func (f embedField) size() int {
+	return utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+}
+
+// createMessages creates the messages of the alert group, one embed for each alert. If
+// the embeds don't fit in a single message they will be split in multiple messages.
+func (n notifier) createMessages(ag model.AlertGroup) []message {
+	// Firing alerts first.
+	alerts := append(ag.FiringAlerts(), ag.ResolvedAlerts()...)
+	for _, a := range ag.Alerts {
+		if a.Status != model.AlertStatusFiring && a.Status != model.AlertStatusResolved {
+			alerts = append(alerts, a)
+		}
+	}
+
+	content := fmt.Sprintf("🚨 %d firing / ✅ %d resolved", len(ag.FiringAlerts()), len(ag.ResolvedAlerts()))
+	msgs := []message{}
+	current := message{Username: n.cfg.Username, Content: content}
+	size := 0
+	for _, a := range alerts {
+		e := alertToEmbed(a)
+		if len(current.Embeds) == maxEmbedsPerMessage || (len(current.Embeds) > 0 && size+e.size() > maxEmbedsSize) {
+			msgs = append(msgs, current)
+			current = message{Username: n.cfg.Username}
+			size = 0
+		}
+		current.Embeds = append(current.Embeds, e)
+		size += e.size()
+	}
+	msgs = append(msgs, current)
+
+	return msgs
+}
+
+func alertToEmbed(a model.Alert) embed {
+	e := embed{
+		Title:       truncate(alertTitle(a), maxTitleLength),
+		Description: truncate(a.Annotations["message"], maxDescLength),
+		URL:         a.GeneratorURL,
+	}
+
+	switch a.Status {
+	case model.AlertStatusFiring:
+		e.Color = colorFiring
+	case model.AlertStatusResolved:
+		e.Color = colorResolved
+	default:
+		e.Color = colorUnknown
+	}
+
+	fields := []embedField{}
+	for _, k := range sortedKeys(a.Labels) {
+		if k == "alertname" {
+			continue
+		}
+		fields = append(fields, newField("🔹 "+k, a.Labels[k]))
+	}
+	for _, k := range sortedKeys(a.Annotations) {
+		if k == "message" {
+			continue
+		}
+		fields = append(fields, newField("🔸 "+k, a.Annotations[k]))
+	}
+	e.Fields = limitFields(e, fields)
+
+	return e
+}
+
+// limitFields returns the fields that fit in the embed, the fields limit and the
+// embed size limit. The fields that don't fit are replaced by a "+N more" field.
+func limitFields(e embed, fields []embedField) []embedField {
+	size := e.size()
+	res := []embedField{}
+	for _, f := range fields {
+		if len(res) == maxFields || size+f.size() > maxEmbedsSize {
+			break
+		}
+		res = append(res, f)
+		size += f.size()
+	}
+
+	// Remove fields until the "+N more" field fits.
+	for len(res) < len(fields) {
+		more := newField("…", fmt.Sprintf("+%d more", len(fields)-len(res)))
+		if len(res) < maxFields && size+more.size() <= maxEmbedsSize {
+			return append(res, more)
+		}
+		size -= res[len(res)-1].size()
+		res = res[:len(res)-1]
+	}
+
+	return res
+}
+
+func alertTitle(a model.Alert) string {
+	status := "❔"
+	switch a.Status {
+	case model.AlertStatusFiring:
+		status = "💥 FIRING"
+	case model.AlertStatusResolved:
+		status = "🟢 RESOLVED"
+	}
+
+	name := a.Name
+	if name == "" {
+		name = a.Labels["alertname"]
+	}
+
+	return fmt.Sprintf("%s: %s", status, name)
+}
+
+func newField(name, value string) embedField {
+	// Discord doesn't allow empty field values.
+	if value == "" {
+		value = "-"
+	}
+	return embedField{
+		Name:   truncate(name, maxFieldNameLength),
+		Value:  truncate(value, maxFieldValueLength),
+		Inline: true,
+	}
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (n notifier) send(ctx context.Context, url string, msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not marshal discord message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected discord response status code %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package discord_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify/discord"
+)
+
+func GetBaseAlertGroup() model.AlertGroup {
+	return model.AlertGroup{
+		ID: "test-alert",
+		Alerts: []model.Alert{
+			{
+				Name:   "ServicePodIsRestarting",
+				Status: model.AlertStatusResolved,
+				Labels: map[string]string{
+					"alertname": "ServicePodIsRestarting",
+					"pod":       "pod-1",
+				},
+				Annotations: map[string]string{
+					"message": "There has been restarting more than 5 times over 20 minutes",
+				},
+			},
+			{
+				Name:   "ServicePodIsRestarting",
+				Status: model.AlertStatusFiring,
+				Labels: map[string]string{
+					"alertname": "ServicePodIsRestarting",
+					"pod":       "pod-2",
+				},
+				Annotations: map[string]string{
+					"message": "There has been restarting more than 5 times over 20 minutes",
+					"graph":   "https://prometheus.test/my-graph",
+				},
+				GeneratorURL: "https://prometheus.test/graph",
+			},
+		},
+	}
+}
+
+func TestNotify(t *testing.T) {
+	tests := map[string]struct {
+		cfg          discord.Config
+		srvStatus    int
+		notification forward.Notification
+		expPath      string
+		expBody      string
+		expErr       error
+	}{
+		"A alertGroup should be sent to the default webhook with colored embeds.": {
+			cfg: discord.Config{
+				WebhookURLs:    map[string]string{"default": "/webhooks/default", "team1": "/webhooks/team1"},
+				DefaultWebhook: "default",
+				Username:       "alertgram",
+			},
+			srvStatus: http.StatusNoContent,
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expPath: "/webhooks/default",
+			expBody: `{
+	"username": "alertgram",
+	"content": "🚨 1 firing / ✅ 1 resolved",
+	"embeds": [
+		{
+			"title": "💥 FIRING: ServicePodIsRestarting",
+			"description": "There has been restarting more than 5 times over 20 minutes",
+			"url": "https://prometheus.test/graph",
+			"color": 15158332,
+			"fields": [
+				{"name": "🔹 pod", "value": "pod-2", "inline": true},
+				{"name": "🔸 graph", "value": "https://prometheus.test/my-graph", "inline": true}
+			]
+		},
+		{
+			"title": "🟢 RESOLVED: ServicePodIsRestarting",
+			"description": "There has been restarting more than 5 times over 20 minutes",
+			"color": 3066993,
+			"fields": [
+				{"name": "🔹 pod", "value": "pod-1", "inline": true}
+			]
+		}
+	]
+}`,
+		},
+
+		"A alertGroup with a chat ID should be sent to the webhook of that alias.": {
+			cfg: discord.Config{
+				WebhookURLs:    map[string]string{"default": "/webhooks/default", "team1": "/webhooks/team1"},
+				DefaultWebhook: "default",
+			},
+			srvStatus: http.StatusNoContent,
+			notification: forward.Notification{
+				ChatID:     "team1",
+				AlertGroup: model.AlertGroup{ID: "test-alert"},
+			},
+			expPath: "/webhooks/team1",
+			expBody: `{"content": "🚨 0 firing / ✅ 0 resolved"}`,
+		},
+
+		"A alertGroup with an unknown chat ID should fail with an invalid configuration error.": {
+			cfg: discord.Config{
+				WebhookURLs:    map[string]string{"default": "/webhooks/default"},
+				DefaultWebhook: "default",
+			},
+			notification: forward.Notification{
+				ChatID:     "team1",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: internalerrors.ErrInvalidConfiguration,
+		},
+
+		"A error from Discord should be processed with communication error.": {
+			cfg: discord.Config{
+				WebhookURLs:    map[string]string{"default": "/webhooks/default"},
+				DefaultWebhook: "default",
+			},
+			srvStatus: http.StatusTooManyRequests,
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: discord.ErrComm,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Discord stand-in.
+			var gotPath, gotBody string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				gotPath, gotBody = r.URL.Path, string(body)
+				w.WriteHeader(test.srvStatus)
+			}))
+			defer srv.Close()
+
+			// Point the webhooks to the stand-in.
+			urls := map[string]string{}
+			for alias, path := range test.cfg.WebhookURLs {
+				urls[alias] = srv.URL + path
+			}
+			test.cfg.WebhookURLs = urls
+
+			// Execute.
+			n, err := discord.NewNotifier(test.cfg)
+			require.NoError(err)
+			err = n.Notify(context.TODO(), test.notification)
+
+			// Check.
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				assert.Equal(test.expPath, gotPath)
+				assert.JSONEq(test.expBody, gotBody)
+			}
+		})
+	}
+}
+
+func TestNotifyEmbedLimits(t *testing.T) {
+	manyLabels := map[string]string{"alertname": "Test"}
+	for i := 0; i < 30; i++ {
+		manyLabels[fmt.Sprintf("l%02d", i)] = "v"
+	}
+	bigAnnotations := map[string]string{"message": strings.Repeat("a", 4096)}
+	for i := 0; i < 5; i++ {
+		bigAnnotations[fmt.Sprintf("a%d", i)] = strings.Repeat("b", 1024)
+	}
+
+	tests := map[string]struct {
+		alert        model.Alert
+		expFields    int
+		expLastValue string
+	}{
+		"A alert with more fields than the Discord limit should replace the rest with a more field.": {
+			alert:        model.Alert{Name: "Test", Status: model.AlertStatusFiring, Labels: manyLabels},
+			expFields:    25,
+			expLastValue: "+6 more",
+		},
+
+		"A alert bigger than the Discord embed size limit should replace the rest with a more field.": {
+			alert:        model.Alert{Name: "Test", Status: model.AlertStatusFiring, Annotations: bigAnnotations},
+			expFields:    2,
+			expLastValue: "+4 more",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Discord stand-in.
+			var gotBody []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotBody, _ = ioutil.ReadAll(r.Body)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer srv.Close()
+
+			n, err := discord.NewNotifier(discord.Config{
+				WebhookURLs:    map[string]string{"default": srv.URL},
+				DefaultWebhook: "default",
+			})
+			require.NoError(err)
+			err = n.Notify(context.TODO(), forward.Notification{AlertGroup: model.AlertGroup{ID: "test", Alerts: []model.Alert{test.alert}}})
+			require.NoError(err)
+
+			// Check.
+			var msg struct {
+				Embeds []struct {
+					Title       string
+					Description string
+					Fields      []struct{ Name, Value string }
+				}
+			}
+			require.NoError(json.Unmarshal(gotBody, &msg))
+			require.Len(msg.Embeds, 1)
+			e := msg.Embeds[0]
+			size := len([]rune(e.Title)) + len([]rune(e.Description))
+			for _, f := range e.Fields {
+				size += len([]rune(f.Name)) + len([]rune(f.Value))
+			}
+			assert.LessOrEqual(size, 6000)
+			if assert.Len(e.Fields, test.expFields) {
+				assert.Equal(test.expLastValue, e.Fields[len(e.Fields)-1].Value)
+			}
+		})
+	}
+}
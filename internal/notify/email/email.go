@@ -0,0 +1,338 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify"
+)
+
+var (
+	// ErrComm will be used when the communication to the SMTP server fails.
+	ErrComm = errors.New("error communicating with smtp server")
+)
+
+// TLSMode is the way the connection with the SMTP server will be secured.
+type TLSMode string
+
+const (
+	// TLSModeStartTLS upgrades the plain connection using STARTTLS, the server is required to support it.
+	TLSModeStartTLS TLSMode = "starttls"
+	// TLSModeTLS uses an implicit TLS connection (normally on port 465).
+	TLSModeTLS TLSMode = "tls"
+	// TLSModeNone doesn't secure the connection.
+	TLSModeNone TLSMode = "none"
+)
+
+// Config is the configuration of the Notifier.
+type Config struct {
+	// SMTPAddress is the `host:port` address of the SMTP server.
+	SMTPAddress string
+	// TLSMode is how the connection is secured, by default STARTTLS.
+	TLSMode TLSMode
+	// InsecureSkipVerify disables the SMTP server TLS certificate verification.
+	InsecureSkipVerify bool
+	// Username is the optional SMTP username, if set, PLAIN auth will be used.
+	Username string
+	// Password is the SMTP password.
+	Password string
+	// From is the sender address of the emails.
+	From string
+	// DefaultRecipients is the comma separated list of recipients where the alerts
+	// will be sent by default. The notifications chat ID will be used as the recipient
+	// list when set.
+	DefaultRecipients string
+	// TemplateRenderer is the renderer that will be used to render the
+	// notifications email body, it should render HTML.
+	TemplateRenderer notify.TemplateRenderer
+	// Timeout is the timeout of the SMTP connection.
+	Timeout time.Duration
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *Config) defaults() error {
+	if c.SMTPAddress == "" {
+		return fmt.Errorf("smtp address is required")
+	}
+
+	if _, _, err := net.SplitHostPort(c.SMTPAddress); err != nil {
+		return fmt.Errorf("invalid smtp address: %w", err)
+	}
+
+	switch c.TLSMode {
+	case "":
+		c.TLSMode = TLSModeStartTLS
+	case TLSModeStartTLS, TLSModeTLS, TLSModeNone:
+	default:
+		return fmt.Errorf("invalid smtp TLS mode %q", c.TLSMode)
+	}
+
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("invalid email from address: %w", err)
+	}
+
+	if _, err := parseRecipients(c.DefaultRecipients); err != nil {
+		return fmt.Errorf("invalid email default recipients: %w", err)
+	}
+
+	if c.TemplateRenderer == nil {
+		c.TemplateRenderer = notify.DefaultTemplateRenderer
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+type notifier struct {
+	tplRenderer notify.TemplateRenderer
+	cfg         Config
+	host        string
+	logger      log.Logger
+}
+
+// NewNotifier returns a notifier is an email notifier
+// that knows how to send alerts using an SMTP server.
+func NewNotifier(cfg Config) (forward.Notifier, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	host, _, _ := net.SplitHostPort(cfg.SMTPAddress)
+
+	return &notifier{
+		cfg:         cfg,
+		tplRenderer: cfg.TemplateRenderer,
+		host:        host,
+		logger:      cfg.Logger.WithValues(log.KV{"notifier": "email"}),
+	}, nil
+}
+
+func (n notifier) Notify(ctx context.Context, notification forward.Notification) error {
+	ag := notification.AlertGroup
+
+	logger := n.logger.WithValues(log.KV{"alertGroup": ag.ID, "alertsNumber": len(ag.Alerts)})
+	select {
+	case <-ctx.Done():
+		logger.Infof("context cancelled, not notifying alerts")
+		return nil
+	default:
+	}
+
+	recipientList := notification.ChatID
+	if recipientList == "" {
+		recipientList = n.cfg.DefaultRecipients
+	}
+	recipients, err := parseRecipients(recipientList)
+	if err != nil {
+		return fmt.Errorf("invalid email recipients %q: %s: %w", recipientList, err, internalerrors.ErrInvalidConfiguration)
+	}
+	logger = logger.WithValues(log.KV{"emailRecipients": len(recipients)})
+
+	msg, err := n.createMessage(ctx, ag, recipients)
+	if err != nil {
+		return fmt.Errorf("could not format the alerts to message: %w", err)
+	}
+
+	err = n.send(ctx, recipients, msg)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrComm, err)
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	logger.Infof("email sent")
+
+	return nil
+}
+
+func (n notifier) Type() string { return "email" }
+
+// parseRecipients parses a comma separated list of email addresses.
+func parseRecipients(list string) ([]string, error) {
+	addrs, err := mail.ParseAddressList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		recipients = append(recipients, addr.Address)
+	}
+
+	return recipients, nil
+}
+
+var tagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// createMessage creates a multipart email with the rendered HTML and a plain text alternative.
+func (n notifier) createMessage(ctx context.Context, ag model.AlertGroup, recipients []string) ([]byte, error) {
+	data, err := n.tplRenderer.Render(ctx, &ag)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering alerts to template: %w", err)
+	}
+	data = strings.TrimSpace(data)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain", content: html.UnescapeString(tagRegexp.ReplaceAllString(data, ""))},
+		{contentType: "text/html", content: "<html><body>" + strings.ReplaceAll(data, "\n", "<br>\n") + "</body></html>"},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", n.cfg.From},
+		{"To", strings.Join(recipients, ", ")},
+		{"Subject", mime.QEncoding.Encode("UTF-8", subject(ag))},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// subject returns the email subject in a similar way to Alertmanager emails
+// (e.g `[FIRING:2, RESOLVED:1] ServicePodIsRestarting`).
+func subject(ag model.AlertGroup) string {
+	status := []string{}
+	if f := len(ag.FiringAlerts()); f > 0 {
+		status = append(status, fmt.Sprintf("FIRING:%d", f))
+	}
+	if r := len(ag.ResolvedAlerts()); r > 0 {
+		status = append(status, fmt.Sprintf("RESOLVED:%d", r))
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, a := range ag.Alerts {
+		name := a.Labels["alertname"]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("[%s] %s", strings.Join(status, ", "), strings.Join(names, ", ")))
+}
+
+func (n notifier) send(ctx context.Context, recipients []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	tlsCfg := &tls.Config{
+		ServerName:         n.host,
+		InsecureSkipVerify: n.cfg.InsecureSkipVerify,
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", n.cfg.SMTPAddress)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if n.cfg.TLSMode == TLSModeTLS {
+		conn = tls.Client(conn, tlsCfg)
+	}
+
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if n.cfg.TLSMode == TLSModeStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server doesn't support STARTTLS")
+		}
+		if err := c.StartTLS(tlsCfg); err != nil {
+			return err
+		}
+	}
+
+	if n.cfg.Username != "" {
+		err := c.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.host))
+		if err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(n.cfg.From)
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, r := range recipients {
+		if err := c.Rcpt(r); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,256 @@
+package email_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	notifymock "github.com/slok/alertgram/internal/mocks/notify"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify/email"
+)
+
+func GetBaseAlertGroup() model.AlertGroup {
+	return model.AlertGroup{
+		ID: "test-alert",
+		Alerts: []model.Alert{
+			{
+				Status: model.AlertStatusFiring,
+				Labels: map[string]string{
+					"alertname": "ServicePodIsRestarting",
+				},
+				Annotations: map[string]string{
+					"message": "There has been restarting more than 5 times over 20 minutes",
+				},
+			},
+		},
+	}
+}
+
+var errTest = errors.New("whatever")
+
+// smtpSink is a minimal SMTP server that stores the received emails.
+type smtpSink struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	from  string
+	rcpts []string
+	data  string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpSink{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	write := func(l string) { _, _ = conn.Write([]byte(l + "\r\n")) }
+
+	write("220 sink ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		s.mu.Lock()
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			write("250 sink")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			write("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpts = append(s.rcpts, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			write("250 ok")
+		case cmd == "DATA":
+			write("354 send data")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					s.mu.Unlock()
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data = data.String()
+			write("250 ok")
+		case cmd == "QUIT":
+			write("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			write("250 ok")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestNotify(t *testing.T) {
+	tests := map[string]struct {
+		cfg          email.Config
+		mocks        func(t *testing.T, mr *notifymock.TemplateRenderer)
+		notification forward.Notification
+		expRcpts     []string
+		expData      []string
+		expErr       error
+	}{
+		"A alertGroup should be rendered and sent to the default recipients.": {
+			cfg: email.Config{
+				TLSMode:           email.TLSModeNone,
+				From:              "Alertgram <alertgram@example.org>",
+				DefaultRecipients: "oncall@example.org",
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("<b>alert1</b>\nmsg1", nil)
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expRcpts: []string{"oncall@example.org"},
+			expData: []string{
+				"From: Alertgram <alertgram@example.org>\r\n",
+				"To: oncall@example.org\r\n",
+				"Subject: [FIRING:1] ServicePodIsRestarting\r\n",
+				"Content-Type: multipart/alternative; boundary=",
+				"Content-Type: text/plain; charset=UTF-8\r\n",
+				"alert1\r\nmsg1",
+				"Content-Type: text/html; charset=UTF-8\r\n",
+				"<html><body><b>alert1</b><br>\r\nmsg1</body></html>",
+			},
+		},
+
+		"A alertGroup with a chat ID should be sent to the chat ID recipients.": {
+			cfg: email.Config{
+				TLSMode:           email.TLSModeNone,
+				From:              "alertgram@example.org",
+				DefaultRecipients: "oncall@example.org",
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("<b>alert1</b>", nil)
+			},
+			notification: forward.Notification{
+				ChatID:     "team1@example.org, Team 2 <team2@example.org>",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expRcpts: []string{"team1@example.org", "team2@example.org"},
+			expData:  []string{"To: team1@example.org, team2@example.org\r\n"},
+		},
+
+		"A alertGroup with invalid chat ID recipients should fail with an invalid configuration error.": {
+			cfg: email.Config{
+				TLSMode:           email.TLSModeNone,
+				From:              "alertgram@example.org",
+				DefaultRecipients: "oncall@example.org",
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {},
+			notification: forward.Notification{
+				ChatID:     "-1001234567",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: internalerrors.ErrInvalidConfiguration,
+		},
+
+		"A error in the template rendering process should be processed.": {
+			cfg: email.Config{
+				TLSMode:           email.TLSModeNone,
+				From:              "alertgram@example.org",
+				DefaultRecipients: "oncall@example.org",
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("", errTest)
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: errTest,
+		},
+
+		"A STARTTLS required connection to a server without STARTTLS should fail with communication error.": {
+			cfg: email.Config{
+				TLSMode:           email.TLSModeStartTLS,
+				From:              "alertgram@example.org",
+				DefaultRecipients: "oncall@example.org",
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("<b>alert1</b>", nil)
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: email.ErrComm,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			sink := newSMTPSink(t)
+			defer sink.ln.Close()
+
+			// Mocks.
+			mr := &notifymock.TemplateRenderer{}
+			test.mocks(t, mr)
+			cfg := test.cfg
+			cfg.SMTPAddress = sink.ln.Addr().String()
+			cfg.TemplateRenderer = mr
+
+			// Execute.
+			n, err := email.NewNotifier(cfg)
+			require.NoError(err)
+			err = n.Notify(context.TODO(), test.notification)
+
+			// Check.
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				mr.AssertExpectations(t)
+				sink.mu.Lock()
+				defer sink.mu.Unlock()
+				assert.Equal("alertgram@example.org", sink.from)
+				assert.Equal(test.expRcpts, sink.rcpts)
+				for _, exp := range test.expData {
+					assert.Contains(sink.data, exp)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify"
+)
+
+var (
+	// ErrComm will be used when the communication to Matrix fails.
+	ErrComm = errors.New("error communicating with matrix")
+)
+
+// Config is the configuration of the Notifier.
+type Config struct {
+	// HomeserverURL is the Matrix homeserver URL (e.g `https://matrix.example.org`).
+	HomeserverURL string
+	// AccessToken is the access token of the Matrix user that will send the messages.
+	AccessToken string
+	// DefaultRoomID is the room ID where the alerts will be sent by default.
+	DefaultRoomID string
+	// TemplateRenderer is the renderer that will be used to render the
+	// notifications before sending to Matrix, it should render HTML.
+	TemplateRenderer notify.TemplateRenderer
+	// HTTPClient is the HTTP client used to communicate with Matrix.
+	HTTPClient *http.Client
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *Config) defaults() error {
+	if c.HomeserverURL == "" {
+		return fmt.Errorf("matrix homeserver URL is required")
+	}
+
+	if c.AccessToken == "" {
+		return fmt.Errorf("matrix access token is required")
+	}
+
+	if c.DefaultRoomID == "" {
+		return fmt.Errorf("matrix default room ID is required")
+	}
+
+	if c.TemplateRenderer == nil {
+		c.TemplateRenderer = notify.DefaultTemplateRenderer
+	}
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+type notifier struct {
+	tplRenderer notify.TemplateRenderer
+	cfg         Config
+	httpCli     *http.Client
+	logger      log.Logger
+}
+
+// NewNotifier returns a notifier is a Matrix notifier
+// that knows how to send alerts to Matrix rooms.
+func NewNotifier(cfg Config) (forward.Notifier, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &notifier{
+		cfg:         cfg,
+		tplRenderer: cfg.TemplateRenderer,
+		httpCli:     cfg.HTTPClient,
+		logger:      cfg.Logger.WithValues(log.KV{"notifier": "matrix"}),
+	}, nil
+}
+
+func (n *notifier) Notify(ctx context.Context, notification forward.Notification) error {
+	ag := notification.AlertGroup
+
+	logger := n.logger.WithValues(log.KV{"alertGroup": ag.ID, "alertsNumber": len(ag.Alerts)})
+	select {
+	case <-ctx.Done():
+		logger.Infof("context cancelled, not notifying alerts")
+		return nil
+	default:
+	}
+
+	roomID := notification.ChatID
+	if roomID == "" {
+		roomID = n.cfg.DefaultRoomID
+	}
+	logger = logger.WithValues(log.KV{"matrixRoomID": roomID})
+
+	msg, err := n.createMessage(ctx, notification)
+	if err != nil {
+		return fmt.Errorf("could not format the alerts to message: %w", err)
+	}
+
+	err = n.send(ctx, roomID, txnID(roomID, ag, msg), msg)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrComm, err)
+		return fmt.Errorf("error sending matrix message: %w", err)
+	}
+	logger.Infof("matrix message sent")
+
+	return nil
+}
+
+func (n *notifier) Type() string { return "matrix" }
+
+// message is a Matrix `m.room.message` event content.
+//
+// More info here: https://spec.matrix.org/v1.1/client-server-api/#mroommessage.
+type message struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+var tagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+func (n *notifier) createMessage(ctx context.Context, notification forward.Notification) (*message, error) {
+	data, err := n.tplRenderer.Render(ctx, &notification.AlertGroup)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering alerts to template: %w", err)
+	}
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return nil, fmt.Errorf("rendered alerts are empty")
+	}
+
+	return &message{
+		MsgType: "m.text",
+		// The plain body is used by the clients that don't support HTML.
+		Body:          html.UnescapeString(tagRegexp.ReplaceAllString(data, "")),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: strings.ReplaceAll(data, "\n", "<br>\n"),
+	}, nil
+}
+
+// txnID returns the transaction ID of the message, the homeserver ignores the events
+// with an already used transaction ID, so it's derived from the notification instead of
+// being random, this way the retries of the same notification (even after a restart
+// using the queue) are not sent twice to the room. The alerts status and times are
+// part of the ID, so an alert that fires again with the same message is not ignored.
+func txnID(roomID string, ag model.AlertGroup, msg *message) string {
+	values := []string{roomID, ag.ID, msg.MsgType, msg.Body, msg.FormattedBody}
+	for _, a := range ag.Alerts {
+		values = append(values, a.ID, strconv.Itoa(int(a.Status)), strconv.FormatInt(a.StartsAt.UnixNano(), 10), strconv.FormatInt(a.EndsAt.UnixNano(), 10))
+	}
+
+	h := sha256.New()
+	for _, v := range values {
+		// Separate the values so different values can't produce the same hash.
+		fmt.Fprintf(h, "%d:%s", len(v), v)
+	}
+
+	return "alertgram-" + hex.EncodeToString(h.Sum(nil))
+}
+
+// send sends the message event to the room.
+//
+// More info here: https://spec.matrix.org/v1.1/client-server-api/#put_matrixclientv3roomsroomidsendeventtypetxnid.
+func (n *notifier) send(ctx context.Context, roomID, txnID string, msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not marshal matrix message: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/_matrix/client/r0/rooms/%s/send/m.room.message/%s",
+		strings.TrimSuffix(n.cfg.HomeserverURL, "/"), url.PathEscape(roomID), url.PathEscape(txnID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.cfg.AccessToken)
+
+	resp, err := n.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected matrix response status code %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
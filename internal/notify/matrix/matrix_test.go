@@ -0,0 +1,196 @@
+package matrix_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	notifymock "github.com/slok/alertgram/internal/mocks/notify"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify/matrix"
+)
+
+func GetBaseAlertGroup() model.AlertGroup {
+	return model.AlertGroup{
+		ID: "test-alert",
+		Alerts: []model.Alert{
+			{
+				Labels: map[string]string{
+					"alertname": "ServicePodIsRestarting",
+				},
+				Annotations: map[string]string{
+					"message": "There has been restarting more than 5 times over 20 minutes",
+				},
+			},
+		},
+	}
+}
+
+var errTest = errors.New("whatever")
+
+type matrixRequest struct {
+	method        string
+	path          string
+	authorization string
+	body          string
+}
+
+func TestNotify(t *testing.T) {
+	tests := map[string]struct {
+		mocks        func(t *testing.T, mr *notifymock.TemplateRenderer)
+		srvStatus    int
+		notification forward.Notification
+		expPathRoom  string
+		expBody      string
+		expErr       error
+	}{
+		"A alertGroup should be rendered and sent to the default room as HTML.": {
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("\n<b>alert1</b> &amp; more\nmsg1\n", nil)
+			},
+			srvStatus: http.StatusOK,
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expPathRoom: "!default:matrix.test",
+			expBody:     `{"msgtype":"m.text","body":"alert1 & more\nmsg1","format":"org.matrix.custom.html","formatted_body":"<b>alert1</b> &amp; more<br>\nmsg1"}`,
+		},
+
+		"A alertGroup with a chat ID should be sent to that room.": {
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("<b>alert1</b>", nil)
+			},
+			srvStatus: http.StatusOK,
+			notification: forward.Notification{
+				ChatID:     "!custom:matrix.test",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expPathRoom: "!custom:matrix.test",
+			expBody:     `{"msgtype":"m.text","body":"alert1","format":"org.matrix.custom.html","formatted_body":"<b>alert1</b>"}`,
+		},
+
+		"A error in the template rendering process should be processed.": {
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("", errTest)
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: errTest,
+		},
+
+		"A error from the Matrix homeserver should be processed with communication error.": {
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("<b>alert1</b>", nil)
+			},
+			srvStatus: http.StatusForbidden,
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: matrix.ErrComm,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Matrix homeserver stand-in.
+			var gotReq *matrixRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				gotReq = &matrixRequest{
+					method:        r.Method,
+					path:          r.URL.Path,
+					authorization: r.Header.Get("Authorization"),
+					body:          string(body),
+				}
+				w.WriteHeader(test.srvStatus)
+				_, _ = w.Write([]byte(`{"event_id": "$test"}`))
+			}))
+			defer srv.Close()
+
+			// Mocks.
+			mr := &notifymock.TemplateRenderer{}
+			test.mocks(t, mr)
+
+			// Execute.
+			n, err := matrix.NewNotifier(matrix.Config{
+				HomeserverURL:    srv.URL,
+				AccessToken:      "test-token",
+				DefaultRoomID:    "!default:matrix.test",
+				TemplateRenderer: mr,
+			})
+			require.NoError(err)
+			err = n.Notify(context.TODO(), test.notification)
+
+			// Check.
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				mr.AssertExpectations(t)
+				if assert.NotNil(gotReq) {
+					assert.Equal(http.MethodPut, gotReq.method)
+					assert.Regexp(`^/_matrix/client/r0/rooms/`+test.expPathRoom+`/send/m\.room\.message/alertgram-[0-9a-f]{64}$`, gotReq.path)
+					assert.Equal("Bearer test-token", gotReq.authorization)
+					assert.JSONEq(test.expBody, gotReq.body)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyTransactionID(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Matrix homeserver stand-in.
+	paths := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = w.Write([]byte(`{"event_id": "$test"}`))
+	}))
+	defer srv.Close()
+
+	mr := &notifymock.TemplateRenderer{}
+	mr.On("Render", mock.Anything, mock.Anything).Twice().Return("<b>alert1</b>", nil)
+	mr.On("Render", mock.Anything, mock.Anything).Once().Return("<b>alert2</b>", nil)
+	mr.On("Render", mock.Anything, mock.Anything).Once().Return("<b>alert1</b>", nil)
+
+	n, err := matrix.NewNotifier(matrix.Config{
+		HomeserverURL:    srv.URL,
+		AccessToken:      "test-token",
+		DefaultRoomID:    "!default:matrix.test",
+		TemplateRenderer: mr,
+	})
+	require.NoError(err)
+	notification := forward.Notification{AlertGroup: GetBaseAlertGroup()}
+	for i := 0; i < 3; i++ {
+		require.NoError(n.Notify(context.TODO(), notification))
+	}
+	// The same alert firing again.
+	notification.AlertGroup.Alerts[0].StartsAt = time.Now()
+	require.NoError(n.Notify(context.TODO(), notification))
+
+	// The retries of the same message should reuse the transaction ID, so the
+	// homeserver doesn't send them twice, and a different message or the same
+	// message of alerts that fired again shouldn't.
+	require.Len(paths, 4)
+	assert.Equal(paths[0], paths[1])
+	assert.NotEqual(paths[0], paths[2])
+	assert.NotEqual(paths[0], paths[3])
+}
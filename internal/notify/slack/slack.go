@@ -0,0 +1,318 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/notify"
+)
+
+var (
+	// ErrComm will be used when the communication to Slack fails.
+	ErrComm = errors.New("error communicating with slack")
+)
+
+const (
+	defAPIURL = "https://slack.com/api"
+	// Slack limits.
+	// More info here: https://api.slack.com/reference/block-kit/blocks.
+	maxSectionTextLength = 3000
+	maxBlocks            = 50
+)
+
+// Config is the configuration of the Notifier.
+type Config struct {
+	// WebhookURL is the Slack incoming webhook URL, if set, the messages will be sent
+	// using the incoming webhook instead of the `chat.postMessage` API.
+	WebhookURL string
+	// APIToken is the Slack bot token that will be used to send the messages
+	// using the `chat.postMessage` API.
+	APIToken string
+	// APIURL is the Slack API URL, by default `https://slack.com/api`.
+	APIURL string
+	// DefaultChannel is the channel where the alerts will be sent by default.
+	DefaultChannel string
+	// TemplateRenderer is the renderer that will be used to render the
+	// notifications before sending to Slack, it should render Slack mrkdwn format.
+	TemplateRenderer notify.TemplateRenderer
+	// HTTPClient is the HTTP client used to communicate with Slack.
+	HTTPClient *http.Client
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *Config) defaults() error {
+	if c.WebhookURL == "" && c.APIToken == "" {
+		return fmt.Errorf("slack webhook URL or API token is required")
+	}
+
+	if c.WebhookURL == "" && c.DefaultChannel == "" {
+		return fmt.Errorf("slack default channel is required when using the API")
+	}
+
+	if c.APIURL == "" {
+		c.APIURL = defAPIURL
+	}
+
+	if c.TemplateRenderer == nil {
+		c.TemplateRenderer = DefaultTemplateRenderer
+	}
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+type notifier struct {
+	tplRenderer notify.TemplateRenderer
+	cfg         Config
+	httpCli     *http.Client
+	logger      log.Logger
+}
+
+// NewNotifier returns a notifier is a Slack notifier
+// that knows how to send alerts to Slack.
+func NewNotifier(cfg Config) (forward.Notifier, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &notifier{
+		cfg:         cfg,
+		tplRenderer: cfg.TemplateRenderer,
+		httpCli:     cfg.HTTPClient,
+		logger:      cfg.Logger.WithValues(log.KV{"notifier": "slack"}),
+	}, nil
+}
+
+func (n notifier) Notify(ctx context.Context, notification forward.Notification) error {
+	ag := notification.AlertGroup
+
+	logger := n.logger.WithValues(log.KV{"alertGroup": ag.ID, "alertsNumber": len(ag.Alerts)})
+	select {
+	case <-ctx.Done():
+		logger.Infof("context cancelled, not notifying alerts")
+		return nil
+	default:
+	}
+
+	msg, err := n.createMessage(ctx, notification)
+	if err != nil {
+		return fmt.Errorf("could not format the alerts to message: %w", err)
+	}
+	logger = logger.WithValues(log.KV{"slackChannel": msg.Channel})
+
+	err = n.send(ctx, msg)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrComm, err)
+		return fmt.Errorf("error sending slack message: %w", err)
+	}
+	logger.Infof("slack message sent")
+
+	return nil
+}
+
+func (n notifier) Type() string { return "slack" }
+
+// message is a Slack message.
+//
+// More info here: https://api.slack.com/methods/chat.postMessage.
+type message struct {
+	Channel string  `json:"channel,omitempty"`
+	Text    string  `json:"text"`
+	Blocks  []block `json:"blocks"`
+}
+
+// block is a Slack Block Kit block.
+//
+// More info here: https://api.slack.com/reference/block-kit/blocks.
+type block struct {
+	Type string     `json:"type"`
+	Text *textBlock `json:"text,omitempty"`
+}
+
+type textBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (n notifier) createMessage(ctx context.Context, notification forward.Notification) (*message, error) {
+	channel := notification.ChatID
+	if channel == "" {
+		channel = n.cfg.DefaultChannel
+	}
+
+	data, err := n.tplRenderer.Render(ctx, &notification.AlertGroup)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering alerts to template: %w", err)
+	}
+
+	blocks := textToBlocks(data)
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("rendered alerts are empty")
+	}
+
+	return &message{
+		Channel: channel,
+		Text:    blocks[0].Text.Text, // Used as the fallback on notifications.
+		Blocks:  blocks,
+	}, nil
+}
+
+// textToBlocks converts the rendered mrkdwn text in section blocks, one section for
+// each paragraph (normally an alert) separated by dividers.
+func textToBlocks(text string) []block {
+	blocks := []block{}
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n ")
+		if paragraph == "" {
+			continue
+		}
+
+		for _, section := range splitText(paragraph, maxSectionTextLength) {
+			if len(blocks) > 0 {
+				blocks = append(blocks, block{Type: "divider"})
+			}
+			blocks = append(blocks, block{
+				Type: "section",
+				Text: &textBlock{Type: "mrkdwn", Text: section},
+			})
+		}
+	}
+
+	// Slack doesn't allow more blocks, remove the ones that don't fit.
+	if len(blocks) > maxBlocks {
+		blocks = blocks[:maxBlocks-1]
+		blocks = append(blocks, block{
+			Type: "section",
+			Text: &textBlock{Type: "mrkdwn", Text: "_Some alerts have been omitted because the message is too big._"},
+		})
+	}
+
+	return blocks
+}
+
+// splitText splits the text by lines in chunks that don't exceed the limit.
+func splitText(text string, limit int) []string {
+	chunks := []string{}
+	current := ""
+	for _, line := range strings.Split(text, "\n") {
+		// Lines that exceed the limit are cut.
+		if utf8.RuneCountInString(line) > limit {
+			line = string([]rune(line)[:limit])
+		}
+
+		switch {
+		case current == "":
+			current = line
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(line) > limit:
+			chunks = append(chunks, current)
+			current = line
+		default:
+			current += "\n" + line
+		}
+	}
+
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// send sends the message using the incoming webhook or the API.
+func (n notifier) send(ctx context.Context, msg *message) error {
+	if n.cfg.WebhookURL != "" {
+		return n.sendWebhook(ctx, msg)
+	}
+	return n.sendAPI(ctx, msg)
+}
+
+// sendWebhook sends the message using the incoming webhooks.
+//
+// More info here: https://api.slack.com/messaging/webhooks.
+func (n notifier) sendWebhook(ctx context.Context, msg *message) error {
+	body, err := n.post(ctx, n.cfg.WebhookURL, msg, nil)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(string(body)) != "ok" {
+		return fmt.Errorf("slack webhook error: %s", body)
+	}
+
+	return nil
+}
+
+// sendAPI sends the message using the `chat.postMessage` API.
+func (n notifier) sendAPI(ctx context.Context, msg *message) error {
+	headers := map[string]string{"Authorization": "Bearer " + n.cfg.APIToken}
+	body, err := n.post(ctx, strings.TrimSuffix(n.cfg.APIURL, "/")+"/chat.postMessage", msg, headers)
+	if err != nil {
+		return err
+	}
+
+	res := struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return fmt.Errorf("could not decode slack API response: %w", err)
+	}
+
+	if !res.OK {
+		return fmt.Errorf("slack API error: %s", res.Error)
+	}
+
+	return nil
+}
+
+func (n notifier) post(ctx context.Context, url string, msg *message, headers map[string]string) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected slack response status code %d: %s", resp.StatusCode, body)
+	}
+
+	return body, nil
+}
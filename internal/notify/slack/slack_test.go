@@ -0,0 +1,201 @@
+package slack_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	notifymock "github.com/slok/alertgram/internal/mocks/notify"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify/slack"
+)
+
+func GetBaseAlertGroup() model.AlertGroup {
+	return model.AlertGroup{
+		ID: "test-alert",
+		Alerts: []model.Alert{
+			{
+				Labels: map[string]string{
+					"alertname": "ServicePodIsRestarting",
+				},
+				Annotations: map[string]string{
+					"message": "There has been restarting more than 5 times over 20 minutes",
+				},
+			},
+		},
+	}
+}
+
+var errTest = errors.New("whatever")
+
+type slackRequest struct {
+	path          string
+	authorization string
+	body          string
+}
+
+func TestNotify(t *testing.T) {
+	tests := map[string]struct {
+		cfg          func(srvURL string) slack.Config
+		mocks        func(t *testing.T, mr *notifymock.TemplateRenderer)
+		srvResp      func(w http.ResponseWriter)
+		notification forward.Notification
+		expReq       *slackRequest
+		expErr       error
+	}{
+		"A alertGroup should be rendered and sent to the Slack incoming webhook.": {
+			cfg: func(srvURL string) slack.Config {
+				return slack.Config{WebhookURL: srvURL + "/webhook"}
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("\n*alert1*\nmsg1\n\n*alert2*\nmsg2\n", nil)
+			},
+			srvResp: func(w http.ResponseWriter) { _, _ = w.Write([]byte("ok")) },
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expReq: &slackRequest{
+				path: "/webhook",
+				body: `{"text":"*alert1*\nmsg1","blocks":[{"type":"section","text":{"type":"mrkdwn","text":"*alert1*\nmsg1"}},{"type":"divider"},{"type":"section","text":{"type":"mrkdwn","text":"*alert2*\nmsg2"}}]}`,
+			},
+		},
+
+		"A alertGroup should be rendered and sent to the Slack API on the notification channel.": {
+			cfg: func(srvURL string) slack.Config {
+				return slack.Config{APIToken: "xoxb-test", APIURL: srvURL + "/api", DefaultChannel: "#default"}
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("*alert1*", nil)
+			},
+			srvResp: func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"ok": true}`)) },
+			notification: forward.Notification{
+				ChatID:     "#alerts",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expReq: &slackRequest{
+				path:          "/api/chat.postMessage",
+				authorization: "Bearer xoxb-test",
+				body:          `{"channel":"#alerts","text":"*alert1*","blocks":[{"type":"section","text":{"type":"mrkdwn","text":"*alert1*"}}]}`,
+			},
+		},
+
+		"A alertGroup without channel should be sent to the Slack API default channel.": {
+			cfg: func(srvURL string) slack.Config {
+				return slack.Config{APIToken: "xoxb-test", APIURL: srvURL + "/api", DefaultChannel: "#default"}
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("*alert1*", nil)
+			},
+			srvResp: func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"ok": true}`)) },
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expReq: &slackRequest{
+				path:          "/api/chat.postMessage",
+				authorization: "Bearer xoxb-test",
+				body:          `{"channel":"#default","text":"*alert1*","blocks":[{"type":"section","text":{"type":"mrkdwn","text":"*alert1*"}}]}`,
+			},
+		},
+
+		"A error in the template rendering process should be processed.": {
+			cfg: func(srvURL string) slack.Config {
+				return slack.Config{WebhookURL: srvURL + "/webhook"}
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("", errTest)
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: errTest,
+		},
+
+		"A error from the Slack API should be processed with communication error.": {
+			cfg: func(srvURL string) slack.Config {
+				return slack.Config{APIToken: "xoxb-test", APIURL: srvURL + "/api", DefaultChannel: "#default"}
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("*alert1*", nil)
+			},
+			srvResp: func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"ok": false, "error": "channel_not_found"}`)) },
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: slack.ErrComm,
+		},
+
+		"A error from the Slack incoming webhook should be processed with communication error.": {
+			cfg: func(srvURL string) slack.Config {
+				return slack.Config{WebhookURL: srvURL + "/webhook"}
+			},
+			mocks: func(t *testing.T, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("*alert1*", nil)
+			},
+			srvResp: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte("no_service"))
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: slack.ErrComm,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Slack stand-in.
+			var gotReq *slackRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				gotReq = &slackRequest{
+					path:          r.URL.Path,
+					authorization: r.Header.Get("Authorization"),
+					body:          string(body),
+				}
+				test.srvResp(w)
+			}))
+			defer srv.Close()
+
+			// Mocks.
+			mr := &notifymock.TemplateRenderer{}
+			test.mocks(t, mr)
+			cfg := test.cfg(srv.URL)
+			cfg.TemplateRenderer = mr
+
+			// Execute.
+			n, err := slack.NewNotifier(cfg)
+			require.NoError(err)
+			err = n.Notify(context.TODO(), test.notification)
+
+			// Check.
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				mr.AssertExpectations(t)
+				if assert.NotNil(gotReq) {
+					assert.Equal(test.expReq.path, gotReq.path)
+					assert.Equal(test.expReq.authorization, gotReq.authorization)
+					assert.JSONEq(test.expReq.body, gotReq.body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify"
+)
+
+// escape escapes the text for Slack mrkdwn format.
+//
+// More info here: https://api.slack.com/reference/surfaces/formatting#escaping.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// DefaultTemplateRenderer is the default renderer that will render the
+// alerts using a premade Slack mrkdwn template.
+var DefaultTemplateRenderer = notify.TemplateRendererFunc(func(_ context.Context, ag *model.AlertGroup) (string, error) {
+	var b bytes.Buffer
+	err := defTemplate.Execute(&b, ag)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", notify.ErrRenderTemplate, err)
+	}
+
+	return b.String(), nil
+})
+
+var defTemplate = template.Must(template.New("def").Funcs(sprig.TxtFuncMap()).Funcs(template.FuncMap{"escape": escape}).Parse(`
+{{- define "alert" }}
+{{- .Annotations.message | escape }}
+{{- range $key, $value := .Labels }}
+	{{- if ne $key "alertname" }}
+	{{- if hasPrefix "http" $value }}
+:small_blue_diamond: <{{ $value }}|{{ $key | escape }}>
+	{{- else }}
+:small_blue_diamond: *{{ $key | escape }}*: {{ $value | escape }}
+	{{- end }}
+	{{- end }}
+{{- end }}
+{{- range $key, $value := .Annotations }}
+	{{- if ne $key "message" }}
+	{{- if hasPrefix "http" $value }}
+:small_orange_diamond: <{{ $value }}|{{ $key | escape }}>
+	{{- else }}
+:small_orange_diamond: *{{ $key | escape }}*: {{ $value | escape }}
+	{{- end }}
+	{{- end }}
+{{- end }}
+{{- end }}
+
+{{- if .HasFiring }}
+:rotating_light: *FIRING ALERTS* :rotating_light:
+{{- range .FiringAlerts }}
+
+:boom: *{{ .Labels.alertname | escape }}*
+{{ template "alert" . }}
+{{- end }}
+{{- end }}
+{{- if .HasResolved }}
+
+:white_check_mark: *RESOLVED ALERTS* :white_check_mark:
+{{- range .ResolvedAlerts }}
+
+:large_green_circle: *{{ .Labels.alertname | escape }}*
+{{ template "alert" . }}
+{{- end }}
+{{- end }}
+{{- if .TruncatedAlerts }}
+
+:warning: {{ .TruncatedAlerts }} alerts truncated
+{{- end }}
+`))
@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+)
+
+// BotsNotifierConfig is the configuration of the BotsNotifier.
+type BotsNotifierConfig struct {
+	// Bots are the notifiers of the Telegram bots by alias, each bot is a
+	// Telegram notifier with its own client and rate limits.
+	Bots map[string]forward.Notifier
+	// DefaultBot is the alias of the bot that will send the notifications
+	// whose chat ID doesn't select a bot.
+	DefaultBot string
+}
+
+func (c *BotsNotifierConfig) defaults() error {
+	if len(c.Bots) == 0 {
+		return fmt.Errorf("at least one telegram bot is required")
+	}
+
+	if _, ok := c.Bots[c.DefaultBot]; !ok {
+		return fmt.Errorf("telegram default bot %q is missing", c.DefaultBot)
+	}
+
+	return nil
+}
+
+type botsNotifier struct {
+	cfg BotsNotifierConfig
+}
+
+// NewBotsNotifier returns a Telegram notifier that sends the notifications using
+// multiple Telegram bots. The bot is selected with its alias as a prefix of the
+// notification chat ID (e.g `team1:-1001234567890`), if the chat ID doesn't have the
+// bot alias the default bot will be used, and if the chat ID only has the bot alias
+// (e.g `team1:`) the default chat of the bot will be used.
+func NewBotsNotifier(cfg BotsNotifierConfig) (forward.Notifier, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &botsNotifier{cfg: cfg}, nil
+}
+
+func (b botsNotifier) Notify(ctx context.Context, notification forward.Notification) error {
+	bot, chatID := SplitBotChatID(notification.ChatID)
+	if bot == "" {
+		bot = b.cfg.DefaultBot
+	}
+
+	notifier, ok := b.cfg.Bots[bot]
+	if !ok {
+		return fmt.Errorf("%w: unknown telegram bot %q", internalerrors.ErrInvalidConfiguration, bot)
+	}
+
+	notification.ChatID = chatID
+	return notifier.Notify(ctx, notification)
+}
+
+func (b botsNotifier) Type() string { return "telegram" }
+
+// SplitBotChatID splits the bot alias from the chat ID of a Telegram chat target,
+// e.g: `team1:-1001234567890/42`, if the target doesn't have a bot alias, the bot
+// will be empty.
+func SplitBotChatID(s string) (bot, chatID string) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return "", s
+	}
+
+	return s[:i], s[i+1:]
+}
@@ -0,0 +1,89 @@
+package telegram_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	forwardmock "github.com/slok/alertgram/internal/mocks/forward"
+	"github.com/slok/alertgram/internal/notify/telegram"
+)
+
+func TestBotsNotifier(t *testing.T) {
+	tests := map[string]struct {
+		chatID string
+		mocks  func(mdef, mteam *forwardmock.Notifier)
+		expErr error
+	}{
+		"A chat ID without bot should be sent by the default bot.": {
+			chatID: "-1001234",
+			mocks: func(mdef, mteam *forwardmock.Notifier) {
+				exp := forward.Notification{ChatID: "-1001234", AlertGroup: GetBaseAlertGroup()}
+				mdef.On("Notify", context.TODO(), exp).Once().Return(nil)
+			},
+		},
+
+		"A missing chat ID should be sent by the default bot to its default chat.": {
+			chatID: "",
+			mocks: func(mdef, mteam *forwardmock.Notifier) {
+				exp := forward.Notification{ChatID: "", AlertGroup: GetBaseAlertGroup()}
+				mdef.On("Notify", context.TODO(), exp).Once().Return(nil)
+			},
+		},
+
+		"A chat ID with bot should be sent by the bot.": {
+			chatID: "team1:-1001234/42",
+			mocks: func(mdef, mteam *forwardmock.Notifier) {
+				exp := forward.Notification{ChatID: "-1001234/42", AlertGroup: GetBaseAlertGroup()}
+				mteam.On("Notify", context.TODO(), exp).Once().Return(nil)
+			},
+		},
+
+		"A chat ID with only the bot should be sent by the bot to its default chat.": {
+			chatID: "team1:",
+			mocks: func(mdef, mteam *forwardmock.Notifier) {
+				exp := forward.Notification{ChatID: "", AlertGroup: GetBaseAlertGroup()}
+				mteam.On("Notify", context.TODO(), exp).Once().Return(nil)
+			},
+		},
+
+		"A chat ID with an unknown bot should fail.": {
+			chatID: "team2:-1001234",
+			mocks:  func(mdef, mteam *forwardmock.Notifier) {},
+			expErr: internalerrors.ErrInvalidConfiguration,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mdef := &forwardmock.Notifier{}
+			mteam := &forwardmock.Notifier{}
+			test.mocks(mdef, mteam)
+
+			// Execute.
+			n, err := telegram.NewBotsNotifier(telegram.BotsNotifierConfig{
+				Bots:       map[string]forward.Notifier{"default": mdef, "team1": mteam},
+				DefaultBot: "default",
+			})
+			require.NoError(err)
+			err = n.Notify(context.TODO(), forward.Notification{ChatID: test.chatID, AlertGroup: GetBaseAlertGroup()})
+
+			// Check.
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				mdef.AssertExpectations(t)
+				mteam.AssertExpectations(t)
+			}
+		})
+	}
+}
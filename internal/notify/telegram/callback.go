@@ -0,0 +1,274 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/slok/alertgram/internal/alertmanager"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+)
+
+// CallbackClient is an small abstraction for the telegram-bot-api client
+// to answer the button presses.
+type CallbackClient interface {
+	Client
+	AnswerCallbackQuery(config tgbotapi.CallbackConfig) (tgbotapi.APIResponse, error)
+}
+
+// CallbackHandlerConfig is the configuration of the CallbackHandler.
+type CallbackHandlerConfig struct {
+	// Client is the Telegram client.
+	Client CallbackClient
+	// AlertmanagerClient is the Alertmanager API client used to silence the alerts,
+	// if not set the silence buttons will not work.
+	AlertmanagerClient alertmanager.Client
+	// MessageStore is the store of the sent messages, it should be the same
+	// store used by the notifier.
+	MessageStore MessageStore
+	// AllowedUserIDs are the users that can press the buttons.
+	AllowedUserIDs []int
+	// AllowedChatIDs are the chats where the buttons can be pressed by anyone.
+	AllowedChatIDs []int64
+	// MetricsRecorder is the metrics recorder.
+	MetricsRecorder MetricsRecorder
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *CallbackHandlerConfig) defaults() error {
+	if c.Client == nil {
+		return fmt.Errorf("telegram client is required")
+	}
+
+	if c.MessageStore == nil {
+		return fmt.Errorf("telegram message store is required")
+	}
+
+	if len(c.AllowedUserIDs) == 0 && len(c.AllowedChatIDs) == 0 {
+		return fmt.Errorf("at least one allowed telegram user or chat is required")
+	}
+
+	if c.MetricsRecorder == nil {
+		c.MetricsRecorder = dummyRecorder
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+type callbackHandler struct {
+	cfg     CallbackHandlerConfig
+	client  CallbackClient
+	amCli   alertmanager.Client
+	store   MessageStore
+	allow   allowList
+	metrics MetricsRecorder
+	logger  log.Logger
+}
+
+// NewCallbackHandler returns a new UpdateHandler that handles the presses
+// of the alert buttons.
+func NewCallbackHandler(cfg CallbackHandlerConfig) (UpdateHandler, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &callbackHandler{
+		cfg:     cfg,
+		client:  cfg.Client,
+		amCli:   cfg.AlertmanagerClient,
+		store:   cfg.MessageStore,
+		allow:   newAllowList(cfg.AllowedUserIDs, cfg.AllowedChatIDs),
+		metrics: cfg.MetricsRecorder,
+		logger:  cfg.Logger.WithValues(log.KV{"service": "telegram-callback-handler"}),
+	}, nil
+}
+
+func (c callbackHandler) HandleUpdate(ctx context.Context, update tgbotapi.Update) error {
+	q := update.CallbackQuery
+	if q == nil {
+		return nil
+	}
+
+	data, err := parseCallbackData(q.Data)
+	if err != nil {
+		_ = c.answer(q, "Unknown action")
+		return err
+	}
+
+	if data.action == callbackActionNoop {
+		return c.answer(q, "")
+	}
+
+	var chat *tgbotapi.Chat
+	if q.Message != nil {
+		chat = q.Message.Chat
+	}
+	if !c.allow.allowed(q.From, chat) {
+		c.logger.WithValues(log.KV{"action": data.action, "user": userName(q.From)}).Warningf("telegram button pressed by a not allowed user or chat")
+		c.metrics.IncTelegramCallbacks(ctx, data.action, false)
+		return c.answer(q, "⛔ You are not allowed to use this bot")
+	}
+
+	var answer string
+	switch data.action {
+	case callbackActionAck:
+		answer, err = c.ack(ctx, q, data)
+	case callbackActionSilence:
+		answer, err = c.silence(ctx, q, data)
+	}
+	c.metrics.IncTelegramCallbacks(ctx, data.action, err == nil)
+	if err != nil {
+		_ = c.answer(q, "❌ "+answer)
+		return fmt.Errorf("could not handle %s action: %w", data.action, err)
+	}
+
+	return c.answer(q, answer)
+}
+
+func (c callbackHandler) ack(ctx context.Context, q *tgbotapi.CallbackQuery, data *callbackData) (string, error) {
+	user := userName(q.From)
+	c.logger.WithValues(log.KV{"alert": data.fingerprint, "user": user}).Infof("alert acknowledged")
+
+	err := c.editMessage(ctx, q, data, "👍 Acked by "+user, "acknowledged by "+user)
+	if err != nil {
+		return "Could not acknowledge the alert", err
+	}
+
+	return "👍 Acknowledged", nil
+}
+
+func (c callbackHandler) silence(ctx context.Context, q *tgbotapi.CallbackQuery, data *callbackData) (string, error) {
+	if c.amCli == nil {
+		return "Silences are not enabled", fmt.Errorf("alertmanager client is missing")
+	}
+
+	alerts, err := c.amCli.GetAlerts(ctx)
+	if err != nil {
+		return "Could not get the alert", err
+	}
+
+	var labels map[string]string
+	for _, a := range alerts {
+		if callbackAlertID(a.ID) == data.fingerprint {
+			labels = a.Labels
+			break
+		}
+	}
+	if labels == nil {
+		return "The alert is not firing anymore", fmt.Errorf("alert %s missing on alertmanager", data.fingerprint)
+	}
+
+	// Silence the alert by all its labels.
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	matchers := make([]alertmanager.Matcher, 0, len(names))
+	for _, k := range names {
+		matchers = append(matchers, alertmanager.Matcher{Name: k, Value: labels[k], IsEqual: true})
+	}
+
+	user := userName(q.From)
+	now := time.Now()
+	id, err := c.amCli.CreateSilence(ctx, alertmanager.Silence{
+		Matchers:  matchers,
+		StartsAt:  now,
+		EndsAt:    now.Add(data.duration),
+		CreatedBy: user,
+		Comment:   "Silenced from Telegram by " + user,
+	})
+	if err != nil {
+		return "Could not silence the alert", err
+	}
+	c.logger.WithValues(log.KV{"alert": data.fingerprint, "user": user, "silence": id}).Infof("alert silenced")
+
+	d := formatDuration(data.duration)
+	err = c.editMessage(ctx, q, data, fmt.Sprintf("🔕 Silenced %s by %s", d, user), fmt.Sprintf("silenced for %s by %s", d, user))
+	if err != nil {
+		return "Could not update the message", err
+	}
+
+	return "🔕 Silenced for " + d, nil
+}
+
+// editMessage shows the action on the message, replacing the action buttons of the alert
+// and adding the action to the message text.
+func (c callbackHandler) editMessage(ctx context.Context, q *tgbotapi.CallbackQuery, data *callbackData, button, text string) error {
+	if q.Message == nil || q.Message.Chat == nil {
+		return nil
+	}
+	chatID, msgID := q.Message.Chat.ID, q.Message.MessageID
+	key := messageKey(chatID, msgID)
+
+	// Without the original message we can't edit it.
+	sent, err := c.store.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("could not get the stored message: %w", err)
+	}
+	if sent == nil || sent.Keyboard == nil {
+		c.logger.Warningf("telegram message %d not found, the message will not be edited", msgID)
+		return nil
+	}
+
+	kb, alertNumber := replaceAlertButtons(*sent.Keyboard, data.action, data.fingerprint, button)
+	alert := "Alert"
+	if alertNumber > 0 {
+		alert = fmt.Sprintf("Alert #%d", alertNumber)
+	}
+	newText := sent.Text + "\n\n" + sent.ParseMode.italic(alert+" "+text)
+
+	var edit tgbotapi.Chattable
+	maxLength := maxMessageLength
+	if sent.Caption {
+		maxLength = maxCaptionLength
+	}
+	if utf8.RuneCountInString(newText) <= maxLength {
+		edit = newEditMessage(chatID, msgID, newText, sent.ParseMode.telegramParseMode(), sent.Caption, &kb)
+	} else {
+		newText = sent.Text
+		edit = tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, kb)
+	}
+
+	_, err = c.client.Send(edit)
+	if err != nil && !isTelegramError(err, "message is not modified") {
+		return err
+	}
+
+	sent.Text = newText
+	sent.Keyboard = &kb
+	err = c.store.Set(ctx, []string{key}, *sent)
+	if err != nil {
+		return fmt.Errorf("could not store the message: %w", err)
+	}
+
+	return nil
+}
+
+func (c callbackHandler) answer(q *tgbotapi.CallbackQuery, text string) error {
+	_, err := c.client.AnswerCallbackQuery(tgbotapi.NewCallback(q.ID, text))
+	return err
+}
+
+func userName(u *tgbotapi.User) string {
+	switch {
+	case u == nil:
+		return "unknown"
+	case u.UserName != "":
+		return "@" + u.UserName
+	default:
+		return strings.TrimSpace(u.FirstName + " " + u.LastName)
+	}
+}
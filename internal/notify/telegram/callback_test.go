@@ -0,0 +1,188 @@
+package telegram_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/alertmanager"
+	"github.com/slok/alertgram/internal/forward"
+	alertmanagermock "github.com/slok/alertgram/internal/mocks/alertmanager"
+	notifymock "github.com/slok/alertgram/internal/mocks/notify"
+	telegrammock "github.com/slok/alertgram/internal/mocks/notify/telegram"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify/telegram"
+)
+
+func TestCallbackHandler(t *testing.T) {
+	firingAlert := model.Alert{
+		ID:     "fp1",
+		Status: model.AlertStatusFiring,
+		Labels: map[string]string{"alertname": "ServicePodIsRestarting", "pod": "pod-1"},
+	}
+	callback := func(data string) tgbotapi.Update {
+		return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:      "callback1",
+			From:    &tgbotapi.User{UserName: "alice"},
+			Message: &tgbotapi.Message{MessageID: 42, Chat: &tgbotapi.Chat{ID: 1234}},
+			Data:    data,
+		}}
+	}
+	edit := func(text string, kb tgbotapi.InlineKeyboardMarkup) tgbotapi.EditMessageTextConfig {
+		e := tgbotapi.NewEditMessageText(1234, 42, text)
+		e.ParseMode = "HTML"
+		e.DisableWebPagePreview = true
+		e.ReplyMarkup = &kb
+		return e
+	}
+
+	tests := map[string]struct {
+		update tgbotapi.Update
+		mocks  func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client)
+		expErr bool
+	}{
+		"Pressing the ack button should show the ack on the message.": {
+			update: callback("ack:fp1"),
+			mocks: func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client) {
+				expKB := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("🔕 1h", "silence:1h:fp1"),
+					tgbotapi.NewInlineKeyboardButtonData("👍 Acked by @alice", "noop"),
+				))
+				mcli.On("Send", edit("rendered\n\n<i>Alert acknowledged by @alice</i>", expKB)).Once().Return(tgbotapi.Message{}, nil)
+				mcli.On("AnswerCallbackQuery", tgbotapi.NewCallback("callback1", "👍 Acknowledged")).Once().Return(tgbotapi.APIResponse{}, nil)
+			},
+		},
+
+		"Pressing the silence button should silence the alert and show the silence on the message.": {
+			update: callback("silence:1h:fp1"),
+			mocks: func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client) {
+				mam.On("GetAlerts", mock.Anything).Once().Return([]model.Alert{firingAlert}, nil)
+				expSilence := mock.MatchedBy(func(s alertmanager.Silence) bool {
+					expMatchers := []alertmanager.Matcher{
+						{Name: "alertname", Value: "ServicePodIsRestarting", IsEqual: true},
+						{Name: "pod", Value: "pod-1", IsEqual: true},
+					}
+					return assert.Equal(t, expMatchers, s.Matchers) &&
+						assert.Equal(t, time.Hour, s.EndsAt.Sub(s.StartsAt)) &&
+						assert.Equal(t, "@alice", s.CreatedBy)
+				})
+				mam.On("CreateSilence", mock.Anything, expSilence).Once().Return("silence1", nil)
+
+				expKB := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("🔕 Silenced 1h by @alice", "noop"),
+					tgbotapi.NewInlineKeyboardButtonData("👍 Ack", "ack:fp1"),
+				))
+				mcli.On("Send", edit("rendered\n\n<i>Alert silenced for 1h by @alice</i>", expKB)).Once().Return(tgbotapi.Message{}, nil)
+				mcli.On("AnswerCallbackQuery", tgbotapi.NewCallback("callback1", "🔕 Silenced for 1h")).Once().Return(tgbotapi.APIResponse{}, nil)
+			},
+		},
+
+		"Pressing the silence button of an alert that is not firing should fail.": {
+			update: callback("silence:1h:fp1"),
+			mocks: func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client) {
+				mam.On("GetAlerts", mock.Anything).Once().Return([]model.Alert{}, nil)
+				mcli.On("AnswerCallbackQuery", tgbotapi.NewCallback("callback1", "❌ The alert is not firing anymore")).Once().Return(tgbotapi.APIResponse{}, nil)
+			},
+			expErr: true,
+		},
+
+		"Pressing a button from a not allowed user or chat should be rejected.": {
+			update: tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+				ID:      "callback1",
+				From:    &tgbotapi.User{ID: 2, UserName: "mallory"},
+				Message: &tgbotapi.Message{MessageID: 42, Chat: &tgbotapi.Chat{ID: 5678}},
+				Data:    "silence:1h:fp1",
+			}},
+			mocks: func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client) {
+				mcli.On("AnswerCallbackQuery", tgbotapi.NewCallback("callback1", "⛔ You are not allowed to use this bot")).Once().Return(tgbotapi.APIResponse{}, nil)
+			},
+		},
+
+		"Pressing a button from an allowed user on any chat should be handled.": {
+			update: tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+				ID:      "callback1",
+				From:    &tgbotapi.User{ID: 1, UserName: "alice"},
+				Message: &tgbotapi.Message{MessageID: 42, Chat: &tgbotapi.Chat{ID: 5678}},
+				Data:    "ack:fp1",
+			}},
+			mocks: func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client) {
+				mcli.On("AnswerCallbackQuery", tgbotapi.NewCallback("callback1", "👍 Acknowledged")).Once().Return(tgbotapi.APIResponse{}, nil)
+			},
+		},
+
+		"Pressing a button with invalid data should fail.": {
+			update: callback("silence:1h:"),
+			mocks: func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client) {
+				mcli.On("AnswerCallbackQuery", tgbotapi.NewCallback("callback1", "Unknown action")).Once().Return(tgbotapi.APIResponse{}, nil)
+			},
+			expErr: true,
+		},
+
+		"Pressing a button without action should only answer.": {
+			update: callback("noop"),
+			mocks: func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client) {
+				mcli.On("AnswerCallbackQuery", tgbotapi.NewCallback("callback1", "")).Once().Return(tgbotapi.APIResponse{}, nil)
+			},
+		},
+
+		"Updates that are not callbacks should be ignored.": {
+			update: tgbotapi.Update{Message: &tgbotapi.Message{Text: "hello"}},
+			mocks:  func(t *testing.T, mcli *telegrammock.CallbackClient, mam *alertmanagermock.Client) {},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Send the alert message with the buttons.
+			store := telegram.NewMemoryMessageStore(0)
+			mncli := &telegrammock.Client{}
+			mncli.On("Send", mock.Anything).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+			mr := &notifymock.TemplateRenderer{}
+			mr.On("Render", mock.Anything, mock.Anything).Once().Return("rendered", nil)
+			n, err := telegram.NewNotifier(telegram.Config{
+				DefaultTelegramChatID: 1234,
+				AlertButtons:          true,
+				SilenceDurations:      []time.Duration{time.Hour},
+				MessageStore:          store,
+				Client:                mncli,
+				TemplateRenderer:      mr,
+			})
+			require.NoError(err)
+			err = n.Notify(context.TODO(), forward.Notification{AlertGroup: model.AlertGroup{ID: "group1", Alerts: []model.Alert{firingAlert}}})
+			require.NoError(err)
+
+			// Mocks.
+			mcli := &telegrammock.CallbackClient{}
+			mam := &alertmanagermock.Client{}
+			test.mocks(t, mcli, mam)
+
+			// Execute.
+			h, err := telegram.NewCallbackHandler(telegram.CallbackHandlerConfig{
+				Client:             mcli,
+				AlertmanagerClient: mam,
+				MessageStore:       store,
+				AllowedUserIDs:     []int{1},
+				AllowedChatIDs:     []int64{1234},
+			})
+			require.NoError(err)
+			err = h.HandleUpdate(context.TODO(), test.update)
+
+			// Check.
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			mcli.AssertExpectations(t)
+			mam.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+type apiClient struct {
+	*tgbotapi.BotAPI
+}
+
+// NewClient returns a Client based on the telegram-bot-api client, the file uploads
+// of the library return the Telegram errors without the response parameters, so the
+// uploads are made by the client to return them (e.g retry after, migrated chat)
+// and to cancel them with the context.
+func NewClient(bot *tgbotapi.BotAPI) Client {
+	return apiClient{BotAPI: bot}
+}
+
+// NewCallbackClient returns a CallbackClient based on the telegram-bot-api client.
+func NewCallbackClient(bot *tgbotapi.BotAPI) CallbackClient {
+	return apiClient{BotAPI: bot}
+}
+
+// UploadFile satisfies Client interface.
+func (a apiClient) UploadFile(ctx context.Context, endpoint string, params map[string]string, fieldname string, file interface{}) (tgbotapi.APIResponse, error) {
+	f, ok := file.(tgbotapi.FileBytes)
+	if !ok {
+		return a.BotAPI.UploadFile(endpoint, params, fieldname, file)
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for k, v := range params {
+		err := w.WriteField(k, v)
+		if err != nil {
+			return tgbotapi.APIResponse{}, err
+		}
+	}
+	fw, err := w.CreateFormFile(fieldname, f.Name)
+	if err != nil {
+		return tgbotapi.APIResponse{}, err
+	}
+	_, err = fw.Write(f.Bytes)
+	if err != nil {
+		return tgbotapi.APIResponse{}, err
+	}
+	err = w.Close()
+	if err != nil {
+		return tgbotapi.APIResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(tgbotapi.APIEndpoint, a.Token, endpoint), body)
+	if err != nil {
+		return tgbotapi.APIResponse{}, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return tgbotapi.APIResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var apiResp tgbotapi.APIResponse
+	err = json.NewDecoder(resp.Body).Decode(&apiResp)
+	if err != nil {
+		return tgbotapi.APIResponse{}, fmt.Errorf("could not decode telegram response: %w", err)
+	}
+
+	if !apiResp.Ok {
+		parameters := tgbotapi.ResponseParameters{}
+		if apiResp.Parameters != nil {
+			parameters = *apiResp.Parameters
+		}
+		return apiResp, tgbotapi.Error{Message: apiResp.Description, ResponseParameters: parameters}
+	}
+
+	return apiResp, nil
+}
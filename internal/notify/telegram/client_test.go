@@ -0,0 +1,85 @@
+package telegram_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/notify/telegram"
+)
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestClientUploadFile(t *testing.T) {
+	tests := map[string]struct {
+		respBody string
+		expResp  tgbotapi.APIResponse
+		expErr   error
+	}{
+		"An uploaded file should return the telegram response.": {
+			respBody: `{"ok": true, "result": {"message_id": 10}}`,
+			expResp:  tgbotapi.APIResponse{Ok: true, Result: []byte(`{"message_id": 10}`)},
+		},
+
+		"A failed upload should return the telegram error with the response parameters.": {
+			respBody: `{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 5", "parameters": {"retry_after": 5}}`,
+			expErr:   tgbotapi.Error{Message: "Too Many Requests: retry after 5", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			httpCli := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				assert.Equal("/bottoken/sendPhoto", r.URL.Path)
+				require.NoError(r.ParseMultipartForm(1024))
+				assert.Equal("1234", r.FormValue("chat_id"))
+				f, _, err := r.FormFile("photo")
+				require.NoError(err)
+				data, _ := ioutil.ReadAll(f)
+				assert.Equal("png", string(data))
+
+				return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(test.respBody))}, nil
+			})}
+			cli := telegram.NewClient(&tgbotapi.BotAPI{Token: "token", Client: httpCli})
+
+			resp, err := cli.UploadFile(context.TODO(), "sendPhoto", map[string]string{"chat_id": "1234"}, "photo", tgbotapi.FileBytes{Name: "graph.png", Bytes: []byte("png")})
+			if test.expErr != nil {
+				var tgErr tgbotapi.Error
+				if assert.True(errors.As(err, &tgErr)) {
+					assert.Equal(test.expErr, tgErr)
+				}
+				return
+			}
+			require.NoError(err)
+			assert.Equal(test.expResp.Ok, resp.Ok)
+			assert.JSONEq(string(test.expResp.Result), string(resp.Result))
+		})
+	}
+}
+
+func TestClientUploadFileContextCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	httpCli := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})}
+	cli := telegram.NewClient(&tgbotapi.BotAPI{Token: "token", Client: httpCli})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cli.UploadFile(ctx, "sendPhoto", map[string]string{"chat_id": "1234"}, "photo", tgbotapi.FileBytes{Name: "graph.png", Bytes: []byte("png")})
+	assert.True(errors.Is(err, context.Canceled))
+}
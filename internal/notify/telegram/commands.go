@@ -0,0 +1,397 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/slok/alertgram/internal/alertmanager"
+	"github.com/slok/alertgram/internal/deadmansswitch"
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+)
+
+const commandsHelp = `<b>Alertgram commands</b>
+
+/alerts - List the firing alerts.
+/silence &lt;matchers&gt; &lt;duration&gt; - Silence the alerts, e.g. <code>/silence alertname="HighLatency" env=~"prod|staging" 2h</code>.
+/dms - Show the dead man's switch status.
+/mute &lt;duration&gt; - Mute the alerts of this chat, e.g. <code>/mute 30m</code>.
+/unmute - Unmute the alerts of this chat.`
+
+// commands are the bot commands, the groups could have other bots commands
+// so the rest of commands are ignored.
+var commands = map[string]bool{
+	"alerts":  true,
+	"silence": true,
+	"dms":     true,
+	"mute":    true,
+	"unmute":  true,
+	"help":    true,
+	"start":   true,
+}
+
+// deniedCommand is the metrics command of the commands from not allowed users
+// or chats, so they can't create arbitrary metrics.
+const deniedCommand = "denied"
+
+// CommandsHandlerConfig is the configuration of the CommandsHandler.
+type CommandsHandlerConfig struct {
+	// Client is the Telegram client.
+	Client Client
+	// AlertmanagerClient is the Alertmanager API client used to list and silence
+	// the alerts, if not set the alerts commands will not work.
+	AlertmanagerClient alertmanager.Client
+	// DeadMansSwitchService is the dead man's switch used to get its status,
+	// by default the dead man's switch is disabled.
+	DeadMansSwitchService deadmansswitch.Service
+	// ChatMuter is used to mute the chats, it should be the same muter used
+	// by the notifier, if not set the mute commands will not work.
+	ChatMuter ChatMuter
+	// AllowedUserIDs are the users that can use the commands.
+	AllowedUserIDs []int
+	// AllowedChatIDs are the chats where the commands can be used by anyone.
+	AllowedChatIDs []int64
+	// BotUserName is the username of the bot, the commands for other bots
+	// (e.g `/alerts@otherbot`) are ignored.
+	BotUserName string
+	// MetricsRecorder is the metrics recorder.
+	MetricsRecorder MetricsRecorder
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *CommandsHandlerConfig) defaults() error {
+	if c.Client == nil {
+		return fmt.Errorf("telegram client is required")
+	}
+
+	if len(c.AllowedUserIDs) == 0 && len(c.AllowedChatIDs) == 0 {
+		return fmt.Errorf("at least one allowed telegram user or chat is required")
+	}
+
+	if c.DeadMansSwitchService == nil {
+		c.DeadMansSwitchService = deadmansswitch.DisabledService
+	}
+
+	if c.MetricsRecorder == nil {
+		c.MetricsRecorder = dummyRecorder
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+type commandsHandler struct {
+	cfg     CommandsHandlerConfig
+	client  Client
+	amCli   alertmanager.Client
+	dms     deadmansswitch.Service
+	muter   ChatMuter
+	allow   allowList
+	metrics MetricsRecorder
+	logger  log.Logger
+}
+
+// NewCommandsHandler returns a new UpdateHandler that handles the bot commands
+// of the allowed users and chats.
+func NewCommandsHandler(cfg CommandsHandlerConfig) (UpdateHandler, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &commandsHandler{
+		cfg:     cfg,
+		client:  cfg.Client,
+		amCli:   cfg.AlertmanagerClient,
+		dms:     cfg.DeadMansSwitchService,
+		muter:   cfg.ChatMuter,
+		allow:   newAllowList(cfg.AllowedUserIDs, cfg.AllowedChatIDs),
+		metrics: cfg.MetricsRecorder,
+		logger:  cfg.Logger.WithValues(log.KV{"service": "telegram-commands-handler"}),
+	}, nil
+}
+
+func (c commandsHandler) HandleUpdate(ctx context.Context, update tgbotapi.Update) error {
+	msg := update.Message
+	if msg == nil || msg.Chat == nil || !msg.IsCommand() {
+		return nil
+	}
+
+	cmd := msg.Command()
+	if !commands[cmd] || !c.forBot(msg) {
+		return nil
+	}
+
+	user := userName(msg.From)
+	logger := c.logger.WithValues(log.KV{"command": cmd, "user": user, "telegramChatID": msg.Chat.ID})
+
+	if !c.allow.allowed(msg.From, msg.Chat) {
+		logger.Warningf("telegram command from a not allowed user or chat")
+		c.metrics.IncTelegramCommands(ctx, deniedCommand, false)
+		return c.reply(msg, "⛔ You are not allowed to use this bot")
+	}
+
+	var reply string
+	var err error
+	switch cmd {
+	case "alerts":
+		reply, err = c.alerts(ctx)
+	case "silence":
+		reply, err = c.silence(ctx, msg.CommandArguments(), user)
+	case "dms":
+		reply, err = c.deadMansSwitch(ctx)
+	case "mute":
+		reply, err = c.mute(ctx, msg.Chat.ID, msg.CommandArguments())
+	case "unmute":
+		reply, err = c.unmute(ctx, msg.Chat.ID)
+	case "help", "start":
+		reply = commandsHelp
+	}
+	c.metrics.IncTelegramCommands(ctx, cmd, err == nil)
+	if err != nil {
+		_ = c.reply(msg, "❌ "+html.EscapeString(reply))
+		return fmt.Errorf("could not handle %s command: %w", cmd, err)
+	}
+	logger.Infof("telegram command handled")
+
+	return c.reply(msg, reply)
+}
+
+// forBot returns true if the command is for the bot, the commands without the bot
+// username are for all the bots of the chat.
+func (c commandsHandler) forBot(msg *tgbotapi.Message) bool {
+	cmd := msg.CommandWithAt()
+	i := strings.Index(cmd, "@")
+	if i < 0 || c.cfg.BotUserName == "" {
+		return true
+	}
+
+	return strings.EqualFold(cmd[i+1:], c.cfg.BotUserName)
+}
+
+func (c commandsHandler) alerts(ctx context.Context) (string, error) {
+	if c.amCli == nil {
+		return "The Alertmanager API is not configured", fmt.Errorf("alertmanager client is missing")
+	}
+
+	all, err := c.amCli.GetAlerts(ctx)
+	if err != nil {
+		return "Could not get the alerts", err
+	}
+
+	// The suppressed alerts are not firing.
+	alerts := make([]model.Alert, 0, len(all))
+	for _, a := range all {
+		if a.IsFiring() {
+			alerts = append(alerts, a)
+		}
+	}
+
+	if len(alerts) == 0 {
+		return "✅ There are no firing alerts", nil
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].StartsAt.Before(alerts[j].StartsAt) })
+
+	// Add the alerts until the message is full.
+	text := fmt.Sprintf("🔥 <b>%d firing alerts</b>\n", len(alerts))
+	for i, a := range alerts {
+		line := "\n" + formatAlertLine(a)
+		more := fmt.Sprintf("\n\n<i>...and %d more alerts</i>", len(alerts)-i)
+		if utf8.RuneCountInString(text+line+more) > maxMessageLength {
+			return text + more, nil
+		}
+		text += line
+	}
+
+	return text, nil
+}
+
+func formatAlertLine(a model.Alert) string {
+	names := make([]string, 0, len(a.Labels))
+	for k := range a.Labels {
+		if k != "alertname" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	labels := make([]string, 0, len(names))
+	for _, k := range names {
+		labels = append(labels, fmt.Sprintf("%s=%q", k, a.Labels[k]))
+	}
+
+	line := "• <b>" + html.EscapeString(a.Name) + "</b>"
+	if since := time.Since(a.StartsAt); !a.StartsAt.IsZero() && since >= time.Minute {
+		line += " for " + formatDuration(since.Truncate(time.Minute))
+	}
+	if len(labels) > 0 {
+		line += " <code>" + html.EscapeString(strings.Join(labels, ", ")) + "</code>"
+	}
+
+	return line
+}
+
+func (c commandsHandler) silence(ctx context.Context, args string, user string) (string, error) {
+	if c.amCli == nil {
+		return "The Alertmanager API is not configured", fmt.Errorf("alertmanager client is missing")
+	}
+
+	matchers, d, err := parseSilenceArgs(args)
+	if err != nil {
+		return "Usage: /silence <matchers> <duration>, " + err.Error(), err
+	}
+
+	now := time.Now()
+	id, err := c.amCli.CreateSilence(ctx, alertmanager.Silence{
+		Matchers:  matchers,
+		StartsAt:  now,
+		EndsAt:    now.Add(d),
+		CreatedBy: user,
+		Comment:   "Silenced from Telegram by " + user,
+	})
+	if err != nil {
+		return "Could not create the silence", err
+	}
+
+	return fmt.Sprintf("🔕 Silence <code>%s</code> created for %s", html.EscapeString(id), formatDuration(d)), nil
+}
+
+// parseSilenceArgs parses the silence command arguments, Alertmanager style label matchers
+// separated by spaces and the duration of the silence at the end.
+func parseSilenceArgs(args string) ([]alertmanager.Matcher, time.Duration, error) {
+	fields, err := splitArgs(args)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(fields) < 2 {
+		return nil, 0, errors.New("at least one matcher and the duration are required")
+	}
+
+	d, err := time.ParseDuration(fields[len(fields)-1])
+	if err != nil || d <= 0 {
+		return nil, 0, fmt.Errorf("invalid duration %q", fields[len(fields)-1])
+	}
+
+	matchers := make([]alertmanager.Matcher, 0, len(fields)-1)
+	for _, f := range fields[:len(fields)-1] {
+		m, err := forward.ParseMatcher(f)
+		if err != nil {
+			return nil, 0, err
+		}
+		matchers = append(matchers, alertmanager.Matcher{
+			Name:    m.Name,
+			Value:   m.Value,
+			IsRegex: m.Type == forward.MatchRegexp || m.Type == forward.MatchNotRegexp,
+			IsEqual: m.Type == forward.MatchEqual || m.Type == forward.MatchRegexp,
+		})
+	}
+
+	return matchers, d, nil
+}
+
+// splitArgs splits the command arguments by spaces, except the spaces inside the
+// double quoted values, e.g. `alertname="High Latency"`. The quotes are kept so
+// the matchers can unquote their values.
+func splitArgs(args string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inQuotes, escaped := false, false
+	for _, r := range args {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuotes && r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && unicode.IsSpace(r):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+		field.WriteRune(r)
+	}
+	if inQuotes {
+		return nil, errors.New("unterminated quoted value")
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
+
+func (c commandsHandler) deadMansSwitch(ctx context.Context) (string, error) {
+	status, err := c.dms.Status(ctx)
+	if err != nil {
+		return "Could not get the dead man's switch status", err
+	}
+
+	lastPush := formatDuration(time.Since(status.LastPush).Truncate(time.Second))
+	switch {
+	case !status.Enabled:
+		return "💤 The dead man's switch is disabled", nil
+	case status.Active:
+		return fmt.Sprintf("🚨 The dead man's switch is <b>active</b>, last alert received %s ago (interval %s)", lastPush, formatDuration(status.Interval)), nil
+	default:
+		return fmt.Sprintf("✅ The dead man's switch is <b>inactive</b>, last alert received %s ago (interval %s)", lastPush, formatDuration(status.Interval)), nil
+	}
+}
+
+func (c commandsHandler) mute(ctx context.Context, chatID int64, args string) (string, error) {
+	if c.muter == nil {
+		return "Muting the chats is not enabled", fmt.Errorf("chat muter is missing")
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(args))
+	if err != nil || d <= 0 {
+		return "Usage: /mute <duration>, e.g. /mute 30m", fmt.Errorf("invalid duration %q", args)
+	}
+
+	until := time.Now().Add(d)
+	err = c.muter.Mute(ctx, chatID, until)
+	if err != nil {
+		return "Could not mute the chat", err
+	}
+
+	return fmt.Sprintf("🔇 Alerts muted on this chat for %s (until %s)", formatDuration(d), until.UTC().Format("2006-01-02 15:04 MST")), nil
+}
+
+func (c commandsHandler) unmute(ctx context.Context, chatID int64) (string, error) {
+	if c.muter == nil {
+		return "Muting the chats is not enabled", fmt.Errorf("chat muter is missing")
+	}
+
+	err := c.muter.Mute(ctx, chatID, time.Time{})
+	if err != nil {
+		return "Could not unmute the chat", err
+	}
+
+	return "🔊 Alerts unmuted on this chat", nil
+}
+
+func (c commandsHandler) reply(msg *tgbotapi.Message, text string) error {
+	r := tgbotapi.NewMessage(msg.Chat.ID, text)
+	r.ParseMode = "HTML"
+	r.DisableWebPagePreview = true
+	r.ReplyToMessageID = msg.MessageID
+	_, err := c.client.Send(r)
+	return err
+}
@@ -0,0 +1,239 @@
+package telegram_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/alertmanager"
+	"github.com/slok/alertgram/internal/deadmansswitch"
+	alertmanagermock "github.com/slok/alertgram/internal/mocks/alertmanager"
+	deadmansswitchmock "github.com/slok/alertgram/internal/mocks/deadmansswitch"
+	telegrammock "github.com/slok/alertgram/internal/mocks/notify/telegram"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify/telegram"
+)
+
+func TestCommandsHandler(t *testing.T) {
+	command := func(userID int, text string) tgbotapi.Update {
+		cmd := text
+		for i, r := range text {
+			if r == ' ' {
+				cmd = text[:i]
+				break
+			}
+		}
+		return tgbotapi.Update{Message: &tgbotapi.Message{
+			MessageID: 42,
+			From:      &tgbotapi.User{ID: userID, UserName: "alice"},
+			Chat:      &tgbotapi.Chat{ID: 1234},
+			Text:      text,
+			Entities:  &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(cmd)}},
+		}}
+	}
+	reply := func(text string) tgbotapi.MessageConfig {
+		r := tgbotapi.NewMessage(1234, text)
+		r.ParseMode = "HTML"
+		r.DisableWebPagePreview = true
+		r.ReplyToMessageID = 42
+		return r
+	}
+
+	tests := map[string]struct {
+		update   tgbotapi.Update
+		mocks    func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service)
+		expMuted bool
+		expErr   bool
+	}{
+		"A command from a not allowed user should not be handled.": {
+			update: command(666, "/alerts"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				mcli.On("Send", reply("⛔ You are not allowed to use this bot")).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"A message without a command should be ignored.": {
+			update: tgbotapi.Update{Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 1234}, Text: "hello"}},
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+			},
+		},
+
+		"An unknown command should be ignored.": {
+			update: command(1, "/other"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+			},
+		},
+
+		"An unknown command from a not allowed user should be ignored.": {
+			update: command(666, "/other"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+			},
+		},
+
+		"A command for other bot should be ignored.": {
+			update: command(1, "/alerts@otherbot"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+			},
+		},
+
+		"A command for the bot should be handled.": {
+			update: command(1, "/help@AlertgramBot"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				mcli.On("Send", mock.Anything).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"The alerts command should list the firing alerts.": {
+			update: command(1, "/alerts"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				alerts := []model.Alert{
+					{Name: "HighLatency", Status: model.AlertStatusFiring, Labels: map[string]string{"alertname": "HighLatency", "env": "prod", "service": "api"}},
+					{Name: "Silenced", Status: model.AlertStatusUnknown, Labels: map[string]string{"alertname": "Silenced"}},
+					{Name: "PodRestarting", Status: model.AlertStatusFiring, Labels: map[string]string{"alertname": "PodRestarting"}},
+				}
+				mam.On("GetAlerts", mock.Anything).Once().Return(alerts, nil)
+				exp := "🔥 <b>2 firing alerts</b>\n\n• <b>HighLatency</b> <code>env=&#34;prod&#34;, service=&#34;api&#34;</code>\n• <b>PodRestarting</b>"
+				mcli.On("Send", reply(exp)).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"The alerts command without firing alerts should notify it.": {
+			update: command(1, "/alerts"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				mam.On("GetAlerts", mock.Anything).Once().Return([]model.Alert{}, nil)
+				mcli.On("Send", reply("✅ There are no firing alerts")).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"The silence command should create a silence with the matchers.": {
+			update: command(1, `/silence alertname="HighLatency" env=~"prod|staging" service!=api 2h`),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				expSilence := mock.MatchedBy(func(s alertmanager.Silence) bool {
+					expMatchers := []alertmanager.Matcher{
+						{Name: "alertname", Value: "HighLatency", IsEqual: true},
+						{Name: "env", Value: "prod|staging", IsRegex: true, IsEqual: true},
+						{Name: "service", Value: "api"},
+					}
+					return assert.Equal(t, expMatchers, s.Matchers) &&
+						assert.Equal(t, 2*time.Hour, s.EndsAt.Sub(s.StartsAt)) &&
+						assert.Equal(t, "@alice", s.CreatedBy)
+				})
+				mam.On("CreateSilence", mock.Anything, expSilence).Once().Return("silence1", nil)
+				mcli.On("Send", reply("🔕 Silence <code>silence1</code> created for 2h")).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"The silence command should create a silence with quoted matcher values with spaces.": {
+			update: command(1, `/silence alertname="High Latency" summary=~"disk \"/data\" .*" 1h`),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				expSilence := mock.MatchedBy(func(s alertmanager.Silence) bool {
+					expMatchers := []alertmanager.Matcher{
+						{Name: "alertname", Value: "High Latency", IsEqual: true},
+						{Name: "summary", Value: `disk "/data" .*`, IsRegex: true, IsEqual: true},
+					}
+					return assert.Equal(t, expMatchers, s.Matchers) &&
+						assert.Equal(t, time.Hour, s.EndsAt.Sub(s.StartsAt))
+				})
+				mam.On("CreateSilence", mock.Anything, expSilence).Once().Return("silence1", nil)
+				mcli.On("Send", reply("🔕 Silence <code>silence1</code> created for 1h")).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"The silence command with an unterminated quoted value should fail.": {
+			update: command(1, `/silence alertname="High Latency 1h`),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				mcli.On("Send", reply("❌ Usage: /silence &lt;matchers&gt; &lt;duration&gt;, unterminated quoted value")).Once().Return(tgbotapi.Message{}, nil)
+			},
+			expErr: true,
+		},
+
+		"The silence command without duration should fail.": {
+			update: command(1, `/silence alertname="HighLatency"`),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				mcli.On("Send", reply("❌ Usage: /silence &lt;matchers&gt; &lt;duration&gt;, at least one matcher and the duration are required")).Once().Return(tgbotapi.Message{}, nil)
+			},
+			expErr: true,
+		},
+
+		"The silence command with an error from Alertmanager should fail.": {
+			update: command(1, `/silence alertname="HighLatency" 1h`),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				mam.On("CreateSilence", mock.Anything, mock.Anything).Once().Return("", errors.New("whatever"))
+				mcli.On("Send", reply("❌ Could not create the silence")).Once().Return(tgbotapi.Message{}, nil)
+			},
+			expErr: true,
+		},
+
+		"The dms command should show the dead man's switch status.": {
+			update: command(1, "/dms"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				status := deadmansswitch.Status{Enabled: true, Interval: 15 * time.Minute, LastPush: time.Now().Add(-2 * time.Minute), Active: false}
+				mdms.On("Status", mock.Anything).Once().Return(status, nil)
+				mcli.On("Send", reply("✅ The dead man's switch is <b>inactive</b>, last alert received 2m ago (interval 15m)")).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"The mute command should mute the chat.": {
+			update: command(1, "/mute 30m"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				mcli.On("Send", mock.MatchedBy(func(r tgbotapi.MessageConfig) bool {
+					return assert.Contains(t, r.Text, "🔇 Alerts muted on this chat for 30m")
+				})).Once().Return(tgbotapi.Message{}, nil)
+			},
+			expMuted: true,
+		},
+
+		"The mute command with an invalid duration should fail.": {
+			update: command(1, "/mute forever"),
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mam *alertmanagermock.Client, mdms *deadmansswitchmock.Service) {
+				mcli.On("Send", reply("❌ Usage: /mute &lt;duration&gt;, e.g. /mute 30m")).Once().Return(tgbotapi.Message{}, nil)
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mcli := &telegrammock.Client{}
+			mam := &alertmanagermock.Client{}
+			mdms := &deadmansswitchmock.Service{}
+			test.mocks(t, mcli, mam, mdms)
+
+			// Execute.
+			muter := telegram.NewMemoryChatMuter()
+			h, err := telegram.NewCommandsHandler(telegram.CommandsHandlerConfig{
+				Client:                mcli,
+				AlertmanagerClient:    mam,
+				DeadMansSwitchService: mdms,
+				ChatMuter:             muter,
+				AllowedUserIDs:        []int{1},
+				BotUserName:           "alertgrambot",
+			})
+			require.NoError(err)
+			err = h.HandleUpdate(context.TODO(), test.update)
+
+			// Check.
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			until, err := muter.MutedUntil(context.TODO(), 1234)
+			require.NoError(err)
+			assert.Equal(test.expMuted, !until.IsZero())
+			mcli.AssertExpectations(t)
+			mam.AssertExpectations(t)
+			mdms.AssertExpectations(t)
+		})
+	}
+}
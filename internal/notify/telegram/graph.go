@@ -0,0 +1,147 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+)
+
+// maxCaptionLength is the maximum number of characters that Telegram
+// accepts on a photo caption.
+const maxCaptionLength = 1024
+
+// GraphRenderer knows how to render the PNG graph image of an alert.
+type GraphRenderer interface {
+	// RenderGraph renders the graph of the alert, if the alert doesn't
+	// have a graph it will return nil.
+	RenderGraph(ctx context.Context, alert model.Alert) ([]byte, error)
+}
+
+// GraphMode is how the alert graphs are sent.
+type GraphMode string
+
+const (
+	// GraphModeCaption sends the graph with the alerts message as the caption, if
+	// the message doesn't fit in a caption, the graph will be sent as a reply.
+	GraphModeCaption GraphMode = "caption"
+	// GraphModeReply sends the graph as a reply to the alerts message.
+	GraphModeReply GraphMode = "reply"
+)
+
+// renderGraphs renders the graphs of the firing alerts, the alerts with the same
+// generator URL share the graph. The graphs are optional so the errors are logged.
+func (n notifier) renderGraphs(ctx context.Context, ag model.AlertGroup) [][]byte {
+	if n.cfg.GraphRenderer == nil {
+		return nil
+	}
+
+	graphs := [][]byte{}
+	rendered := map[string]bool{}
+	for _, a := range ag.Alerts {
+		if len(graphs) >= n.cfg.MaxGraphs {
+			break
+		}
+		if !a.IsFiring() || a.GeneratorURL == "" || rendered[a.GeneratorURL] {
+			continue
+		}
+		rendered[a.GeneratorURL] = true
+
+		graph, err := n.cfg.GraphRenderer.RenderGraph(ctx, a)
+		if err != nil {
+			n.logger.WithValues(log.KV{"alertGroup": ag.ID, "alert": a.ID}).Warningf("could not render the alert graph: %s", err)
+			continue
+		}
+		if graph != nil {
+			graphs = append(graphs, graph)
+		}
+	}
+
+	return graphs
+}
+
+// topicPhoto is a photo that will be sent to a forum topic of the chat, if
+// the topic is not set, it will be sent to the chat.
+type topicPhoto struct {
+	tgbotapi.PhotoConfig
+	topicID int
+}
+
+// newGraphPhoto returns the graph photo with the message as caption.
+func newGraphPhoto(msg topicMessage, graph []byte) topicPhoto {
+	p := tgbotapi.NewPhotoUpload(msg.ChatID, tgbotapi.FileBytes{Name: "graph.png", Bytes: graph})
+	p.Caption = msg.Text
+	p.ParseMode = msg.ParseMode
+	p.ReplyToMessageID = msg.ReplyToMessageID
+	p.ReplyMarkup = msg.ReplyMarkup
+	p.DisableNotification = msg.DisableNotification
+	return topicPhoto{PhotoConfig: p, topicID: msg.topicID}
+}
+
+// sendTopicPhoto sends the photo to the topic, the telegram-bot-api library doesn't
+// support the topics so we need to make the upload request ourselves. The photos
+// without topic are uploaded the same way, so the Telegram errors are the same.
+func sendTopicPhoto(ctx context.Context, client Client, p topicPhoto) (tgbotapi.Message, error) {
+	params := map[string]string{
+		"chat_id":              strconv.FormatInt(p.ChatID, 10),
+		"disable_notification": strconv.FormatBool(p.DisableNotification),
+	}
+	if p.topicID != 0 {
+		params["message_thread_id"] = strconv.Itoa(p.topicID)
+	}
+	if p.Caption != "" {
+		params["caption"] = p.Caption
+		if p.ParseMode != "" {
+			params["parse_mode"] = p.ParseMode
+		}
+	}
+	if p.ReplyToMessageID != 0 {
+		params["reply_to_message_id"] = strconv.Itoa(p.ReplyToMessageID)
+	}
+	if p.ReplyMarkup != nil {
+		data, err := json.Marshal(p.ReplyMarkup)
+		if err != nil {
+			return tgbotapi.Message{}, err
+		}
+		params["reply_markup"] = string(data)
+	}
+
+	resp, err := client.UploadFile(ctx, "sendPhoto", params, "photo", p.File)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	var res tgbotapi.Message
+	err = json.Unmarshal(resp.Result, &res)
+	if err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("could not decode telegram message: %w", err)
+	}
+
+	return res, nil
+}
+
+// newEditMessage returns the edit of the message text, if the message is a
+// photo, the caption will be edited.
+func newEditMessage(chatID int64, msgID int, text string, parseMode string, caption bool, kb *tgbotapi.InlineKeyboardMarkup) tgbotapi.Chattable {
+	if caption {
+		edit := tgbotapi.NewEditMessageCaption(chatID, msgID, text)
+		edit.ParseMode = parseMode
+		if kb != nil {
+			edit.ReplyMarkup = kb
+		}
+		return edit
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, msgID, text)
+	edit.ParseMode = parseMode
+	edit.DisableWebPagePreview = true
+	if kb != nil {
+		edit.ReplyMarkup = kb
+	}
+	return edit
+}
@@ -0,0 +1,170 @@
+package telegram
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/slok/alertgram/internal/model"
+)
+
+// Telegram allows a maximum of 100 buttons, we limit the alerts with buttons
+// so the keyboard doesn't get out of hand.
+const maxKeyboardAlerts = 20
+
+// Callback actions, these are sent on the buttons data and Telegram limits
+// the data to 64 bytes, so we use the alert fingerprint to identify the alert.
+const (
+	callbackActionSilence = "silence"
+	callbackActionAck     = "ack"
+	callbackActionNoop    = "noop"
+)
+
+const (
+	// maxCallbackDataLength is the maximum number of bytes that Telegram accepts on the buttons data.
+	maxCallbackDataLength = 64
+	// maxCallbackAlertIDLength is the maximum length of the alert IDs on the buttons data, the
+	// longer IDs (e.g not Alertmanager fingerprints) are hashed to fit in the data.
+	maxCallbackAlertIDLength = 32
+	// callbackAlertIDHashPrefix is the prefix of the hashed alert IDs.
+	callbackAlertIDHashPrefix = "~"
+)
+
+// callbackAlertID returns the ID of the alert used on the buttons data.
+func callbackAlertID(id string) string {
+	if len(id) <= maxCallbackAlertIDLength {
+		return id
+	}
+
+	sum := sha256.Sum256([]byte(id))
+	return callbackAlertIDHashPrefix + hex.EncodeToString(sum[:])[:maxCallbackAlertIDLength-len(callbackAlertIDHashPrefix)]
+}
+
+// alertsKeyboard returns the inline keyboard of the alerts, a row of buttons for each
+// firing alert. If there are no buttons it will return nil.
+func alertsKeyboard(ag model.AlertGroup, silenceDurations []time.Duration) *tgbotapi.InlineKeyboardMarkup {
+	alerts := ag.FiringAlerts()
+	if len(alerts) > maxKeyboardAlerts {
+		alerts = alerts[:maxKeyboardAlerts]
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	for _, a := range alerts {
+		// Identify the alert of the buttons if there is more than one.
+		suffix := ""
+		if len(alerts) > 1 {
+			suffix = fmt.Sprintf(" #%d", len(rows)+1)
+		}
+
+		row := []tgbotapi.InlineKeyboardButton{}
+		if a.ID != "" {
+			id := callbackAlertID(a.ID)
+			for _, d := range silenceDurations {
+				data := fmt.Sprintf("%s:%s:%s", callbackActionSilence, formatDuration(d), id)
+				if len(data) > maxCallbackDataLength {
+					continue
+				}
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData("🔕 "+formatDuration(d)+suffix, data))
+			}
+			data := fmt.Sprintf("%s:%s", callbackActionAck, id)
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("👍 Ack"+suffix, data))
+		}
+		if a.GeneratorURL != "" {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonURL("📈 Prometheus"+suffix, a.GeneratorURL))
+		}
+
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	kb := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &kb
+}
+
+// callbackData is the data of a pressed button.
+type callbackData struct {
+	action   string
+	duration time.Duration
+	// fingerprint is the alert ID on the buttons data, see callbackAlertID.
+	fingerprint string
+}
+
+func parseCallbackData(data string) (*callbackData, error) {
+	action := strings.SplitN(data, ":", 2)[0]
+	switch action {
+	case callbackActionNoop:
+		return &callbackData{action: callbackActionNoop}, nil
+	case callbackActionAck:
+		parts := strings.SplitN(data, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			break
+		}
+		return &callbackData{action: callbackActionAck, fingerprint: parts[1]}, nil
+	case callbackActionSilence:
+		parts := strings.SplitN(data, ":", 3)
+		if len(parts) != 3 || parts[2] == "" {
+			break
+		}
+		d, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid silence duration: %w", err)
+		}
+		return &callbackData{action: callbackActionSilence, duration: d, fingerprint: parts[2]}, nil
+	}
+
+	return nil, fmt.Errorf("invalid callback data %q", data)
+}
+
+// replaceAlertButtons replaces the buttons of the alert action with a single button
+// without action and returns the number of the alert on the keyboard (0 if
+// the keyboard only has one alert).
+func replaceAlertButtons(kb tgbotapi.InlineKeyboardMarkup, action, fingerprint, text string) (tgbotapi.InlineKeyboardMarkup, int) {
+	alertNumber := 0
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(kb.InlineKeyboard))
+	for i, row := range kb.InlineKeyboard {
+		newRow := []tgbotapi.InlineKeyboardButton{}
+		replaced := false
+		for _, b := range row {
+			if b.CallbackData == nil || !isAlertActionButton(*b.CallbackData, action, fingerprint) {
+				newRow = append(newRow, b)
+				continue
+			}
+
+			if !replaced {
+				newRow = append(newRow, tgbotapi.NewInlineKeyboardButtonData(text, callbackActionNoop))
+				replaced = true
+				if len(kb.InlineKeyboard) > 1 {
+					alertNumber = i + 1
+				}
+			}
+		}
+		rows = append(rows, newRow)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...), alertNumber
+}
+
+func isAlertActionButton(data, action, fingerprint string) bool {
+	return strings.HasPrefix(data, action+":") && strings.HasSuffix(data, ":"+fingerprint)
+}
+
+// formatDuration formats the durations in a short way (e.g 1h, 30m).
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
@@ -0,0 +1,32 @@
+package telegram
+
+import (
+	"context"
+	"time"
+)
+
+// MetricsRecorder knows how to record metrics on the Telegram notifier.
+type MetricsRecorder interface {
+	IncTelegramThrottledSends(ctx context.Context)
+	ObserveTelegramSendDelay(ctx context.Context, reason string, t time.Duration)
+	IncTelegramCallbacks(ctx context.Context, action string, success bool)
+	IncTelegramCommands(ctx context.Context, command string, success bool)
+	IncTelegramChatMigrations(ctx context.Context)
+}
+
+// Send delay reasons.
+const (
+	delayReasonRateLimit  = "rate_limit"
+	delayReasonRetryAfter = "retry_after"
+)
+
+type dummyMetricsRecorder int
+
+// dummyRecorder is a MetricsRecorder that doesn't record anything.
+const dummyRecorder = dummyMetricsRecorder(0)
+
+func (dummyMetricsRecorder) IncTelegramThrottledSends(context.Context)                       {}
+func (dummyMetricsRecorder) ObserveTelegramSendDelay(context.Context, string, time.Duration) {}
+func (dummyMetricsRecorder) IncTelegramCallbacks(context.Context, string, bool)              {}
+func (dummyMetricsRecorder) IncTelegramCommands(context.Context, string, bool)               {}
+func (dummyMetricsRecorder) IncTelegramChatMigrations(context.Context)                       {}
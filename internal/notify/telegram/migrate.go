@@ -0,0 +1,146 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/slok/alertgram/internal/log"
+)
+
+// ChatMigrations knows how to store the chats that have been migrated to a new
+// chat (e.g a group upgraded to a supergroup), so the alerts are sent to the new chat.
+type ChatMigrations interface {
+	// MigratedTo returns the chat where the chat has been migrated, if the chat
+	// has not been migrated it will return 0.
+	MigratedTo(ctx context.Context, chatID int64) (int64, error)
+	// SetMigration stores the migration of the chat to the new chat.
+	SetMigration(ctx context.Context, from, to int64) error
+}
+
+type memoryChatMigrations struct {
+	mu         sync.Mutex
+	migrations map[int64]int64
+}
+
+// NewMemoryChatMigrations returns a new ChatMigrations that stores the migrations in memory.
+func NewMemoryChatMigrations() ChatMigrations {
+	return &memoryChatMigrations{migrations: map[int64]int64{}}
+}
+
+func (m *memoryChatMigrations) MigratedTo(_ context.Context, chatID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.migrations[chatID], nil
+}
+
+func (m *memoryChatMigrations) SetMigration(_ context.Context, from, to int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.migrations[from] = to
+
+	return nil
+}
+
+type fileChatMigrations struct {
+	memoryChatMigrations
+	path string
+}
+
+// NewFileChatMigrations returns a new ChatMigrations that stores the migrations in memory
+// and persists them on a JSON file, so the migrations are known after a restart.
+func NewFileChatMigrations(path string) (ChatMigrations, error) {
+	m := &fileChatMigrations{
+		memoryChatMigrations: memoryChatMigrations{migrations: map[int64]int64{}},
+		path:                 path,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("could not create chat migrations directory: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("could not read chat migrations file: %w", err)
+	default:
+		if err := json.Unmarshal(data, &m.migrations); err != nil {
+			return nil, fmt.Errorf("could not decode chat migrations file: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+func (f *fileChatMigrations) SetMigration(_ context.Context, from, to int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.migrations[from] = to
+
+	data, err := json.Marshal(f.migrations)
+	if err != nil {
+		return fmt.Errorf("could not encode chat migrations: %w", err)
+	}
+
+	tmp := f.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write chat migrations file: %w", err)
+	}
+
+	return os.Rename(tmp, f.path)
+}
+
+// maxChatMigrations is the maximum number of migrations followed to get the
+// current chat, so a migrations loop doesn't block the notifier.
+const maxChatMigrations = 5
+
+// currentChatID returns the chat where the alerts of the chat need to be sent
+// following its migrations.
+func (n notifier) currentChatID(ctx context.Context, chatID int64) (int64, error) {
+	for i := 0; i < maxChatMigrations; i++ {
+		to, err := n.cfg.ChatMigrations.MigratedTo(ctx, chatID)
+		if err != nil {
+			return 0, err
+		}
+		if to == 0 {
+			break
+		}
+		chatID = to
+	}
+
+	return chatID, nil
+}
+
+// migrateChat stores the migration of the chat, the migration is logged so the
+// configuration can be fixed with the new chat ID.
+func (n notifier) migrateChat(ctx context.Context, from, to int64) {
+	n.metrics.IncTelegramChatMigrations(ctx)
+	logger := n.logger.WithValues(log.KV{"telegramChatID": from, "newTelegramChatID": to})
+	logger.Warningf("telegram chat has been migrated, update the configuration with the new chat ID")
+
+	err := n.cfg.ChatMigrations.SetMigration(ctx, from, to)
+	if err != nil {
+		logger.Errorf("could not store the telegram chat migration: %s", err)
+	}
+}
+
+// migratedChatID returns the chat where the chat has been migrated if the error
+// is a Telegram chat migration error, otherwise it will return 0.
+func migratedChatID(err error) int64 {
+	var tgErr tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return 0
+	}
+
+	return tgErr.MigrateToChatID
+}
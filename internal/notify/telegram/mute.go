@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ChatMuter knows how to mute the alerts of the chats for a period of time.
+type ChatMuter interface {
+	// Mute mutes the chat until the time, a zero time unmutes the chat.
+	Mute(ctx context.Context, chatID int64, until time.Time) error
+	// MutedUntil returns the time until the chat is muted, if the chat is not
+	// muted it will return a zero time.
+	MutedUntil(ctx context.Context, chatID int64) (time.Time, error)
+}
+
+type memoryChatMuter struct {
+	mu    sync.Mutex
+	mutes map[int64]time.Time
+}
+
+// NewMemoryChatMuter returns a new ChatMuter that stores the muted chats in memory.
+func NewMemoryChatMuter() ChatMuter {
+	return &memoryChatMuter{mutes: map[int64]time.Time{}}
+}
+
+func (m *memoryChatMuter) Mute(_ context.Context, chatID int64, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if until.IsZero() {
+		delete(m.mutes, chatID)
+		return nil
+	}
+	m.mutes[chatID] = until
+
+	return nil
+}
+
+func (m *memoryChatMuter) MutedUntil(_ context.Context, chatID int64) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	until, ok := m.mutes[chatID]
+	if !ok || time.Now().After(until) {
+		delete(m.mutes, chatID)
+		return time.Time{}, nil
+	}
+
+	return until, nil
+}
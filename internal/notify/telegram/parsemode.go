@@ -0,0 +1,216 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slok/alertgram/internal/notify"
+)
+
+// ParseMode is the markup of the Telegram messages.
+type ParseMode string
+
+const (
+	// ParseModeHTML formats the messages using HTML tags.
+	ParseModeHTML ParseMode = "HTML"
+	// ParseModeMarkdownV2 formats the messages using Telegram MarkdownV2.
+	ParseModeMarkdownV2 ParseMode = "MarkdownV2"
+	// ParseModeText doesn't format the messages.
+	ParseModeText ParseMode = "Text"
+)
+
+// telegramParseMode returns the parse mode of the Telegram API.
+func (p ParseMode) telegramParseMode() string {
+	switch p {
+	case ParseModeText:
+		return ""
+	case "":
+		return string(ParseModeHTML)
+	default:
+		return string(p)
+	}
+}
+
+// italic formats the text in italic escaping it.
+func (p ParseMode) italic(text string) string {
+	switch p {
+	case ParseModeText:
+		return text
+	case ParseModeMarkdownV2:
+		return "_" + notify.EscapeMarkdownV2(text) + "_"
+	default:
+		return "<i>" + html.EscapeString(text) + "</i>"
+	}
+}
+
+// validateMessage checks that the message markup is valid for the parse mode,
+// so we know that the template is wrong before Telegram rejects the message.
+func validateMessage(text string, mode ParseMode) error {
+	switch mode {
+	case ParseModeMarkdownV2:
+		_, err := tokenizeMarkdownV2(text)
+		return err
+	case ParseModeText:
+		return nil
+	default:
+		return validateHTML(text)
+	}
+}
+
+// htmlTags are the HTML tags supported by Telegram.
+var htmlTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true, "s": true, "strike": true, "del": true,
+	"span": true, "tg-spoiler": true, "tg-emoji": true, "a": true, "code": true, "pre": true, "blockquote": true,
+}
+
+func validateHTML(text string) error {
+	var stack []markupTag
+	for _, t := range tokenizeHTML(text) {
+		switch {
+		case t.tag != nil:
+			if !htmlTags[t.tag.name] {
+				return fmt.Errorf("unsupported HTML tag %q", t.text)
+			}
+			if !t.tag.closing {
+				stack = append(stack, *t.tag)
+				continue
+			}
+			if len(stack) == 0 || stack[len(stack)-1].name != t.tag.name {
+				return fmt.Errorf("unexpected HTML closing tag %q", t.text)
+			}
+			stack = stack[:len(stack)-1]
+		case t.text == "<":
+			return fmt.Errorf("unescaped '<' character")
+		case t.text == "&":
+			return fmt.Errorf("unescaped '&' character")
+		}
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed HTML tag %q", stack[len(stack)-1].raw)
+	}
+
+	return nil
+}
+
+// markdownV2Reserved are the characters that need to be escaped on MarkdownV2
+// when they are not part of the markup.
+const markdownV2Reserved = "_*[]()~`>#+-=|{}.!"
+
+// markdownV2Delimiters are the delimiters of the MarkdownV2 formatting entities,
+// the longest first.
+var markdownV2Delimiters = []string{"```", "||", "__", "`", "*", "_", "~"}
+
+// tokenizeMarkdownV2 splits a MarkdownV2 text in tokens that can't be split, if the
+// text is not valid MarkdownV2 it will return an error.
+func tokenizeMarkdownV2(text string) ([]msgToken, error) {
+	tokens := []msgToken{}
+	var stack []markupTag
+	var rErr error
+	setErr := func(err error) {
+		if rErr == nil {
+			rErr = err
+		}
+	}
+
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		t := text[i : i+size]
+		var tag *markupTag
+
+		// Inside code only the code delimiter is markup.
+		code := ""
+		if n := len(stack); n > 0 && (stack[n-1].name == "`" || stack[n-1].name == "```") {
+			code = stack[n-1].name
+		}
+
+		switch {
+		case text[i] == '\\':
+			if i+1 >= len(text) {
+				setErr(fmt.Errorf("unescaped '\\' character at the end"))
+				break
+			}
+			_, size := utf8.DecodeRuneInString(text[i+1:])
+			t = text[i : i+1+size]
+		case code != "":
+			if strings.HasPrefix(text[i:], code) {
+				t = code
+				tag = &markupTag{name: code, raw: code, end: code, closing: true}
+			}
+		case text[i] == '[':
+			end := markdownV2LinkEnd(text[i:])
+			if end < 0 {
+				setErr(fmt.Errorf("invalid link or unescaped '[' character"))
+				break
+			}
+			t = text[i : i+end]
+		case text[i] == '>' && (i == 0 || text[i-1] == '\n'):
+			// Block quotation.
+		default:
+			for _, d := range markdownV2Delimiters {
+				if !strings.HasPrefix(text[i:], d) {
+					continue
+				}
+				t = d
+				tag = &markupTag{name: d, raw: d, end: d}
+				if d == "```" {
+					// The pre block can have the language on the first line.
+					if nl := strings.IndexByte(text[i:], '\n'); nl > 0 {
+						tag.raw = text[i : i+nl+1]
+					}
+					t = tag.raw
+				}
+				for _, open := range stack {
+					if open.name == d {
+						tag.closing = true
+					}
+				}
+				break
+			}
+			if tag == nil && strings.ContainsAny(t, markdownV2Reserved) {
+				setErr(fmt.Errorf("unescaped %q character", t))
+			}
+		}
+
+		if tag != nil {
+			stack = applyTag(stack, tag)
+		}
+		tokens = appendToken(tokens, t, tag)
+		i += len(t)
+	}
+
+	if len(stack) > 0 {
+		setErr(fmt.Errorf("unclosed %q MarkdownV2 entity", stack[len(stack)-1].name))
+	}
+
+	return tokens, rErr
+}
+
+// markdownV2LinkEnd returns the end of the `[text](url)` link that the text starts
+// with, or -1 if the text doesn't start with a link.
+func markdownV2LinkEnd(text string) int {
+	closing := func(text string, c byte) int {
+		for i := 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case c:
+				return i
+			}
+		}
+		return -1
+	}
+
+	textEnd := closing(text, ']')
+	if textEnd < 0 || textEnd+1 >= len(text) || text[textEnd+1] != '(' {
+		return -1
+	}
+	urlEnd := closing(text[textEnd+1:], ')')
+	if urlEnd < 0 {
+		return -1
+	}
+
+	return textEnd + 1 + urlEnd + 1
+}
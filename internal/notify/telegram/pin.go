@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/model"
+)
+
+// updatePin pins the message of the firing alerts that match the pin matchers, replacing
+// the previous pinned message of the alert group, and unpins it when the alerts are resolved.
+func (n notifier) updatePin(ctx context.Context, chatID int64, ag model.AlertGroup, sent SentMessage) error {
+	key := pinKey(chatID, ag)
+	pinned, err := n.cfg.MessageStore.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("could not get the pinned message: %w", err)
+	}
+
+	pin := !isResolved(ag) && len(sent.MessageIDs) > 0 && anyAlertMatches(ag.FiringAlerts(), n.cfg.PinMatchers)
+	if !isResolved(ag) && !pin {
+		return nil
+	}
+
+	if pinned != nil && len(pinned.MessageIDs) > 0 {
+		err := n.pinRequest(ctx, "unpinChatMessage", chatID, pinned.MessageIDs[0])
+		// The pinned message could have been unpinned or deleted by someone.
+		if err != nil && !isTelegramError(err, "not found") && !isTelegramError(err, "not pinned") {
+			return fmt.Errorf("could not unpin the message: %w", err)
+		}
+		err = n.cfg.MessageStore.Delete(ctx, []string{key})
+		if err != nil {
+			return fmt.Errorf("could not delete the pinned message: %w", err)
+		}
+	}
+
+	if !pin {
+		return nil
+	}
+
+	err = n.pinRequest(ctx, "pinChatMessage", chatID, sent.MessageIDs[0])
+	if err != nil {
+		return fmt.Errorf("could not pin the message: %w", err)
+	}
+	err = n.cfg.MessageStore.Set(ctx, []string{key}, sent)
+	if err != nil {
+		return fmt.Errorf("could not store the pinned message: %w", err)
+	}
+
+	return nil
+}
+
+// pinRequest pins or unpins a message, the telegram-bot-api library can't unpin
+// a specific message so we need to make the requests ourselves.
+func (n notifier) pinRequest(ctx context.Context, endpoint string, chatID int64, messageID int) error {
+	v := url.Values{}
+	v.Add("chat_id", strconv.FormatInt(chatID, 10))
+	v.Add("message_id", strconv.Itoa(messageID))
+	if endpoint == "pinChatMessage" {
+		// The pinned message has already notified.
+		v.Add("disable_notification", "true")
+	}
+
+	_, err := n.client.MakeRequest(endpoint, v)
+	return err
+}
+
+// pinKey returns the key used to store the pinned message of an alert group.
+func pinKey(chatID int64, ag model.AlertGroup) string {
+	return fmt.Sprintf("%d/pin/%s", chatID, ag.ID)
+}
+
+// allAlertsMatch returns true if all the alerts match any of the matchers.
+func allAlertsMatch(alerts []model.Alert, matchers []*forward.Matcher) bool {
+	if len(alerts) == 0 || len(matchers) == 0 {
+		return false
+	}
+
+	for _, a := range alerts {
+		if !anyAlertMatches([]model.Alert{a}, matchers) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// anyAlertMatches returns true if any of the alerts match any of the matchers.
+func anyAlertMatches(alerts []model.Alert, matchers []*forward.Matcher) bool {
+	for _, a := range alerts {
+		for _, m := range matchers {
+			if m.Matches(a.Labels) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a token bucket rate limiter that reserves the tokens
+// in advance, so the callers only need to wait the returned duration.
+type tokenBucket struct {
+	ratePerSecond float64
+	burst         float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(ratePerSecond, burst float64) *tokenBucket {
+	return &tokenBucket{
+		ratePerSecond: ratePerSecond,
+		burst:         burst,
+		tokens:        burst,
+	}
+}
+
+// reserve takes a token from the bucket and returns the time the caller
+// needs to wait until the token is available.
+func (t *tokenBucket) reserve(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.last.IsZero() {
+		elapsed := now.Sub(t.last).Seconds()
+		t.tokens = math.Min(t.burst, t.tokens+elapsed*t.ratePerSecond)
+	}
+	t.last = now
+	t.tokens--
+
+	if t.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-t.tokens / t.ratePerSecond * float64(time.Second))
+}
+
+// release returns a reserved token that will not be used to the bucket.
+func (t *tokenBucket) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tokens = math.Min(t.burst, t.tokens+1)
+}
+
+// chatRateLimiter limits the messages sent to each Telegram chat.
+// Telegram allows ~1 message per second on the same chat and
+// 20 messages per minute on the same group.
+//
+// More info here: https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this.
+type chatRateLimiter struct {
+	chatRate  float64
+	groupRate float64
+
+	mu     sync.Mutex
+	chats  map[int64]*tokenBucket
+	groups map[int64]*tokenBucket
+}
+
+func newChatRateLimiter(chatMsgsPerSecond, groupMsgsPerMinute float64) *chatRateLimiter {
+	return &chatRateLimiter{
+		chatRate:  chatMsgsPerSecond,
+		groupRate: groupMsgsPerMinute,
+		chats:     map[int64]*tokenBucket{},
+		groups:    map[int64]*tokenBucket{},
+	}
+}
+
+// Wait waits until a message can be sent to the chat and returns the
+// time that has been waiting.
+func (c *chatRateLimiter) Wait(ctx context.Context, chatID int64) (time.Duration, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	chat, ok := c.chats[chatID]
+	if !ok {
+		chat = newTokenBucket(c.chatRate, 1)
+		c.chats[chatID] = chat
+	}
+
+	// Groups, supergroups and channels have negative IDs.
+	var group *tokenBucket
+	if chatID < 0 {
+		group, ok = c.groups[chatID]
+		if !ok {
+			group = newTokenBucket(c.groupRate/60, c.groupRate)
+			c.groups[chatID] = group
+		}
+	}
+	c.mu.Unlock()
+
+	wait := chat.reserve(now)
+	if group != nil {
+		if groupWait := group.reserve(now); groupWait > wait {
+			wait = groupWait
+		}
+	}
+
+	if err := sleep(ctx, wait); err != nil {
+		// The message will not be sent, release the reservations so
+		// they don't delay the next messages.
+		chat.release()
+		if group != nil {
+			group.release()
+		}
+		return 0, err
+	}
+
+	return wait, nil
+}
+
+// sleep sleeps the duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
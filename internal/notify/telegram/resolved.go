@@ -0,0 +1,211 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+)
+
+// ResolvedMode is how the resolved alerts are notified.
+type ResolvedMode string
+
+const (
+	// ResolvedModeNew sends the resolved alerts in a new message.
+	ResolvedModeNew ResolvedMode = "new"
+	// ResolvedModeEdit edits the firing alerts message in place with the resolved alerts
+	// when all the alerts of the group have been resolved.
+	ResolvedModeEdit ResolvedMode = "edit"
+	// ResolvedModeReply sends the resolved alerts in a new message as a reply to
+	// the firing alerts message.
+	ResolvedModeReply ResolvedMode = "reply"
+)
+
+// editMessages edits the stored message of the alerts with the new messages. If there
+// is no stored message, it will return false.
+func (n notifier) editMessages(ctx context.Context, chatID int64, ag model.AlertGroup, msgs []topicMessage) (bool, error) {
+	keys := append([]string{groupKey(chatID, ag)}, alertKeys(chatID, ag.Alerts)...)
+	sent, err := n.getSentMessage(ctx, keys)
+	if err != nil {
+		return false, err
+	}
+	if sent == nil {
+		return false, nil
+	}
+
+	for i, msg := range msgs {
+		logger := n.logger.WithValues(log.KV{"telegramChatID": sent.ChatID, "part": i + 1, "parts": len(msgs)})
+
+		// If the edited message has more parts than the original, send the new parts.
+		if i >= len(sent.MessageIDs) {
+			if _, err := n.send(ctx, msg.ChatID, msg); err != nil {
+				return false, err
+			}
+			logger.Infof("telegram message sent")
+			continue
+		}
+
+		// The message can't be edited if it doesn't fit in the photo caption.
+		if sent.Caption && (len(msgs) > 1 || utf8.RuneCountInString(msg.Text) > maxCaptionLength) {
+			logger.Warningf("telegram message doesn't fit in the photo caption, sending a new message")
+			return false, nil
+		}
+
+		edit := newEditMessage(sent.ChatID, sent.MessageIDs[i], msg.Text, msg.ParseMode, sent.Caption, nil)
+		_, err := n.send(ctx, sent.ChatID, edit)
+		switch {
+		case err == nil, isTelegramError(err, "message is not modified"):
+		case i == 0 && isTelegramError(err, "message to edit not found"):
+			// The original message has been deleted, send a new one.
+			logger.Warningf("telegram message to edit not found, sending a new message")
+			return false, nil
+		default:
+			return false, err
+		}
+		logger.Infof("telegram message edited")
+	}
+
+	// If the edited message has less parts than the original, remove the extra parts.
+	if len(sent.MessageIDs) > len(msgs) {
+		n.removeMessages(ctx, sent.ChatID, sent.MessageIDs[len(msgs):], msgs[0].ParseMode)
+	}
+
+	err = n.cfg.MessageStore.Delete(ctx, keys)
+	if err != nil {
+		n.logger.Errorf("could not delete the stored telegram message: %s", err)
+	}
+
+	return true, nil
+}
+
+// resolvedPlaceholder is the text of the original message parts that can't be deleted.
+const resolvedPlaceholder = "✅ Resolved"
+
+// removeMessages deletes the messages, the bots can't delete the old messages, in that
+// case the message will be edited with a placeholder. The messages are not required
+// so we don't fail.
+func (n notifier) removeMessages(ctx context.Context, chatID int64, msgIDs []int, parseMode string) {
+	for _, msgID := range msgIDs {
+		logger := n.logger.WithValues(log.KV{"telegramChatID": chatID, "telegramMessageID": msgID})
+
+		v := url.Values{}
+		v.Add("chat_id", strconv.FormatInt(chatID, 10))
+		v.Add("message_id", strconv.Itoa(msgID))
+		_, err := n.client.MakeRequest("deleteMessage", v)
+		if err == nil {
+			logger.Infof("telegram message part deleted")
+			continue
+		}
+		logger.Warningf("could not delete the telegram message part, editing it: %s", err)
+
+		edit := newEditMessage(chatID, msgID, resolvedPlaceholder, parseMode, false, nil)
+		_, err = n.send(ctx, chatID, edit)
+		if err != nil && !isTelegramError(err, "message is not modified") {
+			logger.Errorf("could not edit the telegram message part: %s", err)
+		}
+	}
+}
+
+// setReplyTo sets the firing alerts message of the resolved alerts as the message
+// that the new messages will reply to.
+func (n notifier) setReplyTo(ctx context.Context, chatID int64, ag model.AlertGroup, msgs []topicMessage) error {
+	sent, err := n.getSentMessage(ctx, resolvedKeys(chatID, ag))
+	if err != nil {
+		return err
+	}
+	if sent == nil {
+		return nil
+	}
+
+	msgs[0].ReplyToMessageID = sent.MessageIDs[0]
+
+	return nil
+}
+
+// storeSentMessage remembers the sent message of the firing alerts and forgets the
+// resolved alerts messages.
+func (n notifier) storeSentMessage(ctx context.Context, ag model.AlertGroup, sent SentMessage) error {
+	if keys := resolvedKeys(sent.ChatID, ag); len(keys) > 0 {
+		err := n.cfg.MessageStore.Delete(ctx, keys)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ag.HasFiring() {
+		keys := append([]string{groupKey(sent.ChatID, ag)}, alertKeys(sent.ChatID, ag.FiringAlerts())...)
+		err := n.cfg.MessageStore.Set(ctx, keys, sent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getSentMessage returns the first stored message of the keys, nil if missing.
+func (n notifier) getSentMessage(ctx context.Context, keys []string) (*SentMessage, error) {
+	for _, k := range keys {
+		sent, err := n.cfg.MessageStore.Get(ctx, k)
+		if err != nil {
+			return nil, fmt.Errorf("could not get the stored message: %w", err)
+		}
+		if sent != nil && len(sent.MessageIDs) > 0 {
+			return sent, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// groupKey returns the key used to store the sent message of an alert group.
+func groupKey(chatID int64, ag model.AlertGroup) string {
+	return fmt.Sprintf("%d/group/%s", chatID, ag.ID)
+}
+
+// alertKeys returns the keys used to store the sent message of the alerts, using
+// the alerts fingerprints.
+func alertKeys(chatID int64, alerts []model.Alert) []string {
+	keys := []string{}
+	for _, a := range alerts {
+		if a.ID != "" {
+			keys = append(keys, fmt.Sprintf("%d/alert/%s", chatID, a.ID))
+		}
+	}
+
+	return keys
+}
+
+// messageKey returns the key used to store a single sent message.
+func messageKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d/message/%d", chatID, messageID)
+}
+
+// resolvedKeys returns the keys of the resolved alerts, and the group key
+// if all the alerts have been resolved.
+func resolvedKeys(chatID int64, ag model.AlertGroup) []string {
+	keys := alertKeys(chatID, ag.ResolvedAlerts())
+	if isResolved(ag) {
+		keys = append(keys, groupKey(chatID, ag))
+	}
+
+	return keys
+}
+
+// isResolved returns true if all the alerts of the group have been resolved.
+func isResolved(ag model.AlertGroup) bool {
+	return ag.HasResolved() && !ag.HasFiring()
+}
+
+func isTelegramError(err error, msg string) bool {
+	var tgErr tgbotapi.Error
+	return errors.As(err, &tgErr) && strings.Contains(tgErr.Message, msg)
+}
@@ -0,0 +1,243 @@
+package telegram
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageLength is the maximum number of characters that Telegram
+// accepts on a single text message.
+const maxMessageLength = 4096
+
+// Break priorities, the higher the priority the better place to split
+// a message.
+const (
+	breakNone = iota
+	breakWord
+	breakLine
+	breakAlert
+)
+
+// msgToken is a piece of a message that can't be split, like a markup
+// tag, an HTML entity, an escaped character or a single character.
+type msgToken struct {
+	text string
+	size int
+	// tag will be set when the token is a markup tag.
+	tag *markupTag
+	// breakPrio is the priority to split the message after this token.
+	breakPrio int
+}
+
+// markupTag is a tag that opens or closes a formatting entity, like the
+// HTML tags or the MarkdownV2 formatting delimiters.
+type markupTag struct {
+	name    string
+	raw     string
+	end     string
+	closing bool
+}
+
+// splitMessage splits a message in multiple messages that don't exceed
+// the limit. It will try splitting on alert boundaries (empty lines), if
+// not possible on lines and words and as the last resort in any character.
+// The markup tags that are open when a message is split will be closed
+// at the end of the message and reopened on the next one, so every
+// message is valid by itself.
+func splitMessage(text string, mode ParseMode, limit int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var tokens []msgToken
+	switch mode {
+	case ParseModeMarkdownV2:
+		tokens, _ = tokenizeMarkdownV2(text)
+	case ParseModeText:
+		tokens = tokenizeText(text)
+	default:
+		tokens = tokenizeHTML(text)
+	}
+	msgs := []string{}
+	var open []markupTag
+	for start := 0; start < len(tokens); {
+		end, stack := fitTokens(tokens, start, open, limit)
+		if msg := renderMessage(tokens[start:end], open, stack); msg != "" {
+			msgs = append(msgs, msg)
+		}
+		start = end
+		open = stack
+	}
+
+	return msgs
+}
+
+// fitTokens gets the index where the message starting at start should end
+// to fit in the limit and the tags that are open at that point.
+func fitTokens(tokens []msgToken, start int, open []markupTag, limit int) (end int, stack []markupTag) {
+	size := 0
+	for _, t := range open {
+		size += utf8.RuneCountInString(t.raw)
+	}
+
+	stack = append([]markupTag{}, open...)
+	bestEnd, bestPrio := -1, -1
+	var bestStack []markupTag
+	for i := start; i < len(tokens); i++ {
+		stack = applyTag(stack, tokens[i].tag)
+		size += tokens[i].size
+
+		if size+closingSize(stack) > limit {
+			break
+		}
+
+		// All the remaining tokens fit.
+		if i == len(tokens)-1 {
+			return len(tokens), stack
+		}
+
+		if tokens[i].breakPrio >= bestPrio {
+			bestEnd, bestPrio = i+1, tokens[i].breakPrio
+			bestStack = append([]markupTag{}, stack...)
+		}
+	}
+
+	// If not even one token fits, force one to not get stuck.
+	if bestEnd == -1 {
+		return start + 1, applyTag(append([]markupTag{}, open...), tokens[start].tag)
+	}
+
+	return bestEnd, bestStack
+}
+
+// renderMessage renders the tokens reopening the previous message open tags
+// and closing the ones that remain open at the end.
+func renderMessage(tokens []msgToken, open, stack []markupTag) string {
+	var content strings.Builder
+	for _, t := range tokens {
+		content.WriteString(t.text)
+	}
+	body := strings.Trim(content.String(), "\n ")
+	if body == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, t := range open {
+		b.WriteString(t.raw)
+	}
+	b.WriteString(body)
+	for i := len(stack) - 1; i >= 0; i-- {
+		b.WriteString(stack[i].end)
+	}
+
+	return b.String()
+}
+
+func closingSize(stack []markupTag) int {
+	size := 0
+	for _, t := range stack {
+		size += utf8.RuneCountInString(t.end)
+	}
+	return size
+}
+
+// applyTag returns the open tags stack after the tag.
+func applyTag(stack []markupTag, tag *markupTag) []markupTag {
+	if tag == nil {
+		return stack
+	}
+
+	if !tag.closing {
+		return append(stack, *tag)
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].name == tag.name {
+			return append(stack[:i:i], stack[i+1:]...)
+		}
+	}
+
+	return stack
+}
+
+// tokenizeHTML splits an HTML text in tokens that can't be split.
+func tokenizeHTML(text string) []msgToken {
+	tokens := []msgToken{}
+	for i := 0; i < len(text); {
+		var tag *markupTag
+		var t string
+		switch {
+		case text[i] == '<' && strings.IndexByte(text[i:], '>') > 0:
+			end := i + strings.IndexByte(text[i:], '>') + 1
+			t = text[i:end]
+			tag = parseTag(t)
+		case text[i] == '&' && isEntity(text[i:]):
+			t = text[i : i+strings.IndexByte(text[i:], ';')+1]
+		default:
+			_, size := utf8.DecodeRuneInString(text[i:])
+			t = text[i : i+size]
+		}
+
+		tokens = appendToken(tokens, t, tag)
+		i += len(t)
+	}
+
+	return tokens
+}
+
+// tokenizeText splits a plain text in characters.
+func tokenizeText(text string) []msgToken {
+	tokens := []msgToken{}
+	for _, r := range text {
+		tokens = appendToken(tokens, string(r), nil)
+	}
+
+	return tokens
+}
+
+// appendToken appends a new token setting the priority to split the message after it.
+func appendToken(tokens []msgToken, text string, tag *markupTag) []msgToken {
+	t := msgToken{text: text, tag: tag, size: utf8.RuneCountInString(text)}
+	switch t.text {
+	case "\n":
+		t.breakPrio = breakLine
+		if n := len(tokens); n > 0 && tokens[n-1].text == "\n" {
+			t.breakPrio = breakAlert
+		}
+	case " ":
+		t.breakPrio = breakWord
+	}
+
+	return append(tokens, t)
+}
+
+func parseTag(raw string) *markupTag {
+	tag := &markupTag{raw: raw}
+	name := strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">")
+	if strings.HasPrefix(name, "/") {
+		tag.closing = true
+		name = name[1:]
+	}
+	if i := strings.IndexAny(name, " \t\n"); i >= 0 {
+		name = name[:i]
+	}
+	tag.name = strings.ToLower(name)
+	tag.end = "</" + tag.name + ">"
+
+	return tag
+}
+
+// isEntity checks if the text starts with an HTML entity (e.g `&amp;`, `&#39;`).
+func isEntity(text string) bool {
+	end := strings.IndexByte(text, ';')
+	if end < 2 || end > 10 {
+		return false
+	}
+	for _, r := range text[1:end] {
+		if !(r == '#' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// SentMessage is the reference to an alerts message that has been sent to Telegram,
+// if the message was split, it will have the IDs of all the parts in order.
+type SentMessage struct {
+	ChatID     int64     `json:"chatID"`
+	MessageIDs []int     `json:"messageIDs"`
+	SentAt     time.Time `json:"sentAt"`
+	// Text, ParseMode and Keyboard are the content of the message with the alert
+	// buttons, used to edit the message when the buttons are pressed.
+	Text      string                         `json:"text,omitempty"`
+	ParseMode ParseMode                      `json:"parseMode,omitempty"`
+	Keyboard  *tgbotapi.InlineKeyboardMarkup `json:"keyboard,omitempty"`
+	// Caption is true when the message is the caption of a photo (e.g an alert graph).
+	Caption bool `json:"caption,omitempty"`
+}
+
+// MessageStore knows how to store the sent messages so they can be
+// edited afterwards (e.g when the alerts are resolved).
+type MessageStore interface {
+	// Get returns the sent message of the key, if missing it will return nil.
+	Get(ctx context.Context, key string) (*SentMessage, error)
+	// Set stores the sent message on the keys.
+	Set(ctx context.Context, keys []string, msg SentMessage) error
+	// Delete deletes the keys.
+	Delete(ctx context.Context, keys []string) error
+}
+
+type memoryMessageStore struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	msgs map[string]SentMessage
+}
+
+// NewMemoryMessageStore returns a new MessageStore that stores the messages in memory. The
+// messages sent before the TTL will expire, a TTL of 0 disables the expiration.
+func NewMemoryMessageStore(ttl time.Duration) MessageStore {
+	return &memoryMessageStore{ttl: ttl, msgs: map[string]SentMessage{}}
+}
+
+func (m *memoryMessageStore) Get(_ context.Context, key string) (*SentMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, ok := m.msgs[key]
+	if !ok || m.expired(msg) {
+		return nil, nil
+	}
+
+	return &msg, nil
+}
+
+func (m *memoryMessageStore) Set(_ context.Context, keys []string, msg SentMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(keys, msg)
+
+	return nil
+}
+
+// set stores the message and removes the expired ones, so the store doesn't grow with
+// the messages that will never be resolved.
+func (m *memoryMessageStore) set(keys []string, msg SentMessage) {
+	for k, msg := range m.msgs {
+		if m.expired(msg) {
+			delete(m.msgs, k)
+		}
+	}
+
+	for _, k := range keys {
+		m.msgs[k] = msg
+	}
+}
+
+func (m *memoryMessageStore) expired(msg SentMessage) bool {
+	return m.ttl > 0 && time.Since(msg.SentAt) > m.ttl
+}
+
+func (m *memoryMessageStore) Delete(_ context.Context, keys []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range keys {
+		delete(m.msgs, k)
+	}
+
+	return nil
+}
+
+type fileMessageStore struct {
+	memoryMessageStore
+	path string
+}
+
+// NewFileMessageStore returns a new MessageStore that stores the messages in memory and
+// persists them on a JSON file, so the messages can be used after a restart. The
+// messages sent before the TTL will expire, a TTL of 0 disables the expiration.
+func NewFileMessageStore(path string, ttl time.Duration) (MessageStore, error) {
+	s := &fileMessageStore{
+		memoryMessageStore: memoryMessageStore{ttl: ttl, msgs: map[string]SentMessage{}},
+		path:               path,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("could not create message store directory: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("could not read message store file: %w", err)
+	default:
+		if err := json.Unmarshal(data, &s.msgs); err != nil {
+			return nil, fmt.Errorf("could not decode message store file: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+func (f *fileMessageStore) Set(_ context.Context, keys []string, msg SentMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.set(keys, msg)
+
+	return f.persist()
+}
+
+func (f *fileMessageStore) Delete(_ context.Context, keys []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, k := range keys {
+		delete(f.msgs, k)
+	}
+
+	return f.persist()
+}
+
+// persist writes the messages in a temporary file and then renames it, this way
+// the file will never be half written.
+func (f *fileMessageStore) persist() error {
+	data, err := json.Marshal(f.msgs)
+	if err != nil {
+		return fmt.Errorf("could not encode messages: %w", err)
+	}
+
+	tmp := f.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write message store file: %w", err)
+	}
+
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,107 @@
+package telegram_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/notify/telegram"
+)
+
+func TestFileMessageStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "alertgram-store")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "telegram", "messages.json")
+
+	// Store messages.
+	s, err := telegram.NewFileMessageStore(path, 0)
+	require.NoError(err)
+	msg := telegram.SentMessage{ChatID: 1234, MessageIDs: []int{42, 43}, SentAt: time.Now().UTC()}
+	require.NoError(s.Set(context.TODO(), []string{"k1", "k2"}, msg))
+	require.NoError(s.Delete(context.TODO(), []string{"k2"}))
+
+	// A new store on the same file should have the messages.
+	s, err = telegram.NewFileMessageStore(path, 0)
+	require.NoError(err)
+
+	got, err := s.Get(context.TODO(), "k1")
+	require.NoError(err)
+	assert.Equal(&msg, got)
+
+	got, err = s.Get(context.TODO(), "k2")
+	require.NoError(err)
+	assert.Nil(got)
+}
+
+func TestMemoryMessageStoreTTL(t *testing.T) {
+	tests := map[string]struct {
+		sentAt time.Time
+		expMsg bool
+	}{
+		"A message sent before the TTL should be returned.": {
+			sentAt: time.Now().Add(-30 * time.Minute),
+			expMsg: true,
+		},
+
+		"A message sent after the TTL should be expired.": {
+			sentAt: time.Now().Add(-2 * time.Hour),
+			expMsg: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			s := telegram.NewMemoryMessageStore(time.Hour)
+			msg := telegram.SentMessage{ChatID: 1234, MessageIDs: []int{42}, SentAt: test.sentAt}
+			require.NoError(s.Set(context.TODO(), []string{"k1"}, msg))
+
+			got, err := s.Get(context.TODO(), "k1")
+			require.NoError(err)
+			if test.expMsg {
+				assert.Equal(&msg, got)
+			} else {
+				assert.Nil(got)
+			}
+		})
+	}
+}
+
+func TestFileChatMigrations(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "alertgram-migrations")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "telegram", "migrations.json")
+
+	// Store migrations.
+	m, err := telegram.NewFileChatMigrations(path)
+	require.NoError(err)
+	require.NoError(m.SetMigration(context.TODO(), 1234, -1001234))
+
+	// A new store on the same file should have the migrations.
+	m, err = telegram.NewFileChatMigrations(path)
+	require.NoError(err)
+
+	to, err := m.MigratedTo(context.TODO(), 1234)
+	require.NoError(err)
+	assert.Equal(int64(-1001234), to)
+
+	to, err = m.MigratedTo(context.TODO(), 5678)
+	require.NoError(err)
+	assert.Equal(int64(0), to)
+}
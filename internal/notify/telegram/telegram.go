@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"net/url"
+	"time"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 
 	"github.com/slok/alertgram/internal/forward"
 	"github.com/slok/alertgram/internal/internalerrors"
 	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
 	"github.com/slok/alertgram/internal/notify"
 )
 
@@ -30,12 +33,63 @@ type Config struct {
 	// - Get the `c1234567891_`, get this part: `1234567891`.
 	// - Add `-100` (until you have 13 characters), this should be the chat ID: `-1001234567891`
 	DefaultTelegramChatID int64
+	// DefaultTelegramTopicID is the forum topic of the default chat where the
+	// alerts will be sent by default, if 0 they will be sent to the chat.
+	DefaultTelegramTopicID int
 	// TemplateRenderer is the renderer that will be used to render the
 	// notifications before sending to Telegram.
 	TemplateRenderer notify.TemplateRenderer
+	// ParseMode is the markup of the rendered notifications, by default HTML.
+	ParseMode ParseMode
 	// Client is the telegram client is compatible with "github.com/go-telegram-bot-api/telegram-bot-api"
 	// library client API.
 	Client Client
+	// ChatMessagesPerSecond is the maximum rate of messages that will be sent to
+	// the same chat, by default 1 message per second.
+	ChatMessagesPerSecond float64
+	// GroupMessagesPerMinute is the maximum rate of messages that will be sent to
+	// the same group or channel, by default 20 messages per minute.
+	GroupMessagesPerMinute float64
+	// MaxRetries is the number of times a message will be retried when Telegram
+	// responds with a too many requests error, by default 3. Use a negative value
+	// to disable the retries.
+	MaxRetries int
+	// ResolvedMode is how the resolved alerts will be notified, by default
+	// sending a new message.
+	ResolvedMode ResolvedMode
+	// AlertButtons enables the inline keyboard buttons on the firing alerts to
+	// acknowledge them, silence them and open them in Prometheus.
+	AlertButtons bool
+	// SilenceDurations are the durations of the alert silence buttons, if
+	// empty, the silence buttons will not be shown.
+	SilenceDurations []time.Duration
+	// SilentMatchers are the label matchers of the alerts that will be sent without
+	// notification sound, the messages are silent when all their alerts match
+	// any of the matchers.
+	SilentMatchers []*forward.Matcher
+	// PinMatchers are the label matchers of the firing alerts whose messages will
+	// be pinned on the chat, the messages are pinned when any of their firing alerts
+	// match any of the matchers, and unpinned when all the alerts are resolved.
+	PinMatchers []*forward.Matcher
+	// GraphRenderer renders the graphs of the firing alerts that will be sent with
+	// the alerts message, if not set the graphs will not be sent.
+	GraphRenderer GraphRenderer
+	// GraphMode is how the alert graphs are sent, by default as the photo of the
+	// alerts message.
+	GraphMode GraphMode
+	// MaxGraphs is the maximum number of graphs sent with an alerts message, by default 1.
+	MaxGraphs int
+	// ChatMuter is used to skip the alerts of the muted chats, by default
+	// the chats can't be muted.
+	ChatMuter ChatMuter
+	// ChatMigrations stores the chats that have been migrated to a new chat (e.g a group
+	// upgraded to a supergroup) to send the alerts to the new chat, by default in memory.
+	ChatMigrations ChatMigrations
+	// MessageStore is the store of the sent messages used by the edit and reply
+	// resolved modes and the alert buttons, by default an in memory store.
+	MessageStore MessageStore
+	// MetricsRecorder is the metrics recorder.
+	MetricsRecorder MetricsRecorder
 	// Logger is the logger.
 	Logger log.Logger
 }
@@ -49,6 +103,66 @@ func (c *Config) defaults() error {
 		c.TemplateRenderer = notify.DefaultTemplateRenderer
 	}
 
+	switch c.ParseMode {
+	case "":
+		c.ParseMode = ParseModeHTML
+	case ParseModeHTML, ParseModeMarkdownV2, ParseModeText:
+	default:
+		return fmt.Errorf("invalid telegram parse mode %q", c.ParseMode)
+	}
+
+	if c.ChatMessagesPerSecond == 0 {
+		c.ChatMessagesPerSecond = 1
+	}
+
+	if c.ChatMessagesPerSecond < 0 {
+		return fmt.Errorf("telegram chat messages per second must be greater than 0")
+	}
+
+	if c.GroupMessagesPerMinute == 0 {
+		c.GroupMessagesPerMinute = 20
+	}
+
+	if c.GroupMessagesPerMinute < 0 {
+		return fmt.Errorf("telegram group messages per minute must be greater than 0")
+	}
+
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+
+	switch c.ResolvedMode {
+	case "":
+		c.ResolvedMode = ResolvedModeNew
+	case ResolvedModeNew, ResolvedModeEdit, ResolvedModeReply:
+	default:
+		return fmt.Errorf("invalid telegram resolved mode %q", c.ResolvedMode)
+	}
+
+	switch c.GraphMode {
+	case "":
+		c.GraphMode = GraphModeCaption
+	case GraphModeCaption, GraphModeReply:
+	default:
+		return fmt.Errorf("invalid telegram graph mode %q", c.GraphMode)
+	}
+
+	if c.MaxGraphs == 0 {
+		c.MaxGraphs = 1
+	}
+
+	if c.MessageStore == nil && (c.ResolvedMode != ResolvedModeNew || c.AlertButtons || len(c.PinMatchers) > 0) {
+		c.MessageStore = NewMemoryMessageStore(0)
+	}
+
+	if c.ChatMigrations == nil {
+		c.ChatMigrations = NewMemoryChatMigrations()
+	}
+
+	if c.MetricsRecorder == nil {
+		c.MetricsRecorder = dummyRecorder
+	}
+
 	if c.Logger == nil {
 		c.Logger = log.Dummy
 	}
@@ -60,6 +174,8 @@ type notifier struct {
 	tplRenderer notify.TemplateRenderer
 	cfg         Config
 	client      Client
+	limiter     *chatRateLimiter
+	metrics     MetricsRecorder
 	logger      log.Logger
 }
 
@@ -75,6 +191,8 @@ func NewNotifier(cfg Config) (forward.Notifier, error) {
 		cfg:         cfg,
 		tplRenderer: cfg.TemplateRenderer,
 		client:      cfg.Client,
+		limiter:     newChatRateLimiter(cfg.ChatMessagesPerSecond, cfg.GroupMessagesPerMinute),
+		metrics:     cfg.MetricsRecorder,
 		logger:      cfg.Logger.WithValues(log.KV{"notifier": "telegram"}),
 	}, nil
 }
@@ -90,59 +208,261 @@ func (n notifier) Notify(ctx context.Context, notification forward.Notification)
 	default:
 	}
 
-	msg, err := n.createMessage(ctx, notification)
+	msgs, err := n.createMessages(ctx, notification)
 	if err != nil {
 		return fmt.Errorf("could not format the alerts to message: %w", err)
 	}
-	logger = n.logger.WithValues(log.KV{"telegramChatID": msg.ChatID})
+	chatID := msgs[0].ChatID
 
-	res, err := n.client.Send(msg)
-	if err != nil {
-		err = fmt.Errorf("%w:  %s", ErrComm, err)
-		return fmt.Errorf("error sending telegram message: %w", err)
+	if n.cfg.ChatMuter != nil {
+		until, err := n.cfg.ChatMuter.MutedUntil(ctx, chatID)
+		if err != nil {
+			return fmt.Errorf("could not get the telegram chat mute: %w", err)
+		}
+		if !until.IsZero() {
+			logger.WithValues(log.KV{"telegramChatID": chatID, "mutedUntil": until}).Infof("telegram chat muted, not notifying alerts")
+			return nil
+		}
+	}
+
+	// Notify the resolved alerts based on the firing alerts messages.
+	switch {
+	case n.cfg.ResolvedMode == ResolvedModeEdit && isResolved(ag):
+		edited, err := n.editMessages(ctx, chatID, ag, msgs)
+		if err != nil {
+			err = fmt.Errorf("%w:  %s", ErrComm, err)
+			return fmt.Errorf("error editing telegram message: %w", err)
+		}
+		if edited {
+			n.updatePinOrLog(ctx, chatID, ag, SentMessage{})
+			return nil
+		}
+	case n.cfg.ResolvedMode == ResolvedModeReply && ag.HasResolved():
+		err := n.setReplyTo(ctx, chatID, ag, msgs)
+		if err != nil {
+			return fmt.Errorf("could not get the firing alerts message: %w", err)
+		}
+	}
+
+	// A single graph of a short message is sent with the message as the caption.
+	graphs := n.renderGraphs(ctx, ag)
+	var captionGraph []byte
+	if n.cfg.GraphMode == GraphModeCaption && len(graphs) == 1 && len(msgs) == 1 && utf8.RuneCountInString(msgs[0].Text) <= maxCaptionLength {
+		captionGraph, graphs = graphs[0], nil
+	}
+
+	// Send the messages in order, if the message was split and one of the parts
+	// fails we don't send the rest.
+	sent := SentMessage{ChatID: chatID, SentAt: time.Now(), Caption: captionGraph != nil}
+	for i, msg := range msgs {
+		logger := logger.WithValues(log.KV{"telegramChatID": msg.ChatID, "part": i + 1, "parts": len(msgs)})
+
+		res, err := n.send(ctx, msg.ChatID, n.withGraph(msg, captionGraph))
+		// The group could have been upgraded to a supergroup, send the messages to the new chat.
+		if newChatID := migratedChatID(err); newChatID != 0 {
+			n.migrateChat(ctx, msg.ChatID, newChatID)
+			chatID, sent.ChatID, msg.ChatID = newChatID, newChatID, newChatID
+			for j := range msgs {
+				msgs[j].ChatID = newChatID
+			}
+			logger = logger.WithValues(log.KV{"telegramChatID": newChatID})
+			res, err = n.send(ctx, msg.ChatID, n.withGraph(msg, captionGraph))
+		}
+		// The replied message could have been deleted, send it without the reply.
+		if msg.ReplyToMessageID != 0 && isTelegramError(err, "reply message not found") {
+			logger.Warningf("telegram message to reply not found, sending without reply")
+			msg.ReplyToMessageID = 0
+			res, err = n.send(ctx, msg.ChatID, n.withGraph(msg, captionGraph))
+		}
+		// The graph is optional, if the photo can't be sent, send the message without it.
+		if err != nil && captionGraph != nil && ctx.Err() == nil {
+			logger.Warningf("could not send the telegram alert graph, sending the message without it: %s", err)
+			captionGraph, sent.Caption = nil, false
+			res, err = n.send(ctx, msg.ChatID, msg)
+		}
+		if err != nil {
+			err = fmt.Errorf("%w:  %s", ErrComm, err)
+			return fmt.Errorf("error sending telegram message: %w", err)
+		}
+		sent.MessageIDs = append(sent.MessageIDs, res.MessageID)
+		logger.Infof("telegram message sent")
+		logger.Debugf("telegram response: %+v", res)
+
+		// Remember the message with the alert buttons so it can be edited when the buttons are pressed.
+		if kb, ok := msg.ReplyMarkup.(*tgbotapi.InlineKeyboardMarkup); ok {
+			btnMsg := SentMessage{ChatID: msg.ChatID, MessageIDs: []int{res.MessageID}, SentAt: sent.SentAt, Text: msg.Text, ParseMode: n.cfg.ParseMode, Keyboard: kb, Caption: sent.Caption}
+			err := n.cfg.MessageStore.Set(ctx, []string{messageKey(msg.ChatID, res.MessageID)}, btnMsg)
+			if err != nil {
+				logger.Errorf("could not store the sent telegram message: %s", err)
+			}
+		}
+	}
+
+	// The messages have already been sent, so we don't fail if the graphs can't be sent.
+	for _, graph := range graphs {
+		photo := topicMessage{MessageConfig: tgbotapi.NewMessage(chatID, ""), topicID: msgs[0].topicID}
+		photo.ReplyToMessageID = sent.MessageIDs[0]
+		photo.DisableNotification = msgs[0].DisableNotification
+		_, err := n.send(ctx, chatID, n.withGraph(photo, graph))
+		if err != nil {
+			logger.Errorf("could not send the telegram alert graph: %s", err)
+			continue
+		}
+		logger.Infof("telegram alert graph sent")
+	}
+
+	n.updatePinOrLog(ctx, chatID, ag, sent)
+
+	// Remember the firing alerts message and forget the resolved ones. The messages have
+	// already been sent, so we don't fail, otherwise the messages could be sent again.
+	if n.cfg.ResolvedMode != ResolvedModeNew {
+		err := n.storeSentMessage(ctx, ag, sent)
+		if err != nil {
+			logger.Errorf("could not store the sent telegram message: %s", err)
+		}
 	}
-	logger.Infof("telegram message sent")
-	logger.Debugf("telegram response: %+v", res)
 
 	return nil
 }
 
-func (n notifier) getChatID(notification forward.Notification) (int64, error) {
+// updatePinOrLog updates the pinned message of the alert group if required, the messages
+// have already been sent, so we don't fail, otherwise the messages could be sent again.
+func (n notifier) updatePinOrLog(ctx context.Context, chatID int64, ag model.AlertGroup, sent SentMessage) {
+	if len(n.cfg.PinMatchers) == 0 {
+		return
+	}
+
+	err := n.updatePin(ctx, chatID, ag, sent)
+	if err != nil {
+		n.logger.WithValues(log.KV{"alertGroup": ag.ID, "telegramChatID": chatID}).Errorf("could not update the pinned telegram message: %s", err)
+	}
+}
+
+// send sends the message to Telegram respecting the chat rate limits, if Telegram
+// responds with a too many requests error, it will wait the time that Telegram asks
+// and retry.
+func (n notifier) send(ctx context.Context, chatID int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	for retry := 0; ; retry++ {
+		delay, err := n.limiter.Wait(ctx, chatID)
+		if err != nil {
+			return tgbotapi.Message{}, fmt.Errorf("rate limit wait interrupted: %w", err)
+		}
+		if delay > 0 {
+			n.metrics.ObserveTelegramSendDelay(ctx, delayReasonRateLimit, delay)
+		}
+
+		res, err := n.sendChattable(ctx, c)
+		if err == nil {
+			return res, nil
+		}
+
+		var tgErr tgbotapi.Error
+		if !errors.As(err, &tgErr) || tgErr.RetryAfter <= 0 {
+			return res, err
+		}
+
+		n.metrics.IncTelegramThrottledSends(ctx)
+		if retry >= n.cfg.MaxRetries {
+			return res, err
+		}
+
+		retryAfter := time.Duration(tgErr.RetryAfter) * time.Second
+		n.logger.WithValues(log.KV{"telegramChatID": chatID, "retry": retry + 1}).
+			Warningf("telegram throttled the message, retrying after %s", retryAfter)
+		if err := sleep(ctx, retryAfter); err != nil {
+			return res, fmt.Errorf("retry wait interrupted: %w", err)
+		}
+		n.metrics.ObserveTelegramSendDelay(ctx, delayReasonRetryAfter, retryAfter)
+	}
+}
+
+// withGraph returns the message as the caption of the graph photo, if there is no graph
+// it will return the message.
+func (n notifier) withGraph(msg topicMessage, graph []byte) tgbotapi.Chattable {
+	if graph == nil {
+		return msg
+	}
+
+	return newGraphPhoto(msg, graph)
+}
+
+func (n notifier) sendChattable(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	switch c := c.(type) {
+	case topicMessage:
+		return sendTopicMessage(n.client, c)
+	case topicPhoto:
+		return sendTopicPhoto(ctx, n.client, c)
+	}
+
+	return n.client.Send(c)
+}
+
+func (n notifier) getChatID(notification forward.Notification) (int64, int, error) {
 	if notification.ChatID == "" {
-		return n.cfg.DefaultTelegramChatID, nil
+		return n.cfg.DefaultTelegramChatID, n.cfg.DefaultTelegramTopicID, nil
 	}
 
-	chatID, err := strconv.ParseInt(notification.ChatID, 10, 64)
+	chatID, topicID, err := ParseChatID(notification.ChatID)
 	if err != nil {
-		return 0, fmt.Errorf("%w: %s", internalerrors.ErrInvalidConfiguration, err)
+		return 0, 0, fmt.Errorf("%w: %s", internalerrors.ErrInvalidConfiguration, err)
 	}
 
-	return chatID, nil
+	return chatID, topicID, nil
 }
 
-func (n notifier) createMessage(ctx context.Context, notification forward.Notification) (tgbotapi.MessageConfig, error) {
-	chatID, err := n.getChatID(notification)
+// createMessages creates the messages of the notification, normally this will be a single
+// message, but if the rendered alerts exceed the Telegram message size limit, these
+// will be split in multiple messages.
+func (n notifier) createMessages(ctx context.Context, notification forward.Notification) ([]topicMessage, error) {
+	chatID, topicID, err := n.getChatID(notification)
 	if err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("could not get a valid telegran chat ID: %w", err)
+		return nil, fmt.Errorf("could not get a valid telegran chat ID: %w", err)
+	}
+
+	chatID, err = n.currentChatID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the telegram chat migrations: %w", err)
 	}
 
 	data, err := n.tplRenderer.Render(ctx, &notification.AlertGroup)
 	if err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("error rendering alerts to template: %w", err)
+		return nil, fmt.Errorf("error rendering alerts to template: %w", err)
+	}
+
+	err = validateMessage(data, n.cfg.ParseMode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid rendered %s message: %s", internalerrors.ErrInvalidConfiguration, n.cfg.ParseMode, err)
+	}
+
+	texts := splitMessage(data, n.cfg.ParseMode, maxMessageLength)
+	silent := allAlertsMatch(notification.AlertGroup.Alerts, n.cfg.SilentMatchers)
+	msgs := make([]topicMessage, 0, len(texts))
+	for _, text := range texts {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = n.cfg.ParseMode.telegramParseMode()
+		msg.DisableWebPagePreview = true // TODO(slok): Make it configurable?
+		msg.DisableNotification = silent
+		msgs = append(msgs, topicMessage{MessageConfig: msg, topicID: topicID})
 	}
 
-	msg := tgbotapi.NewMessage(chatID, data)
-	msg.ParseMode = "HTML"
-	msg.DisableWebPagePreview = true // TODO(slok): Make it configurable?
+	// The alert buttons are set on the last part of the message.
+	if n.cfg.AlertButtons {
+		if kb := alertsKeyboard(notification.AlertGroup, n.cfg.SilenceDurations); kb != nil {
+			msgs[len(msgs)-1].ReplyMarkup = kb
+		}
+	}
 
-	return msg, nil
+	return msgs, nil
 }
 
 func (n notifier) Type() string { return "telegram" }
 
-// Client is an small abstraction for the telegram-bot-api client.
-// the client of this lib should satisfy directly.
+// Client is an small abstraction for the telegram-bot-api client, the file uploads
+// receive the context so the uploads can be cancelled, use NewClient to wrap the
+// telegram-bot-api client.
 // More info here: https://godoc.org/github.com/go-telegram-bot-api/telegram-bot-api.
 type Client interface {
 	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	MakeRequest(endpoint string, params url.Values) (tgbotapi.APIResponse, error)
+	UploadFile(ctx context.Context, endpoint string, params map[string]string, fieldname string, file interface{}) (tgbotapi.APIResponse, error)
 }
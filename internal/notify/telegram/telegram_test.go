@@ -3,7 +3,12 @@ package telegram_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/stretchr/testify/assert"
@@ -98,6 +103,116 @@ func TestNotify(t *testing.T) {
 			},
 		},
 
+		"If using a custom chat ID with a topic it should send to that chat topic.": {
+			cfg: telegram.Config{
+				DefaultTelegramChatID: 1234,
+			},
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				expMsgData := "rendered template"
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return(expMsgData, nil)
+
+				expParams := url.Values{
+					"chat_id":                  {"-1009876543210"},
+					"message_thread_id":        {"42"},
+					"text":                     {expMsgData},
+					"parse_mode":               {"HTML"},
+					"disable_web_page_preview": {"true"},
+					"disable_notification":     {"false"},
+				}
+				mcli.On("MakeRequest", "sendMessage", expParams).Once().Return(tgbotapi.APIResponse{Ok: true, Result: []byte(`{"message_id": 1}`)}, nil)
+			},
+			notification: forward.Notification{
+				ChatID:     "-1009876543210/42",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+		},
+
+		"If using a default topic it should send to the default chat topic.": {
+			cfg: telegram.Config{
+				DefaultTelegramChatID:  1234,
+				DefaultTelegramTopicID: 7,
+			},
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("rendered template", nil)
+
+				expParams := mock.MatchedBy(func(v url.Values) bool {
+					return v.Get("chat_id") == "1234" && v.Get("message_thread_id") == "7"
+				})
+				mcli.On("MakeRequest", "sendMessage", expParams).Once().Return(tgbotapi.APIResponse{Ok: true, Result: []byte(`{"message_id": 1}`)}, nil)
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+		},
+
+		"An invalid topic on the chat ID should fail with an invalid configuration error.": {
+			cfg: telegram.Config{
+				DefaultTelegramChatID: 1234,
+			},
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {},
+			notification: forward.Notification{
+				ChatID:     "-1009876543210/general",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: internalerrors.ErrInvalidConfiguration,
+		},
+
+		"A rendered alertGroup that exceeds the Telegram message limit should be split by alerts.": {
+			cfg: telegram.Config{
+				DefaultTelegramChatID: 1234,
+				ChatMessagesPerSecond: 1000,
+			},
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				alert1 := "<b>" + strings.Repeat("a", 3000) + "</b>"
+				alert2 := "<b>" + strings.Repeat("b", 3000) + "</b>"
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return(alert1+"\n\n"+alert2, nil)
+
+				for _, text := range []string{alert1, alert2} {
+					expMsg := tgbotapi.MessageConfig{
+						BaseChat:              tgbotapi.BaseChat{ChatID: 1234},
+						ParseMode:             "HTML",
+						DisableWebPagePreview: true,
+						Text:                  text,
+					}
+					mcli.On("Send", expMsg).Once().Return(tgbotapi.Message{}, nil)
+				}
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+		},
+
+		"A rendered alertGroup that exceeds the Telegram message limit without split points should keep the HTML tags valid.": {
+			cfg: telegram.Config{
+				DefaultTelegramChatID: 1234,
+				ChatMessagesPerSecond: 1000,
+			},
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return("<b>"+strings.Repeat("a", 5000)+"</b>", nil)
+
+				expTexts := []string{
+					"<b>" + strings.Repeat("a", 4089) + "</b>",
+					"<b>" + strings.Repeat("a", 911) + "</b>",
+				}
+				for _, text := range expTexts {
+					expMsg := tgbotapi.MessageConfig{
+						BaseChat:              tgbotapi.BaseChat{ChatID: 1234},
+						ParseMode:             "HTML",
+						DisableWebPagePreview: true,
+						Text:                  text,
+					}
+					mcli.On("Send", expMsg).Once().Return(tgbotapi.Message{}, nil)
+				}
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+		},
+
 		"A error in the template rendering process should be processed.": {
 			cfg: telegram.Config{
 				DefaultTelegramChatID: 1234,
@@ -146,6 +261,61 @@ func TestNotify(t *testing.T) {
 			},
 			expErr: telegram.ErrComm,
 		},
+
+		"A too many requests error from Telegram should be retried after the time Telegram asks.": {
+			cfg: telegram.Config{
+				DefaultTelegramChatID: 1234,
+			},
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				expMsgData := "rendered template"
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return(expMsgData, nil)
+
+				expMsg := tgbotapi.MessageConfig{
+					BaseChat:              tgbotapi.BaseChat{ChatID: 1234},
+					ParseMode:             "HTML",
+					DisableWebPagePreview: true,
+					Text:                  expMsgData,
+				}
+				errTooManyRequests := tgbotapi.Error{
+					Message:            "Too Many Requests: retry after 1",
+					ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 1},
+				}
+				mcli.On("Send", expMsg).Once().Return(tgbotapi.Message{}, errTooManyRequests)
+				mcli.On("Send", expMsg).Once().Return(tgbotapi.Message{}, nil)
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+		},
+
+		"A too many requests error from Telegram without retries should be processed with communication error.": {
+			cfg: telegram.Config{
+				DefaultTelegramChatID: 1234,
+				MaxRetries:            -1,
+			},
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				expMsgData := "rendered template"
+				expAlertGroup := GetBaseAlertGroup()
+				mr.On("Render", mock.Anything, &expAlertGroup).Once().Return(expMsgData, nil)
+
+				expMsg := tgbotapi.MessageConfig{
+					BaseChat:              tgbotapi.BaseChat{ChatID: 1234},
+					ParseMode:             "HTML",
+					DisableWebPagePreview: true,
+					Text:                  expMsgData,
+				}
+				errTooManyRequests := tgbotapi.Error{
+					Message:            "Too Many Requests: retry after 1",
+					ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 1},
+				}
+				mcli.On("Send", expMsg).Once().Return(tgbotapi.Message{}, errTooManyRequests)
+			},
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: telegram.ErrComm,
+		},
 	}
 
 	for name, test := range tests {
@@ -175,3 +345,903 @@ func TestNotify(t *testing.T) {
 		})
 	}
 }
+
+func TestNotifyResolvedMode(t *testing.T) {
+	mixedAG := func(id string) model.AlertGroup {
+		return model.AlertGroup{ID: id, Alerts: []model.Alert{
+			{ID: "fp1", Status: model.AlertStatusResolved},
+			{ID: "fp2", Status: model.AlertStatusFiring},
+		}}
+	}
+	firingAG := func(id string) model.AlertGroup {
+		return model.AlertGroup{ID: id, Alerts: []model.Alert{{ID: "fp1", Status: model.AlertStatusFiring}}}
+	}
+	resolvedAG := func(id string) model.AlertGroup {
+		return model.AlertGroup{ID: id, Alerts: []model.Alert{{ID: "fp1", Status: model.AlertStatusResolved}}}
+	}
+	newMsg := func(text string) tgbotapi.MessageConfig {
+		return tgbotapi.MessageConfig{
+			BaseChat:              tgbotapi.BaseChat{ChatID: 1234},
+			ParseMode:             "HTML",
+			DisableWebPagePreview: true,
+			Text:                  text,
+		}
+	}
+	replyMsg := func(replyTo int, text string) tgbotapi.MessageConfig {
+		msg := newMsg(text)
+		msg.ReplyToMessageID = replyTo
+		return msg
+	}
+	editMsg := func(msgID int, text string) tgbotapi.EditMessageTextConfig {
+		edit := tgbotapi.NewEditMessageText(1234, msgID, text)
+		edit.ParseMode = "HTML"
+		edit.DisableWebPagePreview = true
+		return edit
+	}
+
+	tests := map[string]struct {
+		mode          telegram.ResolvedMode
+		mocks         func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer)
+		notifications []forward.Notification
+	}{
+		"Edit mode resolved alerts should edit the message of the firing alerts.": {
+			mode: telegram.ResolvedModeEdit,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("firing", nil)
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("resolved", nil)
+
+				mcli.On("Send", newMsg("firing")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", editMsg(42, "resolved")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+			},
+		},
+
+		"Edit mode resolved alerts should edit the message of the firing alerts using the alert fingerprint.": {
+			mode: telegram.ResolvedModeEdit,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("firing", nil)
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("resolved", nil)
+
+				mcli.On("Send", newMsg("firing")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", editMsg(42, "resolved")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: resolvedAG("group2")},
+			},
+		},
+
+		"Edit mode resolved alerts without a firing message should send a new message.": {
+			mode: telegram.ResolvedModeEdit,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("resolved", nil)
+
+				mcli.On("Send", newMsg("resolved")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: resolvedAG("group1")},
+			},
+		},
+
+		"Edit mode resolved alerts whose firing message has been deleted should send a new message.": {
+			mode: telegram.ResolvedModeEdit,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("firing", nil)
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("resolved", nil)
+
+				mcli.On("Send", newMsg("firing")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", editMsg(42, "resolved")).Once().Return(tgbotapi.Message{}, tgbotapi.Error{Message: "Bad Request: message to edit not found"})
+				mcli.On("Send", newMsg("resolved")).Once().Return(tgbotapi.Message{MessageID: 43}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+			},
+		},
+
+		"Edit mode resolved alerts with less parts than the firing message should remove the extra parts.": {
+			mode: telegram.ResolvedModeEdit,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				firing1 := strings.Repeat("a", 4000)
+				firing2 := strings.Repeat("b", 4000)
+				firing3 := strings.Repeat("c", 4000)
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return(firing1+"\n\n"+firing2+"\n\n"+firing3, nil)
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("resolved", nil)
+
+				mcli.On("Send", newMsg(firing1)).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", newMsg(firing2)).Once().Return(tgbotapi.Message{MessageID: 43}, nil)
+				mcli.On("Send", newMsg(firing3)).Once().Return(tgbotapi.Message{MessageID: 44}, nil)
+				mcli.On("Send", editMsg(42, "resolved")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("MakeRequest", "deleteMessage", url.Values{"chat_id": {"1234"}, "message_id": {"43"}}).Once().Return(tgbotapi.APIResponse{Ok: true}, nil)
+				mcli.On("MakeRequest", "deleteMessage", url.Values{"chat_id": {"1234"}, "message_id": {"44"}}).Once().Return(tgbotapi.APIResponse{}, tgbotapi.Error{Message: "Bad Request: message can't be deleted"})
+				mcli.On("Send", editMsg(44, "✅ Resolved")).Once().Return(tgbotapi.Message{MessageID: 44}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+			},
+		},
+
+		"Edit mode resolved alerts should only edit the firing message once.": {
+			mode: telegram.ResolvedModeEdit,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("firing", nil)
+				mr.On("Render", mock.Anything, mock.Anything).Twice().Return("resolved", nil)
+
+				mcli.On("Send", newMsg("firing")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", editMsg(42, "resolved")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", newMsg("resolved")).Once().Return(tgbotapi.Message{MessageID: 43}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+			},
+		},
+
+		"New mode resolved alerts should send a new message.": {
+			mode: telegram.ResolvedModeNew,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("firing", nil)
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("resolved", nil)
+
+				mcli.On("Send", newMsg("firing")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", newMsg("resolved")).Once().Return(tgbotapi.Message{MessageID: 43}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+			},
+		},
+
+		"Reply mode resolved alerts should reply to the message of the firing alerts.": {
+			mode: telegram.ResolvedModeReply,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("firing", nil)
+				mr.On("Render", mock.Anything, mock.Anything).Twice().Return("resolved", nil)
+
+				mcli.On("Send", newMsg("firing")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", replyMsg(42, "resolved")).Once().Return(tgbotapi.Message{MessageID: 43}, nil)
+				mcli.On("Send", newMsg("resolved")).Once().Return(tgbotapi.Message{MessageID: 44}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+			},
+		},
+
+		"Reply mode partially resolved alerts should reply to the message of the resolved alert.": {
+			mode: telegram.ResolvedModeReply,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("firing", nil)
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("mixed", nil)
+
+				mcli.On("Send", newMsg("firing")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", replyMsg(42, "mixed")).Once().Return(tgbotapi.Message{MessageID: 43}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: mixedAG("group1")},
+			},
+		},
+
+		"Reply mode resolved alerts whose firing message has been deleted should send it without reply.": {
+			mode: telegram.ResolvedModeReply,
+			mocks: func(t *testing.T, mcli *telegrammock.Client, mr *notifymock.TemplateRenderer) {
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("firing", nil)
+				mr.On("Render", mock.Anything, mock.Anything).Once().Return("resolved", nil)
+
+				mcli.On("Send", newMsg("firing")).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+				mcli.On("Send", replyMsg(42, "resolved")).Once().Return(tgbotapi.Message{}, tgbotapi.Error{Message: "Bad Request: reply message not found"})
+				mcli.On("Send", newMsg("resolved")).Once().Return(tgbotapi.Message{MessageID: 43}, nil)
+			},
+			notifications: []forward.Notification{
+				{AlertGroup: firingAG("group1")},
+				{AlertGroup: resolvedAG("group1")},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mcli := &telegrammock.Client{}
+			mr := &notifymock.TemplateRenderer{}
+			test.mocks(t, mcli, mr)
+
+			// Execute.
+			n, err := telegram.NewNotifier(telegram.Config{
+				DefaultTelegramChatID: 1234,
+				ChatMessagesPerSecond: 1000,
+				ResolvedMode:          test.mode,
+				Client:                mcli,
+				TemplateRenderer:      mr,
+			})
+			require.NoError(err)
+			for _, notification := range test.notifications {
+				err := n.Notify(context.TODO(), notification)
+				require.NoError(err)
+			}
+
+			// Check.
+			assert.True(mcli.AssertExpectations(t))
+			assert.True(mr.AssertExpectations(t))
+		})
+	}
+}
+
+func TestNotifyAlertButtons(t *testing.T) {
+	tests := map[string]struct {
+		cfg        telegram.Config
+		alertGroup model.AlertGroup
+		expMarkup  interface{}
+	}{
+		"A firing alert should have the silence, ack and Prometheus buttons.": {
+			cfg: telegram.Config{
+				AlertButtons:     true,
+				SilenceDurations: []time.Duration{time.Hour, 24 * time.Hour},
+			},
+			alertGroup: model.AlertGroup{ID: "group1", Alerts: []model.Alert{
+				{ID: "fp1", Status: model.AlertStatusFiring, GeneratorURL: "https://prometheus.test/graph"},
+				{ID: "fp2", Status: model.AlertStatusResolved},
+			}},
+			expMarkup: &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+				{
+					tgbotapi.NewInlineKeyboardButtonData("🔕 1h", "silence:1h:fp1"),
+					tgbotapi.NewInlineKeyboardButtonData("🔕 24h", "silence:24h:fp1"),
+					tgbotapi.NewInlineKeyboardButtonData("👍 Ack", "ack:fp1"),
+					tgbotapi.NewInlineKeyboardButtonURL("📈 Prometheus", "https://prometheus.test/graph"),
+				},
+			}},
+		},
+
+		"Multiple firing alerts should have the buttons identified.": {
+			cfg: telegram.Config{
+				AlertButtons: true,
+			},
+			alertGroup: model.AlertGroup{ID: "group1", Alerts: []model.Alert{
+				{ID: "fp1", Status: model.AlertStatusFiring},
+				{ID: "fp2", Status: model.AlertStatusFiring},
+			}},
+			expMarkup: &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+				{tgbotapi.NewInlineKeyboardButtonData("👍 Ack #1", "ack:fp1")},
+				{tgbotapi.NewInlineKeyboardButtonData("👍 Ack #2", "ack:fp2")},
+			}},
+		},
+
+		"A firing alert with a long ID should have the buttons with the hashed ID.": {
+			cfg: telegram.Config{
+				AlertButtons:     true,
+				SilenceDurations: []time.Duration{time.Hour},
+			},
+			alertGroup: model.AlertGroup{ID: "group1", Alerts: []model.Alert{
+				{ID: "generic:payments-api:HighLatency:production-eu-west-1", Status: model.AlertStatusFiring},
+			}},
+			expMarkup: &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+				{
+					tgbotapi.NewInlineKeyboardButtonData("🔕 1h", "silence:1h:~d6e791ca78d8deb98058ab1b1e49c14"),
+					tgbotapi.NewInlineKeyboardButtonData("👍 Ack", "ack:~d6e791ca78d8deb98058ab1b1e49c14"),
+				},
+			}},
+		},
+
+		"Resolved alerts should not have buttons.": {
+			cfg: telegram.Config{
+				AlertButtons:     true,
+				SilenceDurations: []time.Duration{time.Hour},
+			},
+			alertGroup: model.AlertGroup{ID: "group1", Alerts: []model.Alert{
+				{ID: "fp1", Status: model.AlertStatusResolved},
+			}},
+		},
+
+		"Disabled alert buttons should not set buttons.": {
+			cfg: telegram.Config{},
+			alertGroup: model.AlertGroup{ID: "group1", Alerts: []model.Alert{
+				{ID: "fp1", Status: model.AlertStatusFiring},
+			}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mcli := &telegrammock.Client{}
+			mr := &notifymock.TemplateRenderer{}
+			mr.On("Render", mock.Anything, mock.Anything).Once().Return("rendered", nil)
+			expMsg := tgbotapi.MessageConfig{
+				BaseChat:              tgbotapi.BaseChat{ChatID: 1234, ReplyMarkup: test.expMarkup},
+				ParseMode:             "HTML",
+				DisableWebPagePreview: true,
+				Text:                  "rendered",
+			}
+			mcli.On("Send", expMsg).Once().Return(tgbotapi.Message{MessageID: 42}, nil)
+
+			// Execute.
+			cfg := test.cfg
+			cfg.DefaultTelegramChatID = 1234
+			cfg.Client = mcli
+			cfg.TemplateRenderer = mr
+			n, err := telegram.NewNotifier(cfg)
+			require.NoError(err)
+			err = n.Notify(context.TODO(), forward.Notification{AlertGroup: test.alertGroup})
+
+			// Check.
+			if assert.NoError(err) {
+				mcli.AssertExpectations(t)
+				mr.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestNotifyMutedChat(t *testing.T) {
+	tests := map[string]struct {
+		mute    time.Duration
+		expSend bool
+	}{
+		"A muted chat should not receive the alerts.": {
+			mute:    time.Hour,
+			expSend: false,
+		},
+
+		"A not muted chat should receive the alerts.": {
+			expSend: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			// Mocks.
+			mcli := &telegrammock.Client{}
+			if test.expSend {
+				mcli.On("Send", mock.Anything).Once().Return(tgbotapi.Message{}, nil)
+			}
+			mr := &notifymock.TemplateRenderer{}
+			mr.On("Render", mock.Anything, mock.Anything).Once().Return("rendered", nil)
+
+			muter := telegram.NewMemoryChatMuter()
+			if test.mute != 0 {
+				err := muter.Mute(context.TODO(), 1234, time.Now().Add(test.mute))
+				require.NoError(err)
+			}
+
+			// Execute.
+			n, err := telegram.NewNotifier(telegram.Config{
+				DefaultTelegramChatID: 1234,
+				ChatMuter:             muter,
+				Client:                mcli,
+				TemplateRenderer:      mr,
+			})
+			require.NoError(err)
+			err = n.Notify(context.TODO(), forward.Notification{AlertGroup: GetBaseAlertGroup()})
+
+			// Check.
+			require.NoError(err)
+			mcli.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNotifySilentAndPinned(t *testing.T) {
+	mustMatcher := func(s string) *forward.Matcher {
+		m, err := forward.ParseMatcher(s)
+		require.NoError(t, err)
+		return m
+	}
+	alert := func(status model.AlertStatus, severity string) model.Alert {
+		return model.Alert{ID: severity, Status: status, Labels: map[string]string{"severity": severity}}
+	}
+	pinParams := func(endpoint string, msgID string) url.Values {
+		v := url.Values{"chat_id": {"1234"}, "message_id": {msgID}}
+		if endpoint == "pinChatMessage" {
+			v.Add("disable_notification", "true")
+		}
+		return v
+	}
+
+	tests := map[string]struct {
+		cfg         telegram.Config
+		alertGroups []model.AlertGroup
+		mocks       func(mcli *telegrammock.Client)
+	}{
+		"Alerts that match the silent matchers should be sent silently.": {
+			cfg: telegram.Config{SilentMatchers: []*forward.Matcher{mustMatcher(`severity="info"`)}},
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert(model.AlertStatusFiring, "info")}},
+			},
+			mocks: func(mcli *telegrammock.Client) {
+				mcli.On("Send", mock.MatchedBy(func(m tgbotapi.MessageConfig) bool { return m.DisableNotification })).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"Alerts where some don't match the silent matchers should not be sent silently.": {
+			cfg: telegram.Config{SilentMatchers: []*forward.Matcher{mustMatcher(`severity="info"`)}},
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert(model.AlertStatusFiring, "info"), alert(model.AlertStatusFiring, "critical")}},
+			},
+			mocks: func(mcli *telegrammock.Client) {
+				mcli.On("Send", mock.MatchedBy(func(m tgbotapi.MessageConfig) bool { return !m.DisableNotification })).Once().Return(tgbotapi.Message{}, nil)
+			},
+		},
+
+		"Firing alerts that match the pin matchers should be pinned and unpinned when resolved.": {
+			cfg: telegram.Config{PinMatchers: []*forward.Matcher{mustMatcher(`severity="critical"`)}},
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert(model.AlertStatusFiring, "critical")}},
+				{ID: "group1", Alerts: []model.Alert{alert(model.AlertStatusResolved, "critical")}},
+			},
+			mocks: func(mcli *telegrammock.Client) {
+				mcli.On("Send", mock.Anything).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+				mcli.On("MakeRequest", "pinChatMessage", pinParams("pinChatMessage", "10")).Once().Return(tgbotapi.APIResponse{Ok: true}, nil)
+				mcli.On("Send", mock.Anything).Once().Return(tgbotapi.Message{MessageID: 11}, nil)
+				mcli.On("MakeRequest", "unpinChatMessage", pinParams("unpinChatMessage", "10")).Once().Return(tgbotapi.APIResponse{Ok: true}, nil)
+			},
+		},
+
+		"A new pinned message of the alert group should replace the previous pinned message.": {
+			cfg: telegram.Config{PinMatchers: []*forward.Matcher{mustMatcher(`severity="critical"`)}},
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert(model.AlertStatusFiring, "critical")}},
+				{ID: "group1", Alerts: []model.Alert{alert(model.AlertStatusFiring, "critical")}},
+			},
+			mocks: func(mcli *telegrammock.Client) {
+				mcli.On("Send", mock.Anything).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+				mcli.On("MakeRequest", "pinChatMessage", pinParams("pinChatMessage", "10")).Once().Return(tgbotapi.APIResponse{Ok: true}, nil)
+				mcli.On("Send", mock.Anything).Once().Return(tgbotapi.Message{MessageID: 11}, nil)
+				mcli.On("MakeRequest", "unpinChatMessage", pinParams("unpinChatMessage", "10")).Once().Return(tgbotapi.APIResponse{Ok: true}, nil)
+				mcli.On("MakeRequest", "pinChatMessage", pinParams("pinChatMessage", "11")).Once().Return(tgbotapi.APIResponse{Ok: true}, nil)
+			},
+		},
+
+		"Firing alerts that don't match the pin matchers should not be pinned.": {
+			cfg: telegram.Config{PinMatchers: []*forward.Matcher{mustMatcher(`severity="critical"`)}},
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert(model.AlertStatusFiring, "warning")}},
+			},
+			mocks: func(mcli *telegrammock.Client) {
+				mcli.On("Send", mock.Anything).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			// Mocks.
+			mcli := &telegrammock.Client{}
+			test.mocks(mcli)
+			mr := &notifymock.TemplateRenderer{}
+			mr.On("Render", mock.Anything, mock.Anything).Return("rendered", nil)
+
+			// Execute.
+			test.cfg.DefaultTelegramChatID = 1234
+			test.cfg.ChatMessagesPerSecond = 1000
+			test.cfg.Client = mcli
+			test.cfg.TemplateRenderer = mr
+			n, err := telegram.NewNotifier(test.cfg)
+			require.NoError(err)
+			for _, ag := range test.alertGroups {
+				err = n.Notify(context.TODO(), forward.Notification{AlertGroup: ag})
+				require.NoError(err)
+			}
+
+			// Check.
+			mcli.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNotifyParseMode(t *testing.T) {
+	tests := map[string]struct {
+		parseMode   telegram.ParseMode
+		rendered    string
+		expMessages []tgbotapi.MessageConfig
+		expErr      error
+	}{
+		"HTML messages should be sent with HTML parse mode.": {
+			parseMode: telegram.ParseModeHTML,
+			rendered:  `<b>alert</b> &lt;1&gt; <a href="https://prometheus.test">graph</a>`,
+			expMessages: []tgbotapi.MessageConfig{
+				{BaseChat: tgbotapi.BaseChat{ChatID: 1234}, ParseMode: "HTML", DisableWebPagePreview: true, Text: `<b>alert</b> &lt;1&gt; <a href="https://prometheus.test">graph</a>`},
+			},
+		},
+
+		"Invalid HTML messages should fail.": {
+			parseMode: telegram.ParseModeHTML,
+			rendered:  `<b>alert</b><br>`,
+			expErr:    internalerrors.ErrInvalidConfiguration,
+		},
+
+		"Unclosed HTML tags should fail.": {
+			parseMode: telegram.ParseModeHTML,
+			rendered:  `<b>alert`,
+			expErr:    internalerrors.ErrInvalidConfiguration,
+		},
+
+		"MarkdownV2 messages should be sent with MarkdownV2 parse mode.": {
+			parseMode: telegram.ParseModeMarkdownV2,
+			rendered:  "*alert* \\(1\\.5\\) [graph](https://prometheus.test/graph) `a.b`",
+			expMessages: []tgbotapi.MessageConfig{
+				{BaseChat: tgbotapi.BaseChat{ChatID: 1234}, ParseMode: "MarkdownV2", DisableWebPagePreview: true, Text: "*alert* \\(1\\.5\\) [graph](https://prometheus.test/graph) `a.b`"},
+			},
+		},
+
+		"MarkdownV2 messages with unescaped characters should fail.": {
+			parseMode: telegram.ParseModeMarkdownV2,
+			rendered:  "*alert* 1.5",
+			expErr:    internalerrors.ErrInvalidConfiguration,
+		},
+
+		"MarkdownV2 messages with unclosed entities should fail.": {
+			parseMode: telegram.ParseModeMarkdownV2,
+			rendered:  "*alert",
+			expErr:    internalerrors.ErrInvalidConfiguration,
+		},
+
+		"Split MarkdownV2 messages should close and reopen the entities.": {
+			parseMode: telegram.ParseModeMarkdownV2,
+			rendered:  "*" + strings.Repeat("a", 3000) + "\n\n" + strings.Repeat("b", 3000) + "*",
+			expMessages: []tgbotapi.MessageConfig{
+				{BaseChat: tgbotapi.BaseChat{ChatID: 1234}, ParseMode: "MarkdownV2", DisableWebPagePreview: true, Text: "*" + strings.Repeat("a", 3000) + "*"},
+				{BaseChat: tgbotapi.BaseChat{ChatID: 1234}, ParseMode: "MarkdownV2", DisableWebPagePreview: true, Text: "*" + strings.Repeat("b", 3000) + "*"},
+			},
+		},
+
+		"Text messages should be sent without parse mode.": {
+			parseMode: telegram.ParseModeText,
+			rendered:  "alert <1> & *2*",
+			expMessages: []tgbotapi.MessageConfig{
+				{BaseChat: tgbotapi.BaseChat{ChatID: 1234}, DisableWebPagePreview: true, Text: "alert <1> & *2*"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mcli := &telegrammock.Client{}
+			for _, msg := range test.expMessages {
+				mcli.On("Send", msg).Once().Return(tgbotapi.Message{}, nil)
+			}
+			mr := &notifymock.TemplateRenderer{}
+			mr.On("Render", mock.Anything, mock.Anything).Once().Return(test.rendered, nil)
+
+			// Execute.
+			n, err := telegram.NewNotifier(telegram.Config{
+				DefaultTelegramChatID: 1234,
+				ChatMessagesPerSecond: 1000,
+				ParseMode:             test.parseMode,
+				Client:                mcli,
+				TemplateRenderer:      mr,
+			})
+			require.NoError(err)
+			err = n.Notify(context.TODO(), forward.Notification{AlertGroup: GetBaseAlertGroup()})
+
+			// Check.
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				mcli.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestNotifyGraphs(t *testing.T) {
+	graph := []byte("png")
+	alert := func(id string, status model.AlertStatus, generatorURL string) model.Alert {
+		return model.Alert{ID: id, Status: status, GeneratorURL: generatorURL}
+	}
+	message := func(text string) tgbotapi.MessageConfig {
+		msg := tgbotapi.NewMessage(1234, text)
+		msg.ParseMode = "HTML"
+		msg.DisableWebPagePreview = true
+		return msg
+	}
+	photo := func(caption string, replyTo int) map[string]string {
+		p := map[string]string{"chat_id": "1234", "disable_notification": "false"}
+		if caption != "" {
+			p["caption"] = caption
+			p["parse_mode"] = "HTML"
+		}
+		if replyTo != 0 {
+			p["reply_to_message_id"] = strconv.Itoa(replyTo)
+		}
+		return p
+	}
+	photoFile := tgbotapi.FileBytes{Name: "graph.png", Bytes: graph}
+	photoSent := func(msgID int) tgbotapi.APIResponse {
+		return tgbotapi.APIResponse{Ok: true, Result: []byte(fmt.Sprintf(`{"message_id": %d}`, msgID))}
+	}
+
+	tests := map[string]struct {
+		cfg         telegram.Config
+		chatID      string
+		rendered    string
+		alertGroups []model.AlertGroup
+		mocks       func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer)
+	}{
+		"The graph should be sent with the alerts message as the caption.": {
+			rendered: "rendered",
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert("a1", model.AlertStatusFiring, "http://prom/graph")}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, mock.Anything).Once().Return(graph, nil)
+				mcli.On("UploadFile", mock.Anything, "sendPhoto", photo("rendered", 0), "photo", photoFile).Once().Return(photoSent(10), nil)
+			},
+		},
+
+		"The graph should be sent as a reply of the alerts message on reply mode.": {
+			cfg:      telegram.Config{GraphMode: telegram.GraphModeReply},
+			rendered: "rendered",
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert("a1", model.AlertStatusFiring, "http://prom/graph")}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, mock.Anything).Once().Return(graph, nil)
+				mcli.On("Send", message("rendered")).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+				mcli.On("UploadFile", mock.Anything, "sendPhoto", photo("", 10), "photo", photoFile).Once().Return(photoSent(11), nil)
+			},
+		},
+
+		"The graph of a message that doesn't fit in the caption should be sent as a reply.": {
+			rendered: strings.Repeat("a", 1025),
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert("a1", model.AlertStatusFiring, "http://prom/graph")}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, mock.Anything).Once().Return(graph, nil)
+				mcli.On("Send", message(strings.Repeat("a", 1025))).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+				mcli.On("UploadFile", mock.Anything, "sendPhoto", photo("", 10), "photo", photoFile).Once().Return(photoSent(11), nil)
+			},
+		},
+
+		"The alerts with the same generator URL should share the graph and the resolved alerts should not have graph.": {
+			cfg:      telegram.Config{MaxGraphs: 5},
+			rendered: "rendered",
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{
+					alert("a1", model.AlertStatusResolved, "http://prom/graph1"),
+					alert("a2", model.AlertStatusFiring, "http://prom/graph2"),
+					alert("a3", model.AlertStatusFiring, "http://prom/graph2"),
+					alert("a4", model.AlertStatusFiring, ""),
+				}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, alert("a2", model.AlertStatusFiring, "http://prom/graph2")).Once().Return(graph, nil)
+				mcli.On("UploadFile", mock.Anything, "sendPhoto", photo("rendered", 0), "photo", photoFile).Once().Return(photoSent(10), nil)
+			},
+		},
+
+		"Multiple graphs should be sent as a reply of the alerts message.": {
+			cfg:      telegram.Config{MaxGraphs: 2},
+			rendered: "rendered",
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{
+					alert("a1", model.AlertStatusFiring, "http://prom/graph1"),
+					alert("a2", model.AlertStatusFiring, "http://prom/graph2"),
+					alert("a3", model.AlertStatusFiring, "http://prom/graph3"),
+				}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, mock.Anything).Twice().Return(graph, nil)
+				mcli.On("Send", message("rendered")).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+				mcli.On("UploadFile", mock.Anything, "sendPhoto", photo("", 10), "photo", photoFile).Twice().Return(photoSent(11), nil)
+			},
+		},
+
+		"A graph that can't be sent as the caption should fallback to the message without graph.": {
+			rendered: "rendered",
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert("a1", model.AlertStatusFiring, "http://prom/graph")}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, mock.Anything).Once().Return(graph, nil)
+				mcli.On("UploadFile", mock.Anything, "sendPhoto", photo("rendered", 0), "photo", photoFile).Once().Return(tgbotapi.APIResponse{}, errors.New("whatever"))
+				mcli.On("Send", message("rendered")).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+			},
+		},
+
+		"A graph that can't be rendered should not be sent.": {
+			rendered: "rendered",
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert("a1", model.AlertStatusFiring, "http://prom/graph")}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, mock.Anything).Once().Return(nil, errors.New("whatever"))
+				mcli.On("Send", message("rendered")).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+			},
+		},
+
+		"The graph should be uploaded to the forum topic.": {
+			chatID:   "1234/42",
+			rendered: "rendered",
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert("a1", model.AlertStatusFiring, "http://prom/graph")}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, mock.Anything).Once().Return(graph, nil)
+				params := map[string]string{
+					"chat_id":              "1234",
+					"message_thread_id":    "42",
+					"disable_notification": "false",
+					"caption":              "rendered",
+					"parse_mode":           "HTML",
+				}
+				mcli.On("UploadFile", mock.Anything, "sendPhoto", params, "photo", tgbotapi.FileBytes{Name: "graph.png", Bytes: graph}).Once().
+					Return(tgbotapi.APIResponse{Ok: true, Result: []byte(`{"message_id": 10}`)}, nil)
+			},
+		},
+
+		"The caption of the graph should be edited when the alerts are resolved.": {
+			cfg:      telegram.Config{ResolvedMode: telegram.ResolvedModeEdit},
+			rendered: "rendered",
+			alertGroups: []model.AlertGroup{
+				{ID: "group1", Alerts: []model.Alert{alert("a1", model.AlertStatusFiring, "http://prom/graph")}},
+				{ID: "group1", Alerts: []model.Alert{alert("a1", model.AlertStatusResolved, "http://prom/graph")}},
+			},
+			mocks: func(mcli *telegrammock.Client, mg *telegrammock.GraphRenderer) {
+				mg.On("RenderGraph", mock.Anything, mock.Anything).Once().Return(graph, nil)
+				mcli.On("UploadFile", mock.Anything, "sendPhoto", photo("rendered", 0), "photo", photoFile).Once().Return(photoSent(10), nil)
+				edit := tgbotapi.NewEditMessageCaption(1234, 10, "rendered")
+				edit.ParseMode = "HTML"
+				mcli.On("Send", edit).Once().Return(tgbotapi.Message{MessageID: 10}, nil)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			// Mocks.
+			mcli := &telegrammock.Client{}
+			mg := &telegrammock.GraphRenderer{}
+			test.mocks(mcli, mg)
+			mr := &notifymock.TemplateRenderer{}
+			mr.On("Render", mock.Anything, mock.Anything).Return(test.rendered, nil)
+
+			// Execute.
+			test.cfg.DefaultTelegramChatID = 1234
+			test.cfg.ChatMessagesPerSecond = 1000
+			test.cfg.Client = mcli
+			test.cfg.TemplateRenderer = mr
+			test.cfg.GraphRenderer = mg
+			n, err := telegram.NewNotifier(test.cfg)
+			require.NoError(err)
+			for _, ag := range test.alertGroups {
+				err = n.Notify(context.TODO(), forward.Notification{AlertGroup: ag, ChatID: test.chatID})
+				require.NoError(err)
+			}
+
+			// Check.
+			mcli.AssertExpectations(t)
+			mg.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNotifyChatMigration(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	message := func(chatID int64) tgbotapi.MessageConfig {
+		msg := tgbotapi.NewMessage(chatID, "rendered")
+		msg.ParseMode = "HTML"
+		msg.DisableWebPagePreview = true
+		return msg
+	}
+	migrateErr := tgbotapi.Error{
+		Message:            "Bad Request: group chat was upgraded to a supergroup chat",
+		ResponseParameters: tgbotapi.ResponseParameters{MigrateToChatID: -1005678},
+	}
+
+	// Mocks.
+	mcli := &telegrammock.Client{}
+	mcli.On("Send", message(1234)).Once().Return(tgbotapi.Message{}, migrateErr)
+	mcli.On("Send", message(-1005678)).Twice().Return(tgbotapi.Message{}, nil)
+	mr := &notifymock.TemplateRenderer{}
+	mr.On("Render", mock.Anything, mock.Anything).Return("rendered", nil)
+
+	// Execute.
+	migrations := telegram.NewMemoryChatMigrations()
+	n, err := telegram.NewNotifier(telegram.Config{
+		DefaultTelegramChatID: 1234,
+		ChatMessagesPerSecond: 1000,
+		ChatMigrations:        migrations,
+		Client:                mcli,
+		TemplateRenderer:      mr,
+	})
+	require.NoError(err)
+
+	// The first notification should be migrated to the new chat and the next ones sent directly.
+	for i := 0; i < 2; i++ {
+		err = n.Notify(context.TODO(), forward.Notification{AlertGroup: GetBaseAlertGroup()})
+		require.NoError(err)
+	}
+
+	// Check.
+	mcli.AssertExpectations(t)
+	to, err := migrations.MigratedTo(context.TODO(), 1234)
+	require.NoError(err)
+	assert.Equal(int64(-1005678), to)
+}
+
+func TestNewNotifierInvalidRateLimits(t *testing.T) {
+	tests := map[string]struct {
+		cfg telegram.Config
+	}{
+		"A negative chat rate limit should fail.": {
+			cfg: telegram.Config{ChatMessagesPerSecond: -1},
+		},
+
+		"A negative group rate limit should fail.": {
+			cfg: telegram.Config{GroupMessagesPerMinute: -20},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.cfg.Client = &telegrammock.Client{}
+			_, err := telegram.NewNotifier(test.cfg)
+			assert.True(t, errors.Is(err, internalerrors.ErrInvalidConfiguration))
+		})
+	}
+}
+
+func TestNotifyRateLimitCancelled(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Mocks.
+	mcli := &telegrammock.Client{}
+	mcli.On("Send", mock.Anything).Twice().Return(tgbotapi.Message{}, nil)
+	mr := &notifymock.TemplateRenderer{}
+	mr.On("Render", mock.Anything, mock.Anything).Return("rendered", nil)
+
+	// Execute.
+	n, err := telegram.NewNotifier(telegram.Config{
+		DefaultTelegramChatID: 1234,
+		ChatMessagesPerSecond: 2,
+		Client:                mcli,
+		TemplateRenderer:      mr,
+	})
+	require.NoError(err)
+
+	err = n.Notify(context.TODO(), forward.Notification{AlertGroup: GetBaseAlertGroup()})
+	require.NoError(err)
+
+	// The notifications cancelled while waiting should not delay the next ones.
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err = n.Notify(ctx, forward.Notification{AlertGroup: GetBaseAlertGroup()})
+		cancel()
+		require.Error(err)
+	}
+
+	start := time.Now()
+	err = n.Notify(context.TODO(), forward.Notification{AlertGroup: GetBaseAlertGroup()})
+	require.NoError(err)
+
+	// Check.
+	assert.Less(int64(time.Since(start)), int64(1500*time.Millisecond))
+	mcli.AssertExpectations(t)
+}
@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// ParseChatID parses a Telegram chat target, a chat ID with an optional forum
+// topic (message thread) ID, e.g: `-1001234567890` or `-1001234567890/42`.
+func ParseChatID(s string) (chatID int64, topicID int, err error) {
+	chat, topic := s, ""
+	if i := strings.Index(s, "/"); i >= 0 {
+		chat, topic = s[:i], s[i+1:]
+	}
+
+	chatID, err = strconv.ParseInt(chat, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid telegram chat ID %q: %w", s, err)
+	}
+
+	if topic != "" {
+		topicID, err = strconv.Atoi(topic)
+		if err != nil || topicID <= 0 {
+			return 0, 0, fmt.Errorf("invalid telegram topic ID %q", s)
+		}
+	}
+
+	return chatID, topicID, nil
+}
+
+// topicMessage is a message that will be sent to a forum topic of the chat, if
+// the topic is not set, it will be sent to the chat.
+type topicMessage struct {
+	tgbotapi.MessageConfig
+	topicID int
+}
+
+// sendTopicMessage sends the message to the topic, the telegram-bot-api library doesn't
+// support the topics so we need to make the request ourselves.
+func sendTopicMessage(client Client, msg topicMessage) (tgbotapi.Message, error) {
+	if msg.topicID == 0 {
+		return client.Send(msg.MessageConfig)
+	}
+
+	v := url.Values{}
+	v.Add("chat_id", strconv.FormatInt(msg.ChatID, 10))
+	v.Add("message_thread_id", strconv.Itoa(msg.topicID))
+	v.Add("text", msg.Text)
+	v.Add("disable_web_page_preview", strconv.FormatBool(msg.DisableWebPagePreview))
+	v.Add("disable_notification", strconv.FormatBool(msg.DisableNotification))
+	if msg.ParseMode != "" {
+		v.Add("parse_mode", msg.ParseMode)
+	}
+	if msg.ReplyToMessageID != 0 {
+		v.Add("reply_to_message_id", strconv.Itoa(msg.ReplyToMessageID))
+	}
+	if msg.ReplyMarkup != nil {
+		data, err := json.Marshal(msg.ReplyMarkup)
+		if err != nil {
+			return tgbotapi.Message{}, err
+		}
+		v.Add("reply_markup", string(data))
+	}
+
+	resp, err := client.MakeRequest("sendMessage", v)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	var res tgbotapi.Message
+	err = json.Unmarshal(resp.Result, &res)
+	if err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("could not decode telegram message: %w", err)
+	}
+
+	return res, nil
+}
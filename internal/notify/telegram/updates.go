@@ -0,0 +1,247 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+)
+
+// UpdateHandler knows how to handle the Telegram bot updates (e.g button presses).
+type UpdateHandler interface {
+	HandleUpdate(ctx context.Context, update tgbotapi.Update) error
+}
+
+// UpdateHandlerFunc is a helper function to use funcs as UpdateHandler types.
+type UpdateHandlerFunc func(ctx context.Context, update tgbotapi.Update) error
+
+// HandleUpdate satisfies UpdateHandler interface.
+func (u UpdateHandlerFunc) HandleUpdate(ctx context.Context, update tgbotapi.Update) error {
+	return u(ctx, update)
+}
+
+// UpdateHandlers is a list of UpdateHandler that handle all the updates
+// in order, the handlers ignore the updates they don't know how to handle.
+type UpdateHandlers []UpdateHandler
+
+// HandleUpdate satisfies UpdateHandler interface.
+func (u UpdateHandlers) HandleUpdate(ctx context.Context, update tgbotapi.Update) error {
+	var rErr error
+	for _, h := range u {
+		err := h.HandleUpdate(ctx, update)
+		if err != nil && rErr == nil {
+			rErr = err
+		}
+	}
+
+	return rErr
+}
+
+// allowList are the users and chats allowed to use the bot, the users are allowed
+// on any chat and anyone is allowed on the chats.
+type allowList struct {
+	users map[int]bool
+	chats map[int64]bool
+}
+
+func newAllowList(userIDs []int, chatIDs []int64) allowList {
+	a := allowList{users: map[int]bool{}, chats: map[int64]bool{}}
+	for _, id := range userIDs {
+		a.users[id] = true
+	}
+	for _, id := range chatIDs {
+		a.chats[id] = true
+	}
+
+	return a
+}
+
+// allowed returns true if the user or the chat are allowed.
+func (a allowList) allowed(user *tgbotapi.User, chat *tgbotapi.Chat) bool {
+	if user != nil && a.users[user.ID] {
+		return true
+	}
+
+	return chat != nil && a.chats[chat.ID]
+}
+
+// UpdatesClient is an small abstraction for the telegram-bot-api client
+// to get the bot updates.
+type UpdatesClient interface {
+	GetUpdates(config tgbotapi.UpdateConfig) ([]tgbotapi.Update, error)
+}
+
+// UpdatesPollerConfig is the configuration of the UpdatesPoller.
+type UpdatesPollerConfig struct {
+	// Client is the Telegram client used to get the updates.
+	Client UpdatesClient
+	// Handler is the handler of the updates.
+	Handler UpdateHandler
+	// PollTimeout is the long polling timeout, by default 10s.
+	PollTimeout time.Duration
+	// RetryInterval is the wait time after an error getting the updates, by default 5s.
+	RetryInterval time.Duration
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *UpdatesPollerConfig) defaults() error {
+	if c.Client == nil {
+		return fmt.Errorf("telegram updates client is required")
+	}
+
+	if c.Handler == nil {
+		return fmt.Errorf("telegram updates handler is required")
+	}
+
+	if c.PollTimeout == 0 {
+		c.PollTimeout = 10 * time.Second
+	}
+
+	if c.RetryInterval == 0 {
+		c.RetryInterval = 5 * time.Second
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+// UpdatesPoller gets the bot updates from Telegram using long polling
+// and handles them.
+type UpdatesPoller struct {
+	cfg    UpdatesPollerConfig
+	client UpdatesClient
+	logger log.Logger
+}
+
+// NewUpdatesPoller returns a new UpdatesPoller.
+func NewUpdatesPoller(cfg UpdatesPollerConfig) (*UpdatesPoller, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &UpdatesPoller{
+		cfg:    cfg,
+		client: cfg.Client,
+		logger: cfg.Logger.WithValues(log.KV{"service": "telegram-updates-poller"}),
+	}, nil
+}
+
+// Run gets and handles the updates until the context is done.
+func (u *UpdatesPoller) Run(ctx context.Context) error {
+	offset := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		cfg := tgbotapi.NewUpdate(offset)
+		cfg.Timeout = int(u.cfg.PollTimeout.Seconds())
+		updates, err := u.client.GetUpdates(cfg)
+		if err != nil {
+			u.logger.Errorf("could not get telegram updates: %s", err)
+			if err := sleep(ctx, u.cfg.RetryInterval); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			if update.UpdateID >= offset {
+				offset = update.UpdateID + 1
+			}
+
+			err := u.cfg.Handler.HandleUpdate(ctx, update)
+			if err != nil {
+				u.logger.Errorf("could not handle telegram update %d: %s", update.UpdateID, err)
+			}
+		}
+	}
+}
+
+// secretTokenHeader is the header where Telegram sends the webhook secret token.
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookClient is an small abstraction for the telegram-bot-api client
+// to set the bot updates webhook.
+type WebhookClient interface {
+	MakeRequest(endpoint string, params url.Values) (tgbotapi.APIResponse, error)
+}
+
+// SetUpdatesWebhook sets the bot updates webhook with the secret token that Telegram
+// will send on the webhook requests, the telegram-bot-api library doesn't support
+// the secret token so we need to make the request ourselves.
+func SetUpdatesWebhook(client WebhookClient, webhookURL, secretToken string) error {
+	v := url.Values{}
+	v.Set("url", webhookURL)
+	v.Set("secret_token", secretToken)
+	_, err := client.MakeRequest("setWebhook", v)
+	return err
+}
+
+// NewUpdatesWebhookSecret returns a random secret token for the bot updates webhook.
+func NewUpdatesWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate telegram webhook secret token: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// NewUpdatesWebhookHandler returns an HTTP handler that receives the bot updates
+// sent by Telegram to the bot webhook and handles them. The requests without the
+// webhook secret token are rejected, so the updates can't be forged.
+func NewUpdatesWebhookHandler(handler UpdateHandler, secretToken string, logger log.Logger) (http.Handler, error) {
+	if secretToken == "" {
+		return nil, fmt.Errorf("telegram webhook secret token is required: %w", internalerrors.ErrInvalidConfiguration)
+	}
+
+	if logger == nil {
+		logger = log.Dummy
+	}
+	logger = logger.WithValues(log.KV{"service": "telegram-updates-webhook"})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(secretToken)) != 1 {
+			logger.Warningf("rejected telegram update with an invalid secret token")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		update := tgbotapi.Update{}
+		err := json.NewDecoder(r.Body).Decode(&update)
+		if err != nil {
+			logger.Errorf("error unmarshalling telegram update: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// Telegram retries the updates that fail, we don't want to handle them again.
+		err = handler.HandleUpdate(r.Context(), update)
+		if err != nil {
+			logger.Errorf("could not handle telegram update %d: %s", update.UpdateID, err)
+		}
+	}), nil
+}
@@ -0,0 +1,80 @@
+package telegram_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/notify/telegram"
+)
+
+func TestUpdatesWebhookHandler(t *testing.T) {
+	tests := map[string]struct {
+		secretToken string
+		header      string
+		expHandled  bool
+		expCode     int
+		expErr      bool
+	}{
+		"A missing secret token should fail.": {
+			secretToken: "",
+			expErr:      true,
+		},
+
+		"A request without the secret token should be rejected.": {
+			secretToken: "s3cr3t",
+			header:      "",
+			expCode:     http.StatusUnauthorized,
+		},
+
+		"A request with an invalid secret token should be rejected.": {
+			secretToken: "s3cr3t",
+			header:      "wrong",
+			expCode:     http.StatusUnauthorized,
+		},
+
+		"A request with the secret token should be handled.": {
+			secretToken: "s3cr3t",
+			header:      "s3cr3t",
+			expHandled:  true,
+			expCode:     http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handled := false
+			uh := telegram.UpdateHandlerFunc(func(_ context.Context, update tgbotapi.Update) error {
+				handled = true
+				assert.Equal(1234, update.UpdateID)
+				return nil
+			})
+
+			h, err := telegram.NewUpdatesWebhookHandler(uh, test.secretToken, nil)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			req := httptest.NewRequest(http.MethodPost, "/telegram", strings.NewReader(`{"update_id": 1234}`))
+			if test.header != "" {
+				req.Header.Set("X-Telegram-Bot-Api-Secret-Token", test.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(test.expCode, w.Code)
+			assert.Equal(test.expHandled, handled)
+		})
+	}
+}
@@ -6,6 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/slok/alertgram/internal/model"
@@ -43,8 +47,112 @@ func NewHTMLTemplateRenderer(tpl string) (TemplateRenderer, error) {
 	}), nil
 }
 
+// NewTextTemplateRenderer returns a new template renderer using the go text
+// template renderer, the rendered data will not be escaped.
+// The templates use https://github.com/Masterminds/sprig to render.
+func NewTextTemplateRenderer(tpl string) (TemplateRenderer, error) {
+	t, err := texttemplate.New("tpl").Funcs(sprig.TxtFuncMap()).Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering template: %w", err)
+	}
+
+	return TemplateRendererFunc(func(_ context.Context, ag *model.AlertGroup) (string, error) {
+		return renderAlertGroup(ag, t)
+	}), nil
+}
+
+// MarkdownV2 is a safe Telegram MarkdownV2 text that will not be escaped
+// when rendered in the MarkdownV2 templates.
+type MarkdownV2 string
+
+const escapeMarkdownV2Func = "escapeMarkdownV2"
+
+// NewMarkdownV2TemplateRenderer returns a new template renderer for Telegram
+// MarkdownV2 messages using the go text template renderer.
+// Like the HTML templates, the template text is the markup and the data rendered
+// by the template actions is escaped, use `rawMarkdownV2` function to render
+// data as markup.
+// The templates use https://github.com/Masterminds/sprig to render.
+func NewMarkdownV2TemplateRenderer(tpl string) (TemplateRenderer, error) {
+	funcs := sprig.TxtFuncMap()
+	funcs[escapeMarkdownV2Func] = func(v interface{}) string {
+		switch v := v.(type) {
+		case nil:
+			return ""
+		case MarkdownV2:
+			return string(v)
+		default:
+			return EscapeMarkdownV2(fmt.Sprint(v))
+		}
+	}
+	funcs["rawMarkdownV2"] = func(s string) MarkdownV2 { return MarkdownV2(s) }
+
+	t, err := texttemplate.New("tpl").Funcs(funcs).Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering template: %w", err)
+	}
+
+	// Escape the output of all the actions.
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			escapeActions(tt.Tree, tt.Tree.Root)
+		}
+	}
+
+	return TemplateRendererFunc(func(_ context.Context, ag *model.AlertGroup) (string, error) {
+		return renderAlertGroup(ag, t)
+	}), nil
+}
+
+// escapeActions adds the MarkdownV2 escape function at the end of the pipelines
+// of the actions that output data.
+func escapeActions(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, nn := range n.Nodes {
+			escapeActions(tree, nn)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		escape := parse.NewIdentifier(escapeMarkdownV2Func).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{escape}})
+	case *parse.IfNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.RangeNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.WithNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	}
+}
+
+// markdownV2Escaper escapes the Telegram MarkdownV2 reserved characters.
+var markdownV2Escaper = func() *strings.Replacer {
+	pairs := []string{}
+	for _, c := range "\\_*[]()~`>#+-=|{}.!" {
+		pairs = append(pairs, string(c), "\\"+string(c))
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// EscapeMarkdownV2 escapes the text so it can be used on Telegram MarkdownV2 messages.
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Escaper.Replace(s)
+}
+
+type templateExecutor interface {
+	Execute(wr io.Writer, data interface{}) error
+}
+
 // renderAlertGroup takes an alertGroup and renders on the given template.
-func renderAlertGroup(ag *model.AlertGroup, t *template.Template) (string, error) {
+func renderAlertGroup(ag *model.AlertGroup, t templateExecutor) (string, error) {
 	var b bytes.Buffer
 	err := t.Execute(&b, ag)
 	if err != nil {
@@ -118,4 +226,8 @@ var defTemplate = template.Must(template.New("def").Funcs(sprig.FuncMap()).Parse
   {{- end}}
 {{- end }}
 {{- end }}
+{{- if .TruncatedAlerts }}
+
+⚠️ {{ .TruncatedAlerts }} alerts truncated
+{{- end }}
 `))
@@ -41,6 +41,41 @@ func TestTemplateRenderer(t *testing.T) {
 			expData: "test-alert has 3 alerts.",
 		},
 
+		"Text template should render the alerts without escaping.": {
+			alertGroup: func() *model.AlertGroup {
+				return &model.AlertGroup{
+					ID:     "test-alert",
+					Alerts: []model.Alert{{Labels: map[string]string{"alertname": "<Latency> & Errors"}}},
+				}
+			},
+			renderer: func() notify.TemplateRenderer {
+				r, _ := notify.NewTextTemplateRenderer("{{ range .Alerts }}{{ .Labels.alertname }}{{ end }}")
+				return r
+			},
+			expData: "<Latency> & Errors",
+		},
+
+		"MarkdownV2 template should render the alerts escaping the data.": {
+			alertGroup: func() *model.AlertGroup {
+				return &model.AlertGroup{
+					ID: "test-alert",
+					Alerts: []model.Alert{{
+						Labels:       map[string]string{"alertname": "High_Latency (p99)"},
+						Annotations:  map[string]string{"message": "Latency > 1.5s!"},
+						GeneratorURL: "https://prometheus.test/graph?g0.expr=up",
+					}},
+				}
+			},
+			renderer: func() notify.TemplateRenderer {
+				r, _ := notify.NewMarkdownV2TemplateRenderer(
+					"{{ range .Alerts }}*{{ .Labels.alertname }}*\n" +
+						"{{ $msg := .Annotations.message }}{{ $msg }} {{ .Annotations.missing }}\n" +
+						"{{ if .GeneratorURL }}[graph]({{ .GeneratorURL }}){{ end }} {{ rawMarkdownV2 \"_raw_\" }}{{ end }}")
+				return r
+			},
+			expData: "*High\\_Latency \\(p99\\)*\nLatency \\> 1\\.5s\\! \n[graph](https://prometheus\\.test/graph?g0\\.expr\\=up) _raw_",
+		},
+
 		"Default template should render the alerts correctly.": {
 			alertGroup: func() *model.AlertGroup {
 				al1 := model.Alert{
@@ -116,6 +151,29 @@ func TestTemplateRenderer(t *testing.T) {
 
 🟢🟢🟢 <b>ServicePodIsRestarting</b> 🟢🟢🟢
   There has been restarting more than 5 times over 20 minutes
+`,
+			renderer: func() notify.TemplateRenderer { return notify.DefaultTemplateRenderer },
+		},
+
+		"Default template should render the truncated alerts.": {
+			alertGroup: func() *model.AlertGroup {
+				return &model.AlertGroup{
+					ID: "test-alert",
+					Alerts: []model.Alert{{
+						Status:      model.AlertStatusFiring,
+						Labels:      map[string]string{"alertname": "ServicePodIsRestarting"},
+						Annotations: map[string]string{"message": "Restarting"},
+					}},
+					TruncatedAlerts: 5,
+				}
+			},
+			expData: `
+🚨🚨 FIRING ALERTS 🚨🚨
+
+💥💥💥 <b>ServicePodIsRestarting</b> 💥💥💥
+  Restarting
+
+⚠️ 5 alerts truncated
 `,
 			renderer: func() notify.TemplateRenderer { return notify.DefaultTemplateRenderer },
 		},
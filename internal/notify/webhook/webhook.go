@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/log"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify"
+)
+
+var (
+	// ErrComm will be used when the communication to the webhook fails.
+	ErrComm = errors.New("error communicating with webhook")
+)
+
+const (
+	defSignatureHeader = "X-Alertgram-Signature"
+)
+
+var defSuccessStatusCodes = []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent}
+
+// Config is the configuration of the Notifier.
+type Config struct {
+	// URL is the URL where the notifications will be sent.
+	URL string
+	// BodyTemplate is the Go text template used to render the JSON body of the
+	// requests, it receives the `forward.Notification` and can use the sprig
+	// functions and `alertStatus` to get the status of an alert as a string.
+	// If empty, a default JSON representation of the notification will be used.
+	BodyTemplate string
+	// Headers are custom headers that will be set on the requests.
+	Headers map[string]string
+	// HMACSecret is the optional secret used to sign the body of the requests
+	// with HMAC-SHA256, the signature will be set on the signature header.
+	HMACSecret string
+	// SignatureHeader is the header where the signature will be set, by
+	// default `X-Alertgram-Signature`.
+	SignatureHeader string
+	// SuccessStatusCodes are the response status codes that will be
+	// treated as success, by default 200, 201, 202 and 204.
+	SuccessStatusCodes []int
+	// HTTPClient is the HTTP client used to send the requests.
+	HTTPClient *http.Client
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *Config) defaults() error {
+	if c.URL == "" {
+		return fmt.Errorf("webhook URL is required")
+	}
+
+	if c.BodyTemplate == "" {
+		c.BodyTemplate = defBodyTemplate
+	}
+
+	if c.SignatureHeader == "" {
+		c.SignatureHeader = defSignatureHeader
+	}
+
+	if len(c.SuccessStatusCodes) == 0 {
+		c.SuccessStatusCodes = defSuccessStatusCodes
+	}
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+
+	return nil
+}
+
+type notifier struct {
+	cfg          Config
+	tpl          *template.Template
+	successCodes map[int]bool
+	httpCli      *http.Client
+	logger       log.Logger
+}
+
+// NewNotifier returns a notifier is a generic webhook notifier
+// that knows how to send alerts as JSON to any HTTP endpoint.
+func NewNotifier(cfg Config) (forward.Notifier, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	tpl, err := template.New("body").Funcs(sprig.TxtFuncMap()).Funcs(template.FuncMap{
+		"alertStatus": alertStatus,
+	}).Parse(cfg.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook body template: %s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	successCodes := map[int]bool{}
+	for _, code := range cfg.SuccessStatusCodes {
+		successCodes[code] = true
+	}
+
+	return &notifier{
+		cfg:          cfg,
+		tpl:          tpl,
+		successCodes: successCodes,
+		httpCli:      cfg.HTTPClient,
+		logger:       cfg.Logger.WithValues(log.KV{"notifier": "webhook"}),
+	}, nil
+}
+
+func (n notifier) Notify(ctx context.Context, notification forward.Notification) error {
+	ag := notification.AlertGroup
+
+	logger := n.logger.WithValues(log.KV{"alertGroup": ag.ID, "alertsNumber": len(ag.Alerts)})
+	select {
+	case <-ctx.Done():
+		logger.Infof("context cancelled, not notifying alerts")
+		return nil
+	default:
+	}
+
+	body, err := n.renderBody(notification)
+	if err != nil {
+		return fmt.Errorf("could not render the webhook body: %w", err)
+	}
+
+	err = n.send(ctx, body)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrComm, err)
+		return fmt.Errorf("error sending webhook request: %w", err)
+	}
+	logger.Infof("webhook request sent")
+
+	return nil
+}
+
+func (n notifier) Type() string { return "webhook" }
+
+// renderBody renders the template and validates that the result is valid JSON.
+func (n notifier) renderBody(notification forward.Notification) ([]byte, error) {
+	var b bytes.Buffer
+	err := n.tpl.Execute(&b, notification)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", notify.ErrRenderTemplate, err)
+	}
+
+	var body bytes.Buffer
+	err = json.Compact(&body, b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: rendered body is not valid JSON: %s", notify.ErrRenderTemplate, err)
+	}
+
+	return body.Bytes(), nil
+}
+
+func (n notifier) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if n.cfg.HMACSecret != "" {
+		req.Header.Set(n.cfg.SignatureHeader, sign(n.cfg.HMACSecret, body))
+	}
+
+	resp, err := n.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !n.successCodes[resp.StatusCode] {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected webhook response status code %d: %s", resp.StatusCode, respBody)
+	}
+
+	return nil
+}
+
+// sign returns the `sha256=<hex>` HMAC-SHA256 signature of the body.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func alertStatus(s model.AlertStatus) string {
+	switch s {
+	case model.AlertStatusFiring:
+		return "firing"
+	case model.AlertStatusResolved:
+		return "resolved"
+	default:
+		return "unknown"
+	}
+}
+
+const defBodyTemplate = `{
+  "chatID": {{ .ChatID | toJson }},
+  "groupID": {{ .AlertGroup.ID | toJson }},
+  "groupLabels": {{ .AlertGroup.Labels | toJson }},
+  "receiver": {{ .AlertGroup.Receiver | toJson }},
+  "status": {{ alertStatus .AlertGroup.Status | toJson }},
+  "commonLabels": {{ .AlertGroup.CommonLabels | toJson }},
+  "commonAnnotations": {{ .AlertGroup.CommonAnnotations | toJson }},
+  "externalURL": {{ .AlertGroup.ExternalURL | toJson }},
+  "truncatedAlerts": {{ .AlertGroup.TruncatedAlerts }},
+  "alerts": [
+  {{- range $i, $a := .AlertGroup.Alerts }}{{ if $i }},{{ end }}
+    {
+      "id": {{ $a.ID | toJson }},
+      "name": {{ $a.Name | toJson }},
+      "status": {{ alertStatus $a.Status | toJson }},
+      "startsAt": {{ $a.StartsAt | toJson }},
+      "endsAt": {{ $a.EndsAt | toJson }},
+      "labels": {{ $a.Labels | toJson }},
+      "annotations": {{ $a.Annotations | toJson }},
+      "generatorURL": {{ $a.GeneratorURL | toJson }}
+    }
+  {{- end }}
+  ]
+}`
@@ -0,0 +1,182 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/forward"
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/notify"
+	"github.com/slok/alertgram/internal/notify/webhook"
+)
+
+func GetBaseAlertGroup() model.AlertGroup {
+	return model.AlertGroup{
+		ID:              "test-alert",
+		Receiver:        "telegram",
+		Status:          model.AlertStatusFiring,
+		CommonLabels:    map[string]string{"alertname": "ServicePodIsRestarting"},
+		ExternalURL:     "http://alertmanager.test",
+		TruncatedAlerts: 2,
+		Alerts: []model.Alert{
+			{
+				ID:       "alert-1",
+				Name:     "ServicePodIsRestarting",
+				Status:   model.AlertStatusFiring,
+				StartsAt: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+				Labels: map[string]string{
+					"alertname": "ServicePodIsRestarting",
+				},
+				Annotations: map[string]string{
+					"message": "There has been restarting more than 5 times over 20 minutes",
+				},
+			},
+		},
+	}
+}
+
+type webhookRequest struct {
+	headers http.Header
+	body    string
+}
+
+func TestNotify(t *testing.T) {
+	tests := map[string]struct {
+		cfg          webhook.Config
+		srvStatus    int
+		notification forward.Notification
+		expHeaders   map[string]string
+		expBody      string
+		expErr       error
+	}{
+		"A notification should be sent using the default body template.": {
+			srvStatus: http.StatusOK,
+			notification: forward.Notification{
+				ChatID:     "team1",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expHeaders: map[string]string{"Content-Type": "application/json"},
+			expBody: `{
+	"chatID": "team1",
+	"groupID": "test-alert",
+	"groupLabels": null,
+	"receiver": "telegram",
+	"status": "firing",
+	"commonLabels": {"alertname": "ServicePodIsRestarting"},
+	"commonAnnotations": null,
+	"externalURL": "http://alertmanager.test",
+	"truncatedAlerts": 2,
+	"alerts": [{
+		"id": "alert-1",
+		"name": "ServicePodIsRestarting",
+		"status": "firing",
+		"startsAt": "2021-01-02T03:04:05Z",
+		"endsAt": "0001-01-01T00:00:00Z",
+		"labels": {"alertname": "ServicePodIsRestarting"},
+		"annotations": {"message": "There has been restarting more than 5 times over 20 minutes"},
+		"generatorURL": ""
+	}]
+}`,
+		},
+
+		"A notification should be sent using a custom body template, custom headers and signed.": {
+			cfg: webhook.Config{
+				BodyTemplate: `{"title": {{ printf "%d alerts" (len .AlertGroup.Alerts) | toJson }}, "queue": {{ .ChatID | default "ops" | toJson }}}`,
+				Headers:      map[string]string{"X-Token": "secret-token"},
+				HMACSecret:   "test-secret",
+			},
+			srvStatus: http.StatusOK,
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expHeaders: map[string]string{
+				"X-Token":               "secret-token",
+				"X-Alertgram-Signature": "sha256=1a084e8cd56639ed6bbbbdb0fd5b29c5702f8ba93db8516bc33a2b74178dfb19",
+			},
+			expBody: `{"title":"1 alerts","queue":"ops"}`,
+		},
+
+		"A custom success status code should be treated as success.": {
+			cfg: webhook.Config{
+				SuccessStatusCodes: []int{http.StatusFound},
+			},
+			srvStatus: http.StatusFound,
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+		},
+
+		"A not success status code should be processed with communication error.": {
+			cfg: webhook.Config{
+				SuccessStatusCodes: []int{http.StatusCreated},
+			},
+			srvStatus: http.StatusOK,
+			notification: forward.Notification{
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: webhook.ErrComm,
+		},
+
+		"A body template that doesn't render JSON should fail.": {
+			cfg: webhook.Config{
+				BodyTemplate: `{"title": {{ .ChatID }}}`,
+			},
+			srvStatus: http.StatusOK,
+			notification: forward.Notification{
+				ChatID:     "team1",
+				AlertGroup: GetBaseAlertGroup(),
+			},
+			expErr: notify.ErrRenderTemplate,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Webhook stand-in.
+			var gotReq *webhookRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				gotReq = &webhookRequest{headers: r.Header, body: string(body)}
+				w.WriteHeader(test.srvStatus)
+			}))
+			defer srv.Close()
+
+			// Execute.
+			cfg := test.cfg
+			cfg.URL = srv.URL
+			cfg.HTTPClient = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			n, err := webhook.NewNotifier(cfg)
+			require.NoError(err)
+			err = n.Notify(context.TODO(), test.notification)
+
+			// Check.
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) && assert.NotNil(gotReq) {
+				for k, v := range test.expHeaders {
+					assert.Equal(v, gotReq.headers.Get(k))
+				}
+				if test.expBody != "" {
+					assert.JSONEq(test.expBody, gotReq.body)
+				}
+			}
+		})
+	}
+}
+
+func TestNewNotifierInvalidTemplate(t *testing.T) {
+	_, err := webhook.NewNotifier(webhook.Config{URL: "http://127.0.0.1", BodyTemplate: "{{ .Missing"})
+	assert.True(t, errors.Is(err, internalerrors.ErrInvalidConfiguration))
+}
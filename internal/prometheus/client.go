@@ -0,0 +1,128 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+
+	"github.com/slok/alertgram/internal/internalerrors"
+)
+
+var (
+	// ErrComm will be used when the communication to Prometheus fails.
+	ErrComm = errors.New("error communicating with prometheus")
+)
+
+// Client knows how to use the Prometheus HTTP API.
+type Client interface {
+	// QueryRange evaluates the expression over the time range at every step.
+	QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) (prommodel.Matrix, error)
+}
+
+// Config is the configuration of the Client.
+type Config struct {
+	// URL is the Prometheus URL (e.g `http://prometheus:9090`).
+	URL string
+	// HTTPClient is the HTTP client used to communicate with Prometheus.
+	HTTPClient *http.Client
+}
+
+func (c *Config) defaults() error {
+	if c.URL == "" {
+		return fmt.Errorf("prometheus URL is required")
+	}
+	c.URL = strings.TrimSuffix(c.URL, "/")
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+
+	return nil
+}
+
+type client struct {
+	cfg Config
+}
+
+// NewClient returns a new Prometheus v1 HTTP API client.
+//
+// More info here: https://prometheus.io/docs/prometheus/latest/querying/api/.
+func NewClient(cfg Config) (Client, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	return &client{cfg: cfg}, nil
+}
+
+type apiResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (c client) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) (prommodel.Matrix, error) {
+	v := url.Values{}
+	v.Set("query", query)
+	v.Set("start", formatTime(start))
+	v.Set("end", formatTime(end))
+	v.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL+"/api/v1/query_range?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrComm, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrComm, err)
+	}
+
+	// Prometheus errors have a JSON body with the error message.
+	res := apiResponse{}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%w: unexpected status code %d: %s", ErrComm, resp.StatusCode, body)
+		}
+		return nil, fmt.Errorf("could not decode prometheus response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || res.Status != "success" {
+		return nil, fmt.Errorf("%w: unexpected status code %d: %s", ErrComm, resp.StatusCode, res.Error)
+	}
+
+	if res.Data.ResultType != prommodel.ValMatrix.String() {
+		return nil, fmt.Errorf("unexpected prometheus result type %q", res.Data.ResultType)
+	}
+
+	m := prommodel.Matrix{}
+	err = json.Unmarshal(res.Data.Result, &m)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode prometheus matrix: %w", err)
+	}
+
+	return m, nil
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', -1, 64)
+}
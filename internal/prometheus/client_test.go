@@ -0,0 +1,89 @@
+package prometheus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/prometheus"
+)
+
+func TestClientQueryRange(t *testing.T) {
+	tests := map[string]struct {
+		srvStatus int
+		srvBody   string
+		expQuery  url.Values
+		expMatrix prommodel.Matrix
+		expErr    error
+	}{
+		"The range query should be mapped to a matrix.": {
+			srvStatus: http.StatusOK,
+			srvBody: `{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"pod":"pod-1"},"values":[[1609556645,"1.5"],[1609556705,"2"]]}
+			]}}`,
+			expQuery: url.Values{
+				"query": {`rate(errors_total[5m]) > 1`},
+				"start": {"1609556645"},
+				"end":   {"1609560245"},
+				"step":  {"60"},
+			},
+			expMatrix: prommodel.Matrix{
+				{
+					Metric: prommodel.Metric{"pod": "pod-1"},
+					Values: []prommodel.SamplePair{
+						{Timestamp: 1609556645000, Value: 1.5},
+						{Timestamp: 1609556705000, Value: 2},
+					},
+				},
+			},
+		},
+
+		"A Prometheus query error should be processed with communication error.": {
+			srvStatus: http.StatusBadRequest,
+			srvBody:   `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			expErr:    prometheus.ErrComm,
+		},
+
+		"A error from Prometheus should be processed with communication error.": {
+			srvStatus: http.StatusInternalServerError,
+			expErr:    prometheus.ErrComm,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var gotQuery url.Values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal("/api/v1/query_range", r.URL.Path)
+				gotQuery = r.URL.Query()
+				w.WriteHeader(test.srvStatus)
+				_, _ = w.Write([]byte(test.srvBody))
+			}))
+			defer srv.Close()
+
+			cli, err := prometheus.NewClient(prometheus.Config{URL: srv.URL + "/"})
+			require.NoError(err)
+
+			start := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+			gotMatrix, err := cli.QueryRange(context.TODO(), `rate(errors_total[5m]) > 1`, start, start.Add(time.Hour), time.Minute)
+
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				assert.Equal(test.expQuery, gotQuery)
+				assert.Equal(test.expMatrix, gotMatrix)
+			}
+		})
+	}
+}
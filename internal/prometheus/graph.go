@@ -0,0 +1,331 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	prommodel "github.com/prometheus/common/model"
+
+	"github.com/slok/alertgram/internal/internalerrors"
+	"github.com/slok/alertgram/internal/model"
+)
+
+var (
+	// ErrNoData will be used when the alert expression doesn't have data to graph.
+	ErrNoData = errors.New("no data to graph")
+)
+
+// GraphRendererConfig is the configuration of the GraphRenderer.
+type GraphRendererConfig struct {
+	// Client is the Prometheus client used to query the alerts expression.
+	Client Client
+	// Range is the time range of the graph until now, by default 30m.
+	Range time.Duration
+	// Width is the width of the graph image in pixels, by default 800.
+	Width int
+	// Height is the height of the graph image in pixels, by default 400.
+	Height int
+}
+
+func (c *GraphRendererConfig) defaults() error {
+	if c.Client == nil {
+		return fmt.Errorf("prometheus client is required")
+	}
+
+	if c.Range == 0 {
+		c.Range = 30 * time.Minute
+	}
+
+	if c.Width == 0 {
+		c.Width = 800
+	}
+
+	if c.Height == 0 {
+		c.Height = 400
+	}
+
+	if c.Width < 200 || c.Height < 100 {
+		return fmt.Errorf("graph size must be at least 200x100")
+	}
+
+	return nil
+}
+
+// GraphRenderer renders the PNG graphs of the alerts expressions.
+type GraphRenderer struct {
+	client Client
+	rng    time.Duration
+	step   time.Duration
+	width  int
+	height int
+}
+
+// NewGraphRenderer returns a new GraphRenderer.
+func NewGraphRenderer(cfg GraphRendererConfig) (*GraphRenderer, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, internalerrors.ErrInvalidConfiguration)
+	}
+
+	// A point every 4 pixels is enough for the graph.
+	step := (cfg.Range / time.Duration(cfg.Width/4)).Truncate(time.Second)
+	if step < time.Second {
+		step = time.Second
+	}
+
+	return &GraphRenderer{
+		client: cfg.Client,
+		rng:    cfg.Range,
+		step:   step,
+		width:  cfg.Width,
+		height: cfg.Height,
+	}, nil
+}
+
+// RenderGraph renders the PNG graph of the alert expression until now, the expression
+// is obtained from the alert Prometheus generator URL, if the alert doesn't have
+// an expression it will return nil.
+func (g GraphRenderer) RenderGraph(ctx context.Context, alert model.Alert) ([]byte, error) {
+	expr := AlertExpr(alert.GeneratorURL)
+	if expr == "" {
+		return nil, nil
+	}
+
+	end := time.Now()
+	start := end.Add(-g.rng)
+	m, err := g.client.QueryRange(ctx, expr, start, end, g.step)
+	if err != nil {
+		return nil, fmt.Errorf("could not query the alert expression: %w", err)
+	}
+
+	img, err := drawGraph(m, start, end, 2*g.step, g.width, g.height)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	err = png.Encode(&b, img)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the graph: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// AlertExpr returns the expression of the alert from its Prometheus generator URL
+// (e.g `http://prometheus:9090/graph?g0.expr=up+%3D%3D+0&g0.tab=1`).
+func AlertExpr(generatorURL string) string {
+	u, err := url.Parse(generatorURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Query().Get("g0.expr")
+}
+
+const (
+	graphMarginLeft   = 70
+	graphMarginRight  = 20
+	graphMarginTop    = 15
+	graphMarginBottom = 30
+	graphTicks        = 5
+)
+
+var (
+	graphBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	graphGrid       = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+	graphAxis       = color.RGBA{0x80, 0x80, 0x80, 0xff}
+	graphText       = color.RGBA{0x40, 0x40, 0x40, 0xff}
+	graphColors     = []color.RGBA{
+		{0xe0, 0x2f, 0x44, 0xff},
+		{0x56, 0x94, 0xf2, 0xff},
+		{0x73, 0xbf, 0x69, 0xff},
+		{0xff, 0x98, 0x30, 0xff},
+		{0xb8, 0x77, 0xd9, 0xff},
+		{0xf2, 0xcc, 0x0c, 0xff},
+		{0x1f, 0x78, 0xc1, 0xff},
+		{0x8f, 0x3b, 0xb8, 0xff},
+	}
+)
+
+// drawGraph draws the series as lines, the lines are broken when the samples
+// are separated more than the max gap.
+func drawGraph(m prommodel.Matrix, start, end time.Time, maxGap time.Duration, width, height int) (*image.RGBA, error) {
+	min, max, ok := valuesRange(m)
+	if !ok {
+		return nil, ErrNoData
+	}
+	if min == max {
+		min, max = min-1, max+1
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{graphBackground}, image.Point{}, draw.Src)
+	plot := image.Rect(graphMarginLeft, graphMarginTop, width-graphMarginRight, height-graphMarginBottom)
+
+	x := func(t time.Time) int {
+		return plot.Min.X + int(float64(t.Sub(start))/float64(end.Sub(start))*float64(plot.Dx()-1))
+	}
+	y := func(v float64) int {
+		return plot.Max.Y - 1 - int((v-min)/(max-min)*float64(plot.Dy()-1))
+	}
+
+	// Grid and axes labels.
+	for i := 0; i <= graphTicks; i++ {
+		v := min + (max-min)*float64(i)/graphTicks
+		yy := y(v)
+		drawLine(img, plot.Min.X, yy, plot.Max.X-1, yy, graphGrid)
+		label := strconv.FormatFloat(v, 'g', 4, 64)
+		drawText(img, plot.Min.X-8-textWidth(label), yy-textHeight/2, label, graphText)
+
+		t := start.Add(time.Duration(float64(end.Sub(start)) * float64(i) / graphTicks))
+		xx := x(t)
+		drawLine(img, xx, plot.Min.Y, xx, plot.Max.Y-1, graphGrid)
+		label = t.UTC().Format("15:04")
+		drawText(img, xx-textWidth(label)/2, plot.Max.Y+8, label, graphText)
+	}
+	drawLine(img, plot.Min.X, plot.Min.Y, plot.Min.X, plot.Max.Y-1, graphAxis)
+	drawLine(img, plot.Min.X, plot.Max.Y-1, plot.Max.X-1, plot.Max.Y-1, graphAxis)
+
+	// Series.
+	for i, s := range m {
+		c := graphColors[i%len(graphColors)]
+		var prev *prommodel.SamplePair
+		for j := range s.Values {
+			p := &s.Values[j]
+			v := float64(p.Value)
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				prev = nil
+				continue
+			}
+			if prev == nil || p.Timestamp.Sub(prev.Timestamp) > maxGap {
+				drawPoint(img, x(p.Timestamp.Time()), y(v), c)
+			} else {
+				drawLine(img, x(prev.Timestamp.Time()), y(float64(prev.Value)), x(p.Timestamp.Time()), y(v), c)
+			}
+			prev = p
+		}
+	}
+
+	return img, nil
+}
+
+// valuesRange returns the minimum and maximum values of the series, if there
+// are no values it will return false.
+func valuesRange(m prommodel.Matrix) (min, max float64, ok bool) {
+	min, max = math.Inf(1), math.Inf(-1)
+	for _, s := range m {
+		for _, p := range s.Values {
+			v := float64(p.Value)
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+			ok = true
+		}
+	}
+
+	return min, max, ok
+}
+
+// drawLine draws a 2px line using Bresenham's algorithm.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		drawPoint(img, x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func drawPoint(img *image.RGBA, x, y int, c color.RGBA) {
+	img.SetRGBA(x, y, c)
+	img.SetRGBA(x+1, y, c)
+	img.SetRGBA(x, y+1, c)
+	img.SetRGBA(x+1, y+1, c)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// glyphs is a small 3x5 pixels font with the characters used by the axes labels.
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", ".##", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", ".#.", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	'-': {"...", "...", "###", "...", "..."},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'e': {"...", "###", "###", "#..", "###"},
+}
+
+const (
+	glyphScale = 2
+	glyphWidth = 3*glyphScale + glyphScale
+	textHeight = 5 * glyphScale
+)
+
+func textWidth(s string) int {
+	return len(s)*glyphWidth - glyphScale
+}
+
+// drawText draws the text with its top left corner at x,y.
+func drawText(img *image.RGBA, x, y int, s string, c color.RGBA) {
+	for i, r := range s {
+		g, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+		for row, line := range g {
+			for col, px := range line {
+				if px != '#' {
+					continue
+				}
+				rect := image.Rect(0, 0, glyphScale, glyphScale).Add(image.Pt(x+i*glyphWidth+col*glyphScale, y+row*glyphScale))
+				draw.Draw(img, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
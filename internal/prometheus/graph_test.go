@@ -0,0 +1,127 @@
+package prometheus_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/alertgram/internal/model"
+	"github.com/slok/alertgram/internal/prometheus"
+)
+
+func TestAlertExpr(t *testing.T) {
+	tests := map[string]struct {
+		generatorURL string
+		expExpr      string
+	}{
+		"The expression should be obtained from the Prometheus generator URL.": {
+			generatorURL: "http://prometheus:9090/graph?g0.expr=rate%28errors_total%5B5m%5D%29+%3E+1&g0.tab=1",
+			expExpr:      "rate(errors_total[5m]) > 1",
+		},
+
+		"A generator URL without expression should not have expression.": {
+			generatorURL: "http://prometheus:9090/graph",
+		},
+
+		"An invalid generator URL should not have expression.": {
+			generatorURL: "http://prometheus:9090/%zz",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expExpr, prometheus.AlertExpr(test.generatorURL))
+		})
+	}
+}
+
+// fakePrometheus returns a Prometheus server that responds to the range queries
+// with the result of the series function.
+func fakePrometheus(t *testing.T, result func(start, end float64) string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start, end float64
+		_, err := fmt.Sscan(r.URL.Query().Get("start"), &start)
+		require.NoError(t, err)
+		_, err = fmt.Sscan(r.URL.Query().Get("end"), &end)
+		require.NoError(t, err)
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, result(start, end))
+	}))
+}
+
+func TestGraphRendererRenderGraph(t *testing.T) {
+	tests := map[string]struct {
+		alert     model.Alert
+		result    func(start, end float64) string
+		expNil    bool
+		expErr    error
+		expWidth  int
+		expHeight int
+	}{
+		"An alert without expression should not have graph.": {
+			alert:  model.Alert{Name: "test"},
+			expNil: true,
+		},
+
+		"An alert with expression should be rendered as a PNG graph.": {
+			alert: model.Alert{Name: "test", GeneratorURL: "http://prometheus:9090/graph?g0.expr=up+%3D%3D+0"},
+			result: func(start, end float64) string {
+				return fmt.Sprintf(`
+					{"metric":{"pod":"pod-1"},"values":[[%f,"1"],[%f,"3.5"],[%f,"2"]]},
+					{"metric":{"pod":"pod-2"},"values":[[%f,"-1e+06"],[%f,"NaN"]]}`,
+					start, (start+end)/2, end, start, end)
+			},
+			expWidth:  400,
+			expHeight: 200,
+		},
+
+		"An alert without data should fail.": {
+			alert:  model.Alert{Name: "test", GeneratorURL: "http://prometheus:9090/graph?g0.expr=up+%3D%3D+0"},
+			result: func(start, end float64) string { return "" },
+			expErr: prometheus.ErrNoData,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			srv := fakePrometheus(t, test.result)
+			defer srv.Close()
+
+			cli, err := prometheus.NewClient(prometheus.Config{URL: srv.URL})
+			require.NoError(err)
+			r, err := prometheus.NewGraphRenderer(prometheus.GraphRendererConfig{
+				Client: cli,
+				Range:  10 * time.Minute,
+				Width:  400,
+				Height: 200,
+			})
+			require.NoError(err)
+
+			graph, err := r.RenderGraph(context.TODO(), test.alert)
+
+			if test.expErr != nil && assert.Error(err) {
+				assert.True(errors.Is(err, test.expErr))
+			} else if assert.NoError(err) {
+				if test.expNil {
+					assert.Nil(graph)
+					return
+				}
+				img, err := png.Decode(bytes.NewReader(graph))
+				require.NoError(err)
+				assert.Equal(test.expWidth, img.Bounds().Dx())
+				assert.Equal(test.expHeight, img.Bounds().Dy())
+			}
+		})
+	}
+}